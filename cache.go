@@ -0,0 +1,299 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// CachingSupply a Supply keeping fetched rule sets in front of another one, bounded by age and count.
+// Concurrent fetches of the same id and start share one call to the wrapped Supply
+type CachingSupply struct {
+	sp   Supply
+	ttl  time.Duration
+	size int
+
+	group singleflight.Group
+
+	mu      sync.Mutex
+	entries map[cacheKey]*list.Element
+	lru     *list.List                // most recently used first
+	rules   map[string]*cacheRule     // rule sets with a fetch running or cached, dropped once they have neither
+	owners  map[string]map[string]int // setting ID to the rule sets whose cached entries hold it, and how many
+	epoch   uint64                    // the last generation handed out
+	stats   CacheStats
+}
+
+// CacheStats counters of a CachingSupply since it was created
+type CacheStats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	Size      int    `json:"size"`
+}
+
+type cacheKey struct {
+	ruleID string
+	start  int
+}
+
+// cacheRule the state of one rule set in the cache
+type cacheRule struct {
+	gen      uint64 // replaced by Invalidate so fetches already running are not stored, never reused
+	fetching int    // callers waiting on a fetch
+	cached   int    // entries in the cache
+}
+
+type cacheEntry struct {
+	key     cacheKey
+	rs      []RuleSetting
+	expires time.Time
+}
+
+// NewCachingSupply cache sp for ttl keeping at most size fetches, zero or less removes either bound
+func NewCachingSupply(sp Supply, ttl time.Duration, size int) *CachingSupply {
+	return &CachingSupply{
+		sp:      sp,
+		ttl:     ttl,
+		size:    size,
+		entries: make(map[cacheKey]*list.Element),
+		lru:     list.New(),
+		rules:   make(map[string]*cacheRule),
+		owners:  make(map[string]map[string]int),
+	}
+}
+
+func (cs *CachingSupply) FetchRuleSettings(id string, start int) ([]RuleSetting, error) {
+	return cs.FetchRuleSettingsContext(context.Background(), id, start)
+}
+
+// FetchRuleSettingsContext serve from the cache or fetch once for every concurrent caller,
+// a caller leaving on ctx does not cancel the fetch the others wait for
+func (cs *CachingSupply) FetchRuleSettingsContext(ctx context.Context, id string, start int) ([]RuleSetting, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	key := cacheKey{ruleID: id, start: start}
+	rs, gen, ok := cs.lookup(key)
+	if ok {
+		return cloneSettings(rs), nil
+	}
+	defer cs.done(id)
+
+	ch := cs.group.DoChan(fmt.Sprintf("%d:%s@%d", gen, id, start), func() (interface{}, error) {
+		rs, err := cs.fetch(context.WithoutCancel(ctx), id, start)
+		if err != nil {
+			return nil, err
+		}
+		cs.store(key, gen, rs)
+		return rs, nil
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return cloneSettings(res.Val.([]RuleSetting)), nil
+	}
+}
+
+func (cs *CachingSupply) fetch(ctx context.Context, id string, start int) ([]RuleSetting, error) {
+	if sp, ok := cs.sp.(ContextSupply); ok {
+		return sp.FetchRuleSettingsContext(ctx, id, start)
+	}
+	return cs.sp.FetchRuleSettings(id, start)
+}
+
+func (cs *CachingSupply) SaveRuleSettings(rs []RuleSetting) error {
+	return cs.SaveRuleSettingsContext(context.Background(), rs)
+}
+
+// SaveRuleSettingsContext save through to the wrapped Supply and drop every rule set touched,
+// the ones a saved setting is cached in as well so it does not stay in the rule set it moved out of
+func (cs *CachingSupply) SaveRuleSettingsContext(ctx context.Context, rs []RuleSetting) error {
+	var err error
+	if sp, ok := cs.sp.(ContextSupply); ok {
+		err = sp.SaveRuleSettingsContext(ctx, rs)
+	} else {
+		err = cs.sp.SaveRuleSettings(rs)
+	}
+	touched := make(map[string]bool)
+	cs.mu.Lock()
+	for _, setting := range rs {
+		touched[setting.RuleID] = true
+		for ruleID := range cs.owners[setting.ID] {
+			touched[ruleID] = true
+		}
+	}
+	cs.mu.Unlock()
+	for ruleID := range touched {
+		cs.Invalidate(ruleID)
+	}
+	return err
+}
+
+// Invalidate drop every cached fetch of rule set ruleID
+func (cs *CachingSupply) Invalidate(ruleID string) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	r, ok := cs.rules[ruleID]
+	if !ok {
+		return
+	}
+	for key, el := range cs.entries {
+		if key.ruleID == ruleID {
+			cs.index(ruleID, el.Value.(*cacheEntry).rs, -1)
+			cs.lru.Remove(el)
+			delete(cs.entries, key)
+		}
+	}
+	cs.reset(ruleID, r)
+}
+
+// InvalidateAll drop everything cached
+func (cs *CachingSupply) InvalidateAll() {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	for ruleID, r := range cs.rules {
+		cs.reset(ruleID, r)
+	}
+	cs.entries = make(map[cacheKey]*list.Element)
+	cs.owners = make(map[string]map[string]int)
+	cs.lru.Init()
+}
+
+// Stats the counters so far
+func (cs *CachingSupply) Stats() CacheStats {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	stats := cs.stats
+	stats.Size = cs.lru.Len()
+	return stats
+}
+
+func (cs *CachingSupply) lookup(key cacheKey) ([]RuleSetting, uint64, bool) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	el, ok := cs.entries[key]
+	if ok {
+		entry := el.Value.(*cacheEntry)
+		if cs.ttl <= 0 || time.Now().Before(entry.expires) {
+			cs.stats.Hits++
+			cs.lru.MoveToFront(el)
+			return entry.rs, cs.rules[key.ruleID].gen, true
+		}
+		cs.evict(el)
+	}
+	cs.stats.Misses++
+	r, ok := cs.rules[key.ruleID]
+	if !ok {
+		cs.epoch++
+		r = &cacheRule{gen: cs.epoch}
+		cs.rules[key.ruleID] = r
+	}
+	r.fetching++
+	return nil, r.gen, false
+}
+
+// done a caller of lookup that missed is no longer waiting on its fetch
+func (cs *CachingSupply) done(ruleID string) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	r := cs.rules[ruleID]
+	r.fetching--
+	cs.release(ruleID, r)
+}
+
+func (cs *CachingSupply) store(key cacheKey, gen uint64, rs []RuleSetting) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	r, ok := cs.rules[key.ruleID]
+	if !ok || r.gen != gen {
+		return
+	}
+	entry := &cacheEntry{key: key, rs: rs, expires: time.Now().Add(cs.ttl)}
+	cs.index(key.ruleID, rs, 1)
+	if el, ok := cs.entries[key]; ok {
+		cs.index(key.ruleID, el.Value.(*cacheEntry).rs, -1)
+		el.Value = entry
+		cs.lru.MoveToFront(el)
+		return
+	}
+	cs.entries[key] = cs.lru.PushFront(entry)
+	r.cached++
+	for cs.size > 0 && cs.lru.Len() > cs.size {
+		cs.evict(cs.lru.Back())
+	}
+}
+
+// evict drop one cached entry on age or count
+func (cs *CachingSupply) evict(el *list.Element) {
+	key := el.Value.(*cacheEntry).key
+	cs.index(key.ruleID, el.Value.(*cacheEntry).rs, -1)
+	cs.lru.Remove(el)
+	delete(cs.entries, key)
+	cs.stats.Evictions++
+	r := cs.rules[key.ruleID]
+	r.cached--
+	cs.release(key.ruleID, r)
+}
+
+// index count an entry of ruleID holding rs into owners, n is 1 when it is stored and -1 when it is dropped
+func (cs *CachingSupply) index(ruleID string, rs []RuleSetting, n int) {
+	for _, setting := range rs {
+		owners, ok := cs.owners[setting.ID]
+		if !ok {
+			owners = make(map[string]int)
+			cs.owners[setting.ID] = owners
+		}
+		owners[ruleID] += n
+		if owners[ruleID] <= 0 {
+			delete(owners, ruleID)
+		}
+		if len(owners) == 0 {
+			delete(cs.owners, setting.ID)
+		}
+	}
+}
+
+// reset give r a new generation once its entries are dropped, fetches already running are then not stored
+func (cs *CachingSupply) reset(ruleID string, r *cacheRule) {
+	cs.epoch++
+	r.gen = cs.epoch
+	r.cached = 0
+	cs.release(ruleID, r)
+}
+
+// release forget r once nothing is cached or being fetched for it
+func (cs *CachingSupply) release(ruleID string, r *cacheRule) {
+	if r.fetching == 0 && r.cached == 0 {
+		delete(cs.rules, ruleID)
+	}
+}
+
+// cloneSettings copies the engine is free to reorder, the cache keeps its own
+func cloneSettings(rs []RuleSetting) []RuleSetting {
+	if rs == nil {
+		return nil
+	}
+	out := make([]RuleSetting, len(rs))
+	for i, setting := range rs {
+		out[i] = setting.clone()
+	}
+	return out
+}
@@ -0,0 +1,250 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingSupply a MemorySupply counting its fetches, each one waits on release when it is set
+type countingSupply struct {
+	*MemorySupply
+	fetches atomic.Int64
+	started chan struct{}
+	release chan struct{}
+}
+
+func newCountingSupply(t *testing.T, ruleIDs ...string) *countingSupply {
+	t.Helper()
+	sp := &countingSupply{MemorySupply: NewMemorySupply()}
+	for _, ruleID := range ruleIDs {
+		if err := sp.SaveRuleSettings([]RuleSetting{{ID: ruleID + "-1", RuleID: ruleID, Sequence: 1}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return sp
+}
+
+func (sp *countingSupply) FetchRuleSettings(id string, start int) ([]RuleSetting, error) {
+	sp.fetches.Add(1)
+	if sp.started != nil {
+		sp.started <- struct{}{}
+	}
+	if sp.release != nil {
+		<-sp.release
+	}
+	return sp.MemorySupply.FetchRuleSettings(id, start)
+}
+
+func (sp *countingSupply) FetchRuleSettingsContext(ctx context.Context, id string, start int) ([]RuleSetting, error) {
+	return sp.FetchRuleSettings(id, start)
+}
+
+func mustFetch(t *testing.T, sp Supply, id string) []RuleSetting {
+	t.Helper()
+	rs, err := sp.FetchRuleSettings(id, 0)
+	if err != nil {
+		t.Fatalf("FetchRuleSettings(%q): %v", id, err)
+	}
+	return rs
+}
+
+func TestCachingSupplyHits(t *testing.T) {
+	sp := newCountingSupply(t, "r")
+	cs := NewCachingSupply(sp, 0, 0)
+
+	rs := mustFetch(t, cs, "r")
+	rs[0].Sequence = 99
+	rs = mustFetch(t, cs, "r")
+	if rs[0].Sequence != 1 {
+		t.Fatalf("changing a fetched setting changed the cache: %+v", rs[0])
+	}
+	mustFetch(t, cs, "r")
+	if _, err := cs.FetchRuleSettings("r", 5); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := sp.fetches.Load(); got != 2 {
+		t.Fatalf("%d fetches, want one per start", got)
+	}
+	if stats := cs.Stats(); stats.Hits != 2 || stats.Misses != 2 || stats.Size != 2 {
+		t.Fatalf("Stats() = %+v, want 2 hits, 2 misses and 2 cached", stats)
+	}
+}
+
+func TestCachingSupplyTTL(t *testing.T) {
+	sp := newCountingSupply(t, "r")
+	cs := NewCachingSupply(sp, 20*time.Millisecond, 0)
+
+	mustFetch(t, cs, "r")
+	mustFetch(t, cs, "r")
+	time.Sleep(40 * time.Millisecond)
+	mustFetch(t, cs, "r")
+
+	if got := sp.fetches.Load(); got != 2 {
+		t.Fatalf("%d fetches, want 2 with the expired entry fetched again", got)
+	}
+	if stats := cs.Stats(); stats.Evictions != 1 || stats.Size != 1 {
+		t.Fatalf("Stats() = %+v, want 1 eviction and 1 cached", stats)
+	}
+}
+
+func TestCachingSupplyLRU(t *testing.T) {
+	sp := newCountingSupply(t, "a", "b", "c")
+	cs := NewCachingSupply(sp, 0, 2)
+
+	mustFetch(t, cs, "a")
+	mustFetch(t, cs, "b")
+	mustFetch(t, cs, "a")
+	mustFetch(t, cs, "c") // b is the least recently used
+	mustFetch(t, cs, "a")
+	if got := sp.fetches.Load(); got != 3 {
+		t.Fatalf("%d fetches, want 3 with a kept", got)
+	}
+	mustFetch(t, cs, "b")
+	if got := sp.fetches.Load(); got != 4 {
+		t.Fatalf("%d fetches, want 4 with b evicted", got)
+	}
+	if stats := cs.Stats(); stats.Evictions != 2 || stats.Size != 2 {
+		t.Fatalf("Stats() = %+v, want 2 evictions and 2 cached", stats)
+	}
+}
+
+func TestCachingSupplyCollapsesFetches(t *testing.T) {
+	sp := newCountingSupply(t, "r")
+	sp.started = make(chan struct{}, 1)
+	sp.release = make(chan struct{})
+	cs := NewCachingSupply(sp, 0, 0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := cs.FetchRuleSettings("r", 0); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	<-sp.started
+	// let the other callers reach the fetch running before it returns
+	for cs.Stats().Misses < 10 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(sp.release)
+	wg.Wait()
+
+	if got := sp.fetches.Load(); got != 1 {
+		t.Fatalf("%d fetches, want one for every caller", got)
+	}
+}
+
+func TestCachingSupplyInvalidate(t *testing.T) {
+	sp := newCountingSupply(t, "a", "b")
+	cs := NewCachingSupply(sp, 0, 0)
+
+	mustFetch(t, cs, "a")
+	mustFetch(t, cs, "b")
+	cs.Invalidate("a")
+	mustFetch(t, cs, "a")
+	mustFetch(t, cs, "b")
+	if got := sp.fetches.Load(); got != 3 {
+		t.Fatalf("%d fetches, want 3 with a alone fetched again", got)
+	}
+
+	if err := cs.SaveRuleSettings([]RuleSetting{{ID: "b-2", RuleID: "b", Sequence: 2}}); err != nil {
+		t.Fatal(err)
+	}
+	if rs := mustFetch(t, cs, "b"); len(rs) != 2 {
+		t.Fatalf("FetchRuleSettings = %+v, want the saved setting served", rs)
+	}
+
+	cs.InvalidateAll()
+	mustFetch(t, cs, "a")
+	mustFetch(t, cs, "b")
+	if got := sp.fetches.Load(); got != 6 {
+		t.Fatalf("%d fetches, want 6 with both fetched again", got)
+	}
+}
+
+func TestCachingSupplyInvalidateDuringFetch(t *testing.T) {
+	sp := newCountingSupply(t, "r")
+	sp.started = make(chan struct{}, 1)
+	sp.release = make(chan struct{})
+	cs := NewCachingSupply(sp, 0, 0)
+
+	done := make(chan error)
+	go func() {
+		_, err := cs.FetchRuleSettings("r", 0)
+		done <- err
+	}()
+	<-sp.started
+	cs.Invalidate("r")
+	close(sp.release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	sp.started = nil
+	mustFetch(t, cs, "r")
+	if got := sp.fetches.Load(); got != 2 {
+		t.Fatalf("%d fetches, want 2 with the fetch running during Invalidate not stored", got)
+	}
+}
+
+func TestCachingSupplyCallerLeaves(t *testing.T) {
+	sp := newCountingSupply(t, "r")
+	sp.started = make(chan struct{}, 1)
+	sp.release = make(chan struct{})
+	cs := NewCachingSupply(sp, 0, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := cs.FetchRuleSettingsContext(ctx, "r", 0)
+		done <- err
+	}()
+	<-sp.started
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("FetchRuleSettingsContext = %v, want context.Canceled", err)
+	}
+	close(sp.release)
+}
+
+func TestCachingSupplyForgetsRuleSets(t *testing.T) {
+	sp := newCountingSupply(t, "a", "b", "c")
+	cs := NewCachingSupply(sp, 0, 2)
+
+	for i := 0; i < 100; i++ {
+		if _, err := cs.FetchRuleSettings(fmt.Sprintf("missing-%d", i), 0); !errors.Is(err, RuleSettingError.RULE_NOT_FOUND) {
+			t.Fatalf("FetchRuleSettings = %v, want RULE_NOT_FOUND", err)
+		}
+		cs.Invalidate(fmt.Sprintf("never-fetched-%d", i))
+	}
+	for _, id := range []string{"a", "b", "c", "a", "b", "c"} {
+		mustFetch(t, cs, id)
+	}
+	cs.mu.Lock()
+	kept := len(cs.rules)
+	cs.mu.Unlock()
+	if kept != 2 {
+		t.Fatalf("%d rule sets kept, want the 2 cached", kept)
+	}
+
+	cs.InvalidateAll()
+	cs.mu.Lock()
+	kept = len(cs.rules)
+	cs.mu.Unlock()
+	if kept != 0 {
+		t.Fatalf("%d rule sets kept once invalidated, want 0", kept)
+	}
+}
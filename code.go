@@ -0,0 +1,211 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CompareCode a Condition compare, see RuleConditionCompare
+type CompareCode int
+
+// ConditionTypeCode a Condition type, see RuleConditionType
+type ConditionTypeCode int
+
+// SideCode what a Condition or Modifer side holds, see ConditionSideType and ModiferSideType
+type SideCode int
+
+// OperandCode a Modifer operand, see RuleOperand
+type OperandCode int
+
+// DataTypeCode a Modifer data type, see ModiferDataType
+type DataTypeCode int
+
+// GroupModeCode how a ConditionGroup combines its members, see ConditionGroupMode
+type GroupModeCode int
+
+// RoundingModeCode how a Rounding settles the discarded digits, see RoundingMode
+type RoundingModeCode int
+
+// The codes are written to JSON by the name of their constant, "MORE_EQUAL" rather than 3.
+// Reading takes either, names in any case, so rules stored as numbers keep loading
+var (
+	compareNames       = codeNamesOf[CompareCode](RuleConditionCompare)
+	conditionTypeNames = codeNamesOf[ConditionTypeCode](RuleConditionType)
+	operandNames       = codeNamesOf[OperandCode](RuleOperand)
+	sideNames          = codeNamesOf[SideCode](ModiferSideType) // the same codes as ConditionSideType and COMPLEX
+	dataTypeNames      = codeNamesOf[DataTypeCode](ModiferDataType)
+	groupModeNames     = codeNamesOf[GroupModeCode](ConditionGroupMode)
+	roundingModeNames  = codeNamesOf[RoundingModeCode](RoundingMode)
+)
+
+func (c CompareCode) String() string {
+	return compareNames.name(c)
+}
+
+func (c CompareCode) MarshalJSON() ([]byte, error) {
+	return compareNames.marshal(c)
+}
+
+func (c *CompareCode) UnmarshalJSON(b []byte) error {
+	return compareNames.unmarshal(b, c, "compare")
+}
+
+func (c ConditionTypeCode) String() string {
+	return conditionTypeNames.name(c)
+}
+
+func (c ConditionTypeCode) MarshalJSON() ([]byte, error) {
+	return conditionTypeNames.marshal(c)
+}
+
+func (c *ConditionTypeCode) UnmarshalJSON(b []byte) error {
+	return conditionTypeNames.unmarshal(b, c, "condition type")
+}
+
+func (c OperandCode) String() string {
+	return operandNames.name(c)
+}
+
+func (c OperandCode) MarshalJSON() ([]byte, error) {
+	return operandNames.marshal(c)
+}
+
+func (c *OperandCode) UnmarshalJSON(b []byte) error {
+	return operandNames.unmarshal(b, c, "operand")
+}
+
+func (c SideCode) String() string {
+	return sideNames.name(c)
+}
+
+func (c SideCode) MarshalJSON() ([]byte, error) {
+	return sideNames.marshal(c)
+}
+
+func (c *SideCode) UnmarshalJSON(b []byte) error {
+	return sideNames.unmarshal(b, c, "side type")
+}
+
+func (c DataTypeCode) String() string {
+	return dataTypeNames.name(c)
+}
+
+func (c DataTypeCode) MarshalJSON() ([]byte, error) {
+	return dataTypeNames.marshal(c)
+}
+
+func (c *DataTypeCode) UnmarshalJSON(b []byte) error {
+	return dataTypeNames.unmarshal(b, c, "data type")
+}
+
+func (c GroupModeCode) String() string {
+	return groupModeNames.name(c)
+}
+
+func (c GroupModeCode) MarshalJSON() ([]byte, error) {
+	return groupModeNames.marshal(c)
+}
+
+func (c *GroupModeCode) UnmarshalJSON(b []byte) error {
+	return groupModeNames.unmarshal(b, c, "group mode")
+}
+
+func (c RoundingModeCode) String() string {
+	return roundingModeNames.name(c)
+}
+
+func (c RoundingModeCode) MarshalJSON() ([]byte, error) {
+	return roundingModeNames.marshal(c)
+}
+
+func (c *RoundingModeCode) UnmarshalJSON(b []byte) error {
+	return roundingModeNames.unmarshal(b, c, "rounding mode")
+}
+
+// codeNames the names of the codes of one enum var
+type codeNames[T ~int] struct {
+	names map[T]string
+	codes map[string]T
+}
+
+// codeNamesOf take the names from the fields of enum, a var such as RuleConditionCompare
+func codeNamesOf[T ~int](enum interface{}) codeNames[T] {
+	v := reflect.ValueOf(enum)
+	cn := codeNames[T]{names: make(map[T]string), codes: make(map[string]T)}
+	for i := 0; i < v.NumField(); i++ {
+		code := v.Field(i).Interface().(T)
+		name := v.Type().Field(i).Name
+		cn.names[code] = name
+		cn.codes[name] = code
+	}
+	return cn
+}
+
+func (cn codeNames[T]) name(code T) string {
+	if name, ok := cn.names[code]; ok {
+		return name
+	}
+	return strconv.Itoa(int(code))
+}
+
+// all every code in order
+func (cn codeNames[T]) all() []T {
+	codes := make([]T, 0, len(cn.names))
+	for code := range cn.names {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool {
+		return codes[i] < codes[j]
+	})
+	return codes
+}
+
+// enum the name and number of each of codes, as JSON may hold them
+func (cn codeNames[T]) enum(codes ...T) []interface{} {
+	values := make([]interface{}, 0, 2*len(codes))
+	for _, code := range codes {
+		values = append(values, cn.name(code), int(code))
+	}
+	return values
+}
+
+// marshal a code without a name stays a number, Validate is the one to reject it
+func (cn codeNames[T]) marshal(code T) ([]byte, error) {
+	if name, ok := cn.names[code]; ok {
+		return json.Marshal(name)
+	}
+	return json.Marshal(int(code))
+}
+
+func (cn codeNames[T]) unmarshal(b []byte, code *T, what string) error {
+	b = bytes.TrimSpace(b)
+	if bytes.Equal(b, []byte("null")) {
+		return nil
+	}
+	if len(b) == 0 || b[0] != '"' {
+		var n int
+		if err := json.Unmarshal(b, &n); err != nil {
+			return fmt.Errorf("%w: %s %s", RuleSettingError.VALUE_INVALID, what, b)
+		}
+		*code = T(n)
+		return nil
+	}
+	var name string
+	if err := json.Unmarshal(b, &name); err != nil {
+		return err
+	}
+	c, ok := cn.codes[strings.ToUpper(name)]
+	if !ok {
+		return fmt.Errorf("%w: %s %q", RuleSettingError.VALUE_INVALID, what, name)
+	}
+	*code = c
+	return nil
+}
@@ -0,0 +1,118 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// codeEnums every enum var written by name, with a pointer to a code of its type to read into
+func codeEnums() map[string]struct {
+	enum interface{}
+	code func() interface{}
+} {
+	return map[string]struct {
+		enum interface{}
+		code func() interface{}
+	}{
+		"Compare":       {RuleConditionCompare, func() interface{} { return new(CompareCode) }},
+		"ConditionType": {RuleConditionType, func() interface{} { return new(ConditionTypeCode) }},
+		"Side":          {ModiferSideType, func() interface{} { return new(SideCode) }},
+		"Operand":       {RuleOperand, func() interface{} { return new(OperandCode) }},
+		"DataType":      {ModiferDataType, func() interface{} { return new(DataTypeCode) }},
+		"GroupMode":     {ConditionGroupMode, func() interface{} { return new(GroupModeCode) }},
+		"RoundingMode":  {RoundingMode, func() interface{} { return new(RoundingModeCode) }},
+	}
+}
+
+func TestCodeJSON(t *testing.T) {
+	for name, e := range codeEnums() {
+		t.Run(name, func(t *testing.T) {
+			v := reflect.ValueOf(e.enum)
+			for i := 0; i < v.NumField(); i++ {
+				code, constant := v.Field(i), v.Type().Field(i).Name
+				b, err := json.Marshal(code.Interface())
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(b) != `"`+constant+`"` {
+					t.Fatalf("%s written as %s, want its name", constant, b)
+				}
+
+				for _, doc := range []string{string(b), `"` + strings.ToLower(constant) + `"`, string(mustMarshal(t, code.Int()))} {
+					back := e.code()
+					if err := json.Unmarshal([]byte(doc), back); err != nil {
+						t.Fatalf("%s: %v", doc, err)
+					}
+					if got := reflect.ValueOf(back).Elem().Int(); got != code.Int() {
+						t.Fatalf("%s read as %d, want %d", doc, got, code.Int())
+					}
+				}
+			}
+
+			if err := json.Unmarshal([]byte(`"NO_SUCH_CODE"`), e.code()); !errors.Is(err, RuleSettingError.VALUE_INVALID) {
+				t.Fatalf("an unknown name = %v, want VALUE_INVALID", err)
+			}
+			if err := json.Unmarshal([]byte(`true`), e.code()); !errors.Is(err, RuleSettingError.VALUE_INVALID) {
+				t.Fatalf("a boolean = %v, want VALUE_INVALID", err)
+			}
+			unknown := reflect.ValueOf(e.code()).Elem()
+			unknown.SetInt(999)
+			if b, err := json.Marshal(unknown.Interface()); err != nil || string(b) != "999" {
+				t.Fatalf("an unknown code written as %s, %v, want the number", b, err)
+			}
+		})
+	}
+}
+
+func mustMarshal(t *testing.T, v interface{}) []byte {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestModeJSON(t *testing.T) {
+	r := Rule{
+		ConditionTree: &ConditionGroup{
+			Mode:       ConditionGroupMode.ANY,
+			Conditions: []Condition{intCondition("Adults", RuleConditionCompare.EQUAL, "2")},
+			Groups:     []ConditionGroup{{Mode: ConditionGroupMode.NONE}},
+		},
+		ModiferChain: []Modifer{withRounding(intModifer(RuleOperand.DIV, "Price", "3"), &Rounding{Mode: RoundingMode.HALF_EVEN, Places: -2})},
+	}
+	b, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, written := range []string{`"mode":"ANY"`, `"mode":"NONE"`, `"mode":"HALF_EVEN"`} {
+		if !strings.Contains(string(b), written) {
+			t.Errorf("%s, want %s in it", b, written)
+		}
+	}
+
+	var back Rule
+	if err := json.Unmarshal(b, &back); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(back, r) {
+		t.Fatalf("read back as %+v, want %+v", back, r)
+	}
+
+	// rules stored before the modes had names
+	stored := `{"condition_tree":{"mode":1,"groups":[{"mode":2}]},"rate_modifer":[{"rounding":{"mode":2,"places":-2}}]}`
+	var old Rule
+	if err := json.Unmarshal([]byte(stored), &old); err != nil {
+		t.Fatal(err)
+	}
+	if old.ConditionTree.Mode != ConditionGroupMode.ANY || old.ConditionTree.Groups[0].Mode != ConditionGroupMode.NONE || old.ModiferChain[0].Rounding.Mode != RoundingMode.HALF_EVEN {
+		t.Fatalf("numbered modes read as %+v", old)
+	}
+}
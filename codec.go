@@ -0,0 +1,275 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"sigs.k8s.io/yaml"
+)
+
+// tomlSettingsKey the array of tables a TOML rule set is kept under, TOML has no top level arrays
+const tomlSettingsKey = "settings"
+
+// RuleFormatOf the format of a rule set file by its extension, .json, .yaml, .yml or .toml
+func RuleFormatOf(path string) (int, bool) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return RuleFormat.JSON, true
+	case ".yaml", ".yml":
+		return RuleFormat.YAML, true
+	case ".toml":
+		return RuleFormat.TOML, true
+	}
+	return 0, false
+}
+
+// MarshalRuleSettings write rs in format following the JSON model. YAML and TOML write a COMPLEX side
+// holding a JSON object as that object, TOML keeps the settings under [[settings]]
+func MarshalRuleSettings(rs []RuleSetting, format int) ([]byte, error) {
+	if rs == nil {
+		rs = []RuleSetting{}
+	}
+	if format == RuleFormat.JSON {
+		return json.MarshalIndent(rs, "", "  ")
+	}
+	if format != RuleFormat.YAML && format != RuleFormat.TOML {
+		return nil, fmt.Errorf("%w: rule format %d", RuleSettingError.UNSUPPORTED_OPERATION, format)
+	}
+
+	b, err := json.Marshal(rs)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := decodeJSONDoc(b)
+	if err != nil {
+		return nil, err
+	}
+	eachSide(doc, true, func(side map[string]interface{}, key string, complex bool) {
+		if !complex {
+			return
+		}
+		text, ok := side[key].(string)
+		if !ok || !strings.HasPrefix(strings.TrimSpace(text), "{") {
+			return
+		}
+		if obj, err := decodeJSONDoc([]byte(text)); err == nil {
+			side[key] = obj
+		}
+	})
+
+	if format == RuleFormat.YAML {
+		return yaml.Marshal(doc)
+	}
+	v, err := tomlValue(doc)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(map[string]interface{}{tomlSettingsKey: v}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalRuleSettings read a rule set written in format. Whatever the format a side may be written as
+// an object, kept as its JSON, or as a number, kept as its text
+func UnmarshalRuleSettings(b []byte, format int) ([]RuleSetting, error) {
+	var err error
+	switch format {
+	case RuleFormat.JSON:
+	case RuleFormat.YAML:
+		if b, err = yaml.YAMLToJSON(b); err != nil {
+			return nil, err
+		}
+	case RuleFormat.TOML:
+		var doc map[string]interface{}
+		if _, err := toml.Decode(string(b), &doc); err != nil {
+			return nil, err
+		}
+		settings, ok := doc[tomlSettingsKey]
+		if !ok {
+			settings = []interface{}{}
+		}
+		if b, err = json.Marshal(settings); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: rule format %d", RuleSettingError.UNSUPPORTED_OPERATION, format)
+	}
+
+	doc, err := decodeJSONDoc(b)
+	if err != nil {
+		return nil, err
+	}
+	eachSide(doc, false, func(side map[string]interface{}, key string, complex bool) {
+		switch v := side[key].(type) {
+		case json.Number:
+			side[key] = v.String()
+		case map[string]interface{}, []interface{}:
+			text, _ := json.Marshal(v)
+			side[key] = string(text)
+		}
+	})
+	if b, err = json.Marshal(doc); err != nil {
+		return nil, err
+	}
+	rs := []RuleSetting{}
+	if err := json.Unmarshal(b, &rs); err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+// ConvertRuleSettings rewrite a rule set from one format to another
+func ConvertRuleSettings(b []byte, from, to int) ([]byte, error) {
+	rs, err := UnmarshalRuleSettings(b, from)
+	if err != nil {
+		return nil, err
+	}
+	return MarshalRuleSettings(rs, to)
+}
+
+// MarshalRuleSettingsDB write rows of DB_TABLE_RULE in format, their Rule blob written out as a Rule
+func MarshalRuleSettingsDB(rows []RuleSettingDB, format int) ([]byte, error) {
+	rs := make([]RuleSetting, len(rows))
+	for i := range rows {
+		setting, err := rows[i].MakeObject()
+		if err != nil {
+			return nil, fmt.Errorf("setting %s: %w", rows[i].ID, err)
+		}
+		rs[i] = *setting
+	}
+	return MarshalRuleSettings(rs, format)
+}
+
+// UnmarshalRuleSettingsDB read a rule set written in format as rows of DB_TABLE_RULE, settings without an ID get one
+func UnmarshalRuleSettingsDB(b []byte, format int) ([]RuleSettingDB, error) {
+	rs, err := UnmarshalRuleSettings(b, format)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]RuleSettingDB, len(rs))
+	for i := range rs {
+		row, err := rs[i].MakeDBObject()
+		if err != nil {
+			return nil, err
+		}
+		rows[i] = *row
+	}
+	return rows, nil
+}
+
+func decodeJSONDoc(b []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// eachSide call fn with every left_side and right_side of the rule set in doc, as decodeJSONDoc reads it.
+// complex tells whether it is a modifer side typed COMPLEX, modifersOnly leaves the conditions out
+func eachSide(doc interface{}, modifersOnly bool, fn func(side map[string]interface{}, key string, complex bool)) {
+	settings, _ := doc.([]interface{})
+	for _, s := range settings {
+		setting, _ := s.(map[string]interface{})
+		r, _ := setting["rule"].(map[string]interface{})
+		if r == nil {
+			continue
+		}
+		for _, m := range asList(r["rate_modifer"]) {
+			for _, key := range []string{"left_side", "right_side"} {
+				typeKey := strings.TrimSuffix(key, "side") + "type"
+				fn(m, key, isComplexCode(m[typeKey]))
+			}
+		}
+		if modifersOnly {
+			continue
+		}
+		conditions := asList(r["condition_chain"])
+		groups := []interface{}{r["condition_tree"]}
+		for len(groups) > 0 {
+			g, _ := groups[0].(map[string]interface{})
+			groups = groups[1:]
+			if g == nil {
+				continue
+			}
+			conditions = append(conditions, asList(g["conditions"])...)
+			if sub, ok := g["groups"].([]interface{}); ok {
+				groups = append(groups, sub...)
+			}
+		}
+		for _, c := range conditions {
+			fn(c, "left_side", false)
+			fn(c, "right_side", false)
+		}
+	}
+}
+
+func asList(v interface{}) []map[string]interface{} {
+	items, _ := v.([]interface{})
+	out := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		if m, ok := item.(map[string]interface{}); ok {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// isComplexCode whether a side type read from JSON is ModiferSideType.COMPLEX, by name or number
+func isComplexCode(v interface{}) bool {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return false
+	}
+	var code SideCode
+	return json.Unmarshal(b, &code) == nil && code == ModiferSideType.COMPLEX
+}
+
+// tomlValue doc as TOML can hold it, numbers as int64 or float64 and null members left out
+func tomlValue(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n, nil
+		}
+		return v.Float64()
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			if item == nil {
+				continue
+			}
+			tv, err := tomlValue(item)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = tv
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			if item == nil {
+				return nil, fmt.Errorf("%w: TOML cannot hold null in an array", RuleSettingError.VALUE_INVALID)
+			}
+			tv, err := tomlValue(item)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = tv
+		}
+		return out, nil
+	}
+	return v, nil
+}
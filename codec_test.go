@@ -0,0 +1,223 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// complexSettings settings with COMPLEX sides holding objects, a selection and plain text, the objects written as
+// json.Marshal writes a map so they read back the same
+func complexSettings() []RuleSetting {
+	complexModifer := func(operand OperandCode, left, right string) Modifer {
+		return Modifer{
+			Operand:     operand,
+			DataType:    ModiferDataType.INT,
+			LeftSide:    left,
+			LeftType:    ModiferSideType.COMPLEX,
+			RightSide:   right,
+			RightType:   ModiferSideType.COMPLEX,
+			TargetField: "Price",
+		}
+	}
+	discount := setting("rates", 1, []Condition{intCondition("Adults", RuleConditionCompare.MORE_EQUAL, "2")},
+		withRounding(complexModifer(RuleOperand.SUB, `{"flat":500,"percentage":10}`, `{"flat":0,"percentage":0,"select":[{"key":"gold","name":"Gold","value":"15"},{"key":"silver","name":"Silver","value":"5"}]}`),
+			&Rounding{Mode: RoundingMode.HALF_UP, Places: -2}),
+		complexModifer(RuleOperand.ADD, "raw", `{"flat":-20,"percentage":0}`),
+	)
+	discount.Rule.ConditionTree = &ConditionGroup{
+		Mode:       ConditionGroupMode.ANY,
+		Conditions: []Condition{intCondition("Price", RuleConditionCompare.LESS, "1000")},
+	}
+	return []RuleSetting{discount, setting("rates", 2, nil, intModifer(RuleOperand.ADD, "Price", "10"))}
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		format  int
+		written []string
+	}{
+		{"JSON", RuleFormat.JSON, []string{`"left_side": "{\"flat\":500,\"percentage\":10}"`, `"mode": "HALF_UP"`}},
+		{"YAML", RuleFormat.YAML, []string{"flat: 500", "percentage: 10", "key: gold", "flat: -20", "left_side: raw", "mode: HALF_UP"}},
+		{"TOML", RuleFormat.TOML, []string{"flat = 500", "percentage = 10", `key = "gold"`, "flat = -20", `left_side = "raw"`, "[[settings]]"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rs := complexSettings()
+			b, err := MarshalRuleSettings(rs, tc.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, written := range tc.written {
+				if !strings.Contains(string(b), written) {
+					t.Errorf("written as\n%s\nwant %s in it", b, written)
+				}
+			}
+
+			back, err := UnmarshalRuleSettings(b, tc.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(back, rs) {
+				t.Fatalf("read back as %+v, want %+v", back, rs)
+			}
+		})
+	}
+}
+
+func TestConvertRuleSettings(t *testing.T) {
+	rs := complexSettings()
+	b, err := MarshalRuleSettings(rs, RuleFormat.JSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// through every format and back to where it started
+	for _, hop := range [][2]int{
+		{RuleFormat.JSON, RuleFormat.YAML},
+		{RuleFormat.YAML, RuleFormat.TOML},
+		{RuleFormat.TOML, RuleFormat.YAML},
+		{RuleFormat.YAML, RuleFormat.JSON},
+	} {
+		if b, err = ConvertRuleSettings(b, hop[0], hop[1]); err != nil {
+			t.Fatalf("%d to %d: %v", hop[0], hop[1], err)
+		}
+	}
+	back, err := UnmarshalRuleSettings(b, RuleFormat.JSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(back, rs) {
+		t.Fatalf("read back as %+v, want %+v", back, rs)
+	}
+}
+
+func TestUnmarshalRuleSettingsWritten(t *testing.T) {
+	// written by hand, sides as objects and numbers, codes by name and number
+	yamlDoc := `
+- id: a
+  rule_id: rates
+  enable: true
+  sequence: 1
+  rule:
+    condition_chain:
+      - {type: INT, left_side: Adults, left_type: FIELD, compare: MORE_EQUAL, right_side: 2, right_type: 118}
+    rate_modifer:
+      - operand: SUB
+        data_type: INT
+        left_side: Price
+        left_type: FIELD
+        right_side: {flat: 500, percentage: 10}
+        right_type: COMPLEX
+        target_field: Price
+        rounding: {mode: half_up, places: -2}
+`
+	tomlDoc := `
+[[settings]]
+id = "a"
+rule_id = "rates"
+enable = true
+sequence = 1
+
+[[settings.rule.condition_chain]]
+type = "INT"
+left_side = "Adults"
+left_type = "FIELD"
+compare = 3
+right_side = 2
+right_type = "VALUE"
+
+[[settings.rule.rate_modifer]]
+operand = "SUB"
+data_type = 1
+left_side = "Price"
+left_type = "FIELD"
+right_side = { flat = 500, percentage = 10 }
+right_type = "COMPLEX"
+target_field = "Price"
+rounding = { mode = 1, places = -2 }
+`
+	for _, tc := range []struct {
+		name   string
+		format int
+		doc    string
+	}{
+		{"YAML", RuleFormat.YAML, yamlDoc},
+		{"TOML", RuleFormat.TOML, tomlDoc},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rs, err := UnmarshalRuleSettings([]byte(tc.doc), tc.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rs) != 1 || len(rs[0].Rule.ConditionChain) != 1 || len(rs[0].Rule.ModiferChain) != 1 {
+				t.Fatalf("UnmarshalRuleSettings = %+v, want one setting of one condition and one modifer", rs)
+			}
+			c, m := rs[0].Rule.ConditionChain[0], rs[0].Rule.ModiferChain[0]
+			if c.RightSide != "2" || c.RightType != ConditionSideType.VALUE || c.Compare != RuleConditionCompare.MORE_EQUAL {
+				t.Errorf("condition read as %+v", c)
+			}
+			var mc ModiferComplex
+			if err := json.Unmarshal([]byte(m.RightSide), &mc); err != nil || mc.Flat != 500 || mc.Percent != 10 {
+				t.Errorf("complex side read as %s, %v", m.RightSide, err)
+			}
+			if m.Operand != RuleOperand.SUB || m.RightType != ModiferSideType.COMPLEX || m.Rounding == nil || m.Rounding.Mode != RoundingMode.HALF_UP {
+				t.Errorf("modifer read as %+v", m)
+			}
+
+			b := &booking{Adults: 2, Price: 10000}
+			if _, err := NewEngine(stubSupply{}).ApplySettings(b, rs); err != nil {
+				t.Fatal(err)
+			}
+			if b.Price != 9500 {
+				t.Errorf("Price = %d, want 9500, the smaller of 500 and 10%% taken off", b.Price)
+			}
+		})
+	}
+}
+
+func TestRuleSettingsDBCodec(t *testing.T) {
+	rs := complexSettings()
+	rows := make([]RuleSettingDB, len(rs))
+	for i := range rs {
+		row, err := rs[i].MakeDBObject()
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows[i] = *row
+	}
+	for _, format := range []int{RuleFormat.JSON, RuleFormat.YAML, RuleFormat.TOML} {
+		b, err := MarshalRuleSettingsDB(rows, format)
+		if err != nil {
+			t.Fatalf("format %d: %v", format, err)
+		}
+		back, err := UnmarshalRuleSettingsDB(b, format)
+		if err != nil {
+			t.Fatalf("format %d: %v", format, err)
+		}
+		if !reflect.DeepEqual(back, rows) {
+			t.Fatalf("format %d: read back as %+v, want %+v", format, back, rows)
+		}
+	}
+}
+
+func TestCodecUnsupportedFormat(t *testing.T) {
+	if _, err := MarshalRuleSettings(complexSettings(), 7); !errors.Is(err, RuleSettingError.UNSUPPORTED_OPERATION) {
+		t.Errorf("MarshalRuleSettings = %v, want UNSUPPORTED_OPERATION", err)
+	}
+	if _, err := UnmarshalRuleSettings([]byte("[]"), 7); !errors.Is(err, RuleSettingError.UNSUPPORTED_OPERATION) {
+		t.Errorf("UnmarshalRuleSettings = %v, want UNSUPPORTED_OPERATION", err)
+	}
+	for path, want := range map[string]int{"rates.json": RuleFormat.JSON, "rates.YML": RuleFormat.YAML, "a/rates.toml": RuleFormat.TOML} {
+		if format, ok := RuleFormatOf(path); !ok || format != want {
+			t.Errorf("RuleFormatOf(%s) = %d, %v", path, format, ok)
+		}
+	}
+	if _, ok := RuleFormatOf("rates.ini"); ok {
+		t.Error("RuleFormatOf(rates.ini) found a format, want none")
+	}
+}
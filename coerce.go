@@ -0,0 +1,148 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+var complexType = reflect.TypeOf(ModiferComplex{})
+
+// intOf read ints as they are, integral floats and numeric strings such as json.Number as ints
+func intOf(v reflect.Value) (int64, error) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v.Uint() > math.MaxInt64 {
+			return 0, fmt.Errorf("%w: integer overflow %d", RuleSettingError.VALUE_INVALID, v.Uint())
+		}
+		return int64(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if f != math.Trunc(f) || f > math.MaxInt64 || f < math.MinInt64 {
+			return 0, fmt.Errorf("%w: integer %v", RuleSettingError.VALUE_INVALID, f)
+		}
+		return int64(f), nil
+	case reflect.String:
+		i, err := strconv.ParseInt(strings.TrimSpace(v.String()), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%w: integer %s", RuleSettingError.VALUE_INVALID, v.String())
+		}
+		return i, nil
+	}
+	return 0, fmt.Errorf("%w: integer field of kind %s", RuleSettingError.FIELD_TYPE_MISMATCH, v.Kind())
+}
+
+// stringOf read strings as they are and numbers or bools in their plain text form
+func stringOf(v reflect.Value) (string, error) {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	}
+	return "", fmt.Errorf("%w: string field of kind %s", RuleSettingError.FIELD_TYPE_MISMATCH, v.Kind())
+}
+
+// timeOf read time.Time as it is, numbers as unix seconds and strings as RFC 3339 or unix seconds
+func timeOf(v reflect.Value) (time.Time, error) {
+	if v.Type() == timeType && v.CanInterface() {
+		return v.Interface().(time.Time), nil
+	}
+	if v.Kind() == reflect.String {
+		s := strings.TrimSpace(v.String())
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			return t, nil
+		}
+		if t, err := time.Parse("2006-01-02", s); err == nil {
+			return t, nil
+		}
+	}
+	i, err := intOf(v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: date field of kind %s", RuleSettingError.FIELD_TYPE_MISMATCH, v.Kind())
+	}
+	return time.Unix(i, 0), nil
+}
+
+// stringsOf read []string as it is, other slices element by element
+func stringsOf(v reflect.Value) ([]string, error) {
+	if v.CanInterface() {
+		if ss, ok := v.Interface().([]string); ok {
+			return ss, nil
+		}
+	}
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("%w: list field of kind %s", RuleSettingError.FIELD_TYPE_MISMATCH, v.Kind())
+	}
+	ss := make([]string, v.Len())
+	for i := range ss {
+		e, err := deref(v.Index(i), "")
+		if err != nil {
+			return nil, err
+		}
+		if ss[i], err = stringOf(e); err != nil {
+			return nil, err
+		}
+	}
+	return ss, nil
+}
+
+// complexOf read ModiferComplex as it is, decoded JSON objects through their JSON form
+func complexOf(v reflect.Value) (ModiferComplex, error) {
+	if v.Type() == complexType && v.CanInterface() {
+		return v.Interface().(ModiferComplex), nil
+	}
+	var mc ModiferComplex
+	if v.Kind() != reflect.Map || !v.CanInterface() {
+		return mc, fmt.Errorf("%w: complex field of kind %s", RuleSettingError.FIELD_TYPE_MISMATCH, v.Kind())
+	}
+	b, err := json.Marshal(v.Interface())
+	if err != nil {
+		return mc, err
+	}
+	err = json.Unmarshal(b, &mc)
+	return mc, err
+}
+
+// assignInt write i into an int field or into an interface such as a document map element
+func assignInt(v reflect.Value, i int64) error {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(i)
+		return nil
+	case reflect.Interface:
+		v.Set(reflect.ValueOf(i))
+		return nil
+	}
+	return fmt.Errorf("%w: integer target field of kind %s", RuleSettingError.FIELD_TYPE_MISMATCH, v.Kind())
+}
+
+// assignString write s into a string field or into an interface such as a document map element
+func assignString(v reflect.Value, s string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+		return nil
+	case reflect.Interface:
+		v.Set(reflect.ValueOf(s))
+		return nil
+	}
+	return fmt.Errorf("%w: string target field of kind %s", RuleSettingError.FIELD_TYPE_MISMATCH, v.Kind())
+}
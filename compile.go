@@ -0,0 +1,882 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// CompiledRuleSet a rule set prepared once for requests of one type: VALUE sides are parsed,
+// complex values decoded, modifers sorted and field paths resolved to indices.
+// DATE and DAY_OF_WEEK conditions, condition trees, DECIMAL modifers and jumps still run through the engine.
+// Condition chains are always checked in order, one at a time: the prepared checks cost less than the goroutines
+// of ConditionStrategy.PARALLEL, so WithConditionStrategy and WithParallelism are not used here.
+// It is safe for concurrent use
+type CompiledRuleSet struct {
+	re       *ruleEngine
+	t        reflect.Type
+	rs       []RuleSetting
+	settings []compiledSetting
+}
+
+type compiledSetting struct {
+	rs         RuleSetting
+	conditions []conditionFunc
+	modifers   []compiledModifer
+	err        error // why a setting the engine may skip did not compile, returned once it is not skipped
+}
+
+type compiledModifer struct {
+	rm    Modifer
+	apply modiferFunc // nil for JMP
+}
+
+type conditionFunc func(v reflect.Value, rqr interface{}) (bool, error)
+
+type modiferFunc func(ev *evaluation, v reflect.Value, rqr interface{}) (bool, error)
+
+// Compile prepare rs for requests of type t, a struct or a map such as map[string]interface{}.
+// A disabled setting, or one with an activation window, that does not compile fails Apply only when it
+// is not skipped, the way the engine only fails on the settings it runs
+func (re *ruleEngine) Compile(rs []RuleSetting, t reflect.Type) (*CompiledRuleSet, error) {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || (t.Kind() != reflect.Struct && t.Kind() != reflect.Map) {
+		return nil, fmt.Errorf("%w: cannot compile for %v", RuleSettingError.FIELD_TYPE_MISMATCH, t)
+	}
+	if !re.checkSettingSequence(rs) {
+		return nil, RuleSettingError.SETTING_NOT_IN_ORDER
+	}
+
+	cs := &CompiledRuleSet{
+		re:       re,
+		t:        t,
+		rs:       rs,
+		settings: make([]compiledSetting, len(rs)),
+	}
+	for i, setting := range rs {
+		s, err := re.compileSetting(t, setting)
+		if err != nil {
+			if (setting.Enable || re.includeDisabled) && setting.ValidFrom == nil && setting.ValidTo == nil {
+				return nil, err
+			}
+			s = compiledSetting{rs: setting, err: err}
+		}
+		cs.settings[i] = s
+	}
+	return cs, nil
+}
+
+func (re *ruleEngine) compileSetting(t reflect.Type, rs RuleSetting) (compiledSetting, error) {
+	s := compiledSetting{
+		rs:         rs,
+		conditions: make([]conditionFunc, len(rs.Rule.ConditionChain)),
+		modifers:   make([]compiledModifer, len(rs.Rule.ModiferChain)),
+	}
+	for i, c := range rs.Rule.ConditionChain {
+		check, err := re.compileCondition(t, c)
+		if err != nil {
+			return s, ruleError(rs, i, -1, conditionField(c), err)
+		}
+		s.conditions[i] = check
+	}
+
+	modifers := make([]Modifer, len(rs.Rule.ModiferChain))
+	copy(modifers, rs.Rule.ModiferChain)
+	sort.Slice(modifers, func(i, j int) bool {
+		return modifers[i].Sequence < modifers[j].Sequence
+	})
+	for i, rm := range modifers {
+		apply, err := re.compileModifer(t, rm)
+		if err != nil {
+			return s, ruleError(rs, -1, i, rm.TargetField, err)
+		}
+		s.modifers[i] = compiledModifer{rm: rm, apply: apply}
+	}
+	return s, nil
+}
+
+// Apply ApplySettings with the compiled rule set, rqr must be a pointer to the compiled type or the map itself
+func (cs *CompiledRuleSet) Apply(rqr interface{}) (bool, error) {
+	return cs.ApplyContext(context.Background(), rqr)
+}
+
+// ApplyContext Apply bound to ctx
+func (cs *CompiledRuleSet) ApplyContext(ctx context.Context, rqr interface{}) (bool, error) {
+	if cs.t.Kind() == reflect.Map {
+		doc, err := openDocument(rqr)
+		if err != nil {
+			return false, err
+		}
+		result, err := cs.apply(ctx, doc.rqr)
+		if cerr := doc.close(); err == nil {
+			err = cerr
+		}
+		return result, err
+	}
+	return cs.apply(ctx, rqr)
+}
+
+func (cs *CompiledRuleSet) apply(ctx context.Context, rqr interface{}) (bool, error) {
+	v := reflect.ValueOf(rqr)
+	if !v.IsValid() || (v.Type() != reflect.PtrTo(cs.t) && (cs.t.Kind() != reflect.Map || v.Type() != cs.t)) {
+		return false, fmt.Errorf("%w: compiled for %s, got %T", RuleSettingError.FIELD_TYPE_MISMATCH, cs.t, rqr)
+	}
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return false, fmt.Errorf("%w: request", RuleSettingError.FIELD_NIL)
+	}
+
+	ev := newEvaluation(ctx).at(cs.re.clock()).enter(cs.rs...)
+	for _, s := range cs.settings {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		if cs.re.skip(ev, s.rs) != SettingSkip.NONE {
+			continue
+		}
+		if s.err != nil {
+			return false, s.err
+		}
+		result, br, err := s.apply(cs.re, ev, v, rqr)
+		if err != nil {
+			return false, ruleError(s.rs, -1, -1, "", err)
+		}
+		if br {
+			return result, nil
+		}
+	}
+	return true, nil
+}
+
+func (s *compiledSetting) apply(re *ruleEngine, ev *evaluation, v reflect.Value, rqr interface{}) (_ bool, _ bool, err error) {
+	defer recoverError(&err)
+
+	matched := true
+	for i, check := range s.conditions {
+		if err := ev.ctx.Err(); err != nil {
+			return false, false, err
+		}
+		result, err := check(v, rqr)
+		if err != nil {
+			return false, false, ruleError(s.rs, i, -1, conditionField(s.rs.Rule.ConditionChain[i]), err)
+		}
+		if !result {
+			matched = false
+			break
+		}
+	}
+	if matched && s.rs.Rule.ConditionTree != nil {
+		result, err := re.checkConditionGroup(rqr, *s.rs.Rule.ConditionTree, nil)
+		if err != nil {
+			return false, false, err
+		}
+		matched = result
+	}
+	if !matched {
+		return false, s.rs.BreakOnFail, nil
+	}
+
+	for i, m := range s.modifers {
+		if err := ev.ctx.Err(); err != nil {
+			return false, false, err
+		}
+		if m.apply == nil {
+			// Jump then leave
+			result, err := re.applyModiferJump(ev, nil, rqr, m.rm)
+			return result, true, ruleError(s.rs, -1, i, "", err)
+		}
+		result, err := m.apply(ev, v, rqr)
+		if err != nil {
+			return false, false, ruleError(s.rs, -1, i, m.rm.TargetField, err)
+		}
+		if !result {
+			return false, false, nil
+		}
+	}
+	return true, false, nil
+}
+
+// accessor a field path resolved against the compiled type,
+// dynamic paths run through an interface and are resolved on every call
+type accessor struct {
+	path    string
+	steps   []accessStep
+	dynamic bool
+	keyed   bool // a map sits on the path so writes go through setField
+}
+
+// accessStep one of a struct field index, a map key or a slice index
+type accessStep struct {
+	field []int
+	key   reflect.Value
+	index int
+	at    string // the path up to and including the step, for errors
+}
+
+func compileAccessor(t reflect.Type, path string) (*accessor, error) {
+	steps, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	a := &accessor{path: path}
+	at := ""
+	for _, ps := range steps {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		at = joinPath(at, ps)
+		switch {
+		case t.Kind() == reflect.Interface:
+			return &accessor{path: path, dynamic: true}, nil
+		case t.Kind() == reflect.Map:
+			k, err := mapKey(t.Key(), ps)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", err, at)
+			}
+			a.steps = append(a.steps, accessStep{key: k, at: at})
+			a.keyed = true
+			t = t.Elem()
+		case ps.name != "" && t.Kind() == reflect.Struct:
+			f, ok := t.FieldByName(ps.name)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s", RuleSettingError.MODIFER_FEILD_NOT_EXISTED, at)
+			}
+			a.steps = append(a.steps, accessStep{field: f.Index, at: at})
+			t = f.Type
+		case ps.name == "" && !ps.key && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array):
+			i, err := strconv.Atoi(ps.index)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", RuleSettingError.FIELD_PATH_INVALID, at)
+			}
+			if i < 0 || (t.Kind() == reflect.Array && i >= t.Len()) {
+				return nil, fmt.Errorf("%w: %s", RuleSettingError.MODIFER_FEILD_NOT_EXISTED, at)
+			}
+			a.steps = append(a.steps, accessStep{index: i, at: at})
+			t = t.Elem()
+		default:
+			return nil, fmt.Errorf("%w: %s", RuleSettingError.MODIFER_FEILD_NOT_EXISTED, at)
+		}
+	}
+	return a, nil
+}
+
+// walk follow the steps from v, the value reached is not dereferenced
+func (a *accessor) walk(v reflect.Value) (reflect.Value, error) {
+	var err error
+	at := ""
+	for _, s := range a.steps {
+		if v, err = deref(v, at); err != nil {
+			return v, err
+		}
+		at = s.at
+		switch {
+		case s.field != nil:
+			if v, err = v.FieldByIndexErr(s.field); err != nil {
+				return v, fmt.Errorf("%w: %s", RuleSettingError.FIELD_NIL, at)
+			}
+		case s.key.IsValid():
+			if v = v.MapIndex(s.key); !v.IsValid() {
+				return v, fmt.Errorf("%w: %s", RuleSettingError.MODIFER_FEILD_NOT_EXISTED, at)
+			}
+		default:
+			if s.index >= v.Len() {
+				return v, fmt.Errorf("%w: %s", RuleSettingError.MODIFER_FEILD_NOT_EXISTED, at)
+			}
+			v = v.Index(s.index)
+		}
+	}
+	return v, nil
+}
+
+func (a *accessor) get(v reflect.Value) (reflect.Value, error) {
+	if a.dynamic {
+		return fieldValue(v.Interface(), a.path)
+	}
+	v, err := a.walk(v)
+	if err != nil {
+		return v, err
+	}
+	return deref(v, a.path)
+}
+
+func (a *accessor) set(v reflect.Value, set func(v reflect.Value) error) error {
+	if a.dynamic || a.keyed {
+		return setField(v.Interface(), a.path, set)
+	}
+	v, err := a.walk(v)
+	if err != nil {
+		return err
+	}
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			if !v.CanSet() {
+				return fmt.Errorf("%w: %s", RuleSettingError.FIELD_NIL, a.path)
+			}
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if !v.CanSet() {
+		return fmt.Errorf("%w: %s", RuleSettingError.MODIFER_FEILD_NOT_EXISTED, a.path)
+	}
+	return set(v)
+}
+
+type intSide func(v reflect.Value) (int64, error)
+
+type stringSide func(v reflect.Value) (string, error)
+
+type decimalSide func(v reflect.Value) (decimal.Decimal, error)
+
+type complexSide func(v reflect.Value) (*ModiferComplex, error)
+
+// compileIntSide condition and modifer sides share the FIELD and VALUE codes, invalid is returned for any other
+func compileIntSide(t reflect.Type, side string, sideType SideCode, invalid error) (intSide, error) {
+	switch sideType {
+	case ConditionSideType.VALUE:
+		i, err := strconv.ParseInt(side, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", RuleSettingError.VALUE_INVALID, side)
+		}
+		return func(reflect.Value) (int64, error) {
+			return i, nil
+		}, nil
+	case ConditionSideType.FIELD:
+		a, err := compileAccessor(t, side)
+		if err != nil {
+			return nil, err
+		}
+		return func(v reflect.Value) (int64, error) {
+			f, err := a.get(v)
+			if err != nil {
+				return 0, err
+			}
+			return intOf(f)
+		}, nil
+	}
+	return nil, invalid
+}
+
+func compileStringSide(t reflect.Type, side string, sideType SideCode, invalid error) (stringSide, error) {
+	switch sideType {
+	case ConditionSideType.VALUE:
+		return func(reflect.Value) (string, error) {
+			return side, nil
+		}, nil
+	case ConditionSideType.FIELD:
+		a, err := compileAccessor(t, side)
+		if err != nil {
+			return nil, err
+		}
+		return func(v reflect.Value) (string, error) {
+			f, err := a.get(v)
+			if err != nil {
+				return "", err
+			}
+			return stringOf(f)
+		}, nil
+	}
+	return nil, invalid
+}
+
+func compileDecimalSide(t reflect.Type, side string, sideType SideCode, invalid error) (decimalSide, error) {
+	switch sideType {
+	case ConditionSideType.VALUE:
+		d, err := decimal.NewFromString(side)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", RuleSettingError.VALUE_INVALID, side)
+		}
+		return func(reflect.Value) (decimal.Decimal, error) {
+			return d, nil
+		}, nil
+	case ConditionSideType.FIELD:
+		a, err := compileAccessor(t, side)
+		if err != nil {
+			return nil, err
+		}
+		return func(v reflect.Value) (decimal.Decimal, error) {
+			f, err := a.get(v)
+			if err != nil {
+				return decimal.Zero, err
+			}
+			return decimalOf(f)
+		}, nil
+	}
+	return nil, invalid
+}
+
+func compileComplexSide(t reflect.Type, rm Modifer) (complexSide, error) {
+	switch rm.RightType {
+	case ModiferSideType.COMPLEX:
+		mc := new(ModiferComplex)
+		if err := json.Unmarshal([]byte(rm.RightSide), mc); err != nil {
+			return nil, fmt.Errorf("%w: %v", RuleSettingError.MODIFER_SIDE_INVALID, err)
+		}
+		return func(reflect.Value) (*ModiferComplex, error) {
+			return mc, nil
+		}, nil
+	case ModiferSideType.FIELD:
+		a, err := compileAccessor(t, rm.RightSide)
+		if err != nil {
+			return nil, err
+		}
+		return func(v reflect.Value) (*ModiferComplex, error) {
+			f, err := a.get(v)
+			if err != nil {
+				return nil, err
+			}
+			mc, err := complexOf(f)
+			return &mc, err
+		}, nil
+	}
+	return nil, RuleSettingError.MODIFER_SIDE_INVALID
+}
+
+// compileList the items of an IN or NOT_IN right side, split once when it is a VALUE
+func compileList(t reflect.Type, side string, sideType SideCode) (func(v reflect.Value) ([]string, error), error) {
+	read, err := compileStringSide(t, side, sideType, RuleSettingError.CONDITION_SIDE_INVALID)
+	if err != nil {
+		return nil, err
+	}
+	if sideType == ConditionSideType.VALUE {
+		items := strings.Split(side, ",")
+		return func(reflect.Value) ([]string, error) {
+			return items, nil
+		}, nil
+	}
+	return func(v reflect.Value) ([]string, error) {
+		s, err := read(v)
+		if err != nil {
+			return nil, err
+		}
+		return strings.Split(s, ","), nil
+	}, nil
+}
+
+func (re *ruleEngine) compileCondition(t reflect.Type, c Condition) (conditionFunc, error) {
+	switch c.Type {
+	case RuleConditionType.MUST:
+		return func(reflect.Value, interface{}) (bool, error) {
+			return true, nil
+		}, nil
+	case RuleConditionType.INT, RuleConditionType.STRING, RuleConditionType.DECIMAL:
+		if !slices.Contains(conditionCompares[c.Type], c.Compare) {
+			return nil, RuleSettingError.UNSUPPORTED_OPERATION
+		}
+	default:
+		return func(_ reflect.Value, rqr interface{}) (bool, error) {
+			return re.checkRuleCondition(rqr, c, nil)
+		}, nil
+	}
+
+	in := c.Compare == RuleConditionCompare.IN || c.Compare == RuleConditionCompare.NOT_IN
+	switch c.Type {
+	case RuleConditionType.INT:
+		left, err := compileIntSide(t, c.LeftSide, c.LeftType, RuleSettingError.CONDITION_SIDE_INVALID)
+		if err != nil {
+			return nil, err
+		}
+		if in {
+			return compileIntIn(t, c, left)
+		}
+		right, err := compileIntSide(t, c.RightSide, c.RightType, RuleSettingError.CONDITION_SIDE_INVALID)
+		if err != nil {
+			return nil, err
+		}
+		return func(v reflect.Value, _ interface{}) (bool, error) {
+			vl, err := left(v)
+			if err != nil {
+				return false, err
+			}
+			vr, err := right(v)
+			if err != nil {
+				return false, err
+			}
+			switch {
+			case vl < vr:
+				return compareResult(c.Compare, -1), nil
+			case vl > vr:
+				return compareResult(c.Compare, 1), nil
+			}
+			return compareResult(c.Compare, 0), nil
+		}, nil
+	case RuleConditionType.DECIMAL:
+		left, err := compileDecimalSide(t, c.LeftSide, c.LeftType, RuleSettingError.CONDITION_SIDE_INVALID)
+		if err != nil {
+			return nil, err
+		}
+		if in {
+			return compileDecimalIn(t, c, left)
+		}
+		right, err := compileDecimalSide(t, c.RightSide, c.RightType, RuleSettingError.CONDITION_SIDE_INVALID)
+		if err != nil {
+			return nil, err
+		}
+		return func(v reflect.Value, _ interface{}) (bool, error) {
+			vl, err := left(v)
+			if err != nil {
+				return false, err
+			}
+			vr, err := right(v)
+			if err != nil {
+				return false, err
+			}
+			return compareResult(c.Compare, vl.Cmp(vr)), nil
+		}, nil
+	}
+
+	left, err := compileStringSide(t, c.LeftSide, c.LeftType, RuleSettingError.CONDITION_SIDE_INVALID)
+	if err != nil {
+		return nil, err
+	}
+	right, err := compileStringSide(t, c.RightSide, c.RightType, RuleSettingError.CONDITION_SIDE_INVALID)
+	if err != nil {
+		return nil, err
+	}
+	return func(v reflect.Value, _ interface{}) (bool, error) {
+		vl, err := left(v)
+		if err != nil {
+			return false, err
+		}
+		vr, err := right(v)
+		if err != nil {
+			return false, err
+		}
+		return (vl == vr) == (c.Compare == RuleConditionCompare.EQUAL), nil
+	}, nil
+}
+
+func compileIntIn(t reflect.Type, c Condition, left intSide) (conditionFunc, error) {
+	list, err := compileList(t, c.RightSide, c.RightType)
+	if err != nil {
+		return nil, err
+	}
+	var values []int64
+	if c.RightType == ConditionSideType.VALUE {
+		for _, item := range strings.Split(c.RightSide, ",") {
+			i, err := strconv.ParseInt(item, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", RuleSettingError.VALUE_INVALID, item)
+			}
+			values = append(values, i)
+		}
+	}
+	return func(v reflect.Value, _ interface{}) (bool, error) {
+		vl, err := left(v)
+		if err != nil {
+			return false, err
+		}
+		if values != nil {
+			for _, i := range values {
+				if i == vl {
+					return c.Compare == RuleConditionCompare.IN, nil
+				}
+			}
+			return c.Compare == RuleConditionCompare.NOT_IN, nil
+		}
+		items, err := list(v)
+		if err != nil {
+			return false, err
+		}
+		for _, item := range items {
+			i, err := strconv.ParseInt(item, 10, 64)
+			if err != nil {
+				return false, err
+			}
+			if i == vl {
+				return c.Compare == RuleConditionCompare.IN, nil
+			}
+		}
+		return c.Compare == RuleConditionCompare.NOT_IN, nil
+	}, nil
+}
+
+func compileDecimalIn(t reflect.Type, c Condition, left decimalSide) (conditionFunc, error) {
+	list, err := compileList(t, c.RightSide, c.RightType)
+	if err != nil {
+		return nil, err
+	}
+	var values []decimal.Decimal
+	if c.RightType == ConditionSideType.VALUE {
+		for _, item := range strings.Split(c.RightSide, ",") {
+			d, err := decimal.NewFromString(strings.TrimSpace(item))
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", RuleSettingError.VALUE_INVALID, item)
+			}
+			values = append(values, d)
+		}
+	}
+	return func(v reflect.Value, _ interface{}) (bool, error) {
+		vl, err := left(v)
+		if err != nil {
+			return false, err
+		}
+		if values != nil {
+			for _, d := range values {
+				if d.Equal(vl) {
+					return c.Compare == RuleConditionCompare.IN, nil
+				}
+			}
+			return c.Compare == RuleConditionCompare.NOT_IN, nil
+		}
+		items, err := list(v)
+		if err != nil {
+			return false, err
+		}
+		for _, item := range items {
+			d, err := decimal.NewFromString(strings.TrimSpace(item))
+			if err != nil {
+				return false, err
+			}
+			if d.Equal(vl) {
+				return c.Compare == RuleConditionCompare.IN, nil
+			}
+		}
+		return c.Compare == RuleConditionCompare.NOT_IN, nil
+	}, nil
+}
+
+// compareResult the outcome of an ordered compare given cmp, negative when left is smaller
+func compareResult(compare CompareCode, cmp int) bool {
+	switch compare {
+	case RuleConditionCompare.EQUAL:
+		return cmp == 0
+	case RuleConditionCompare.NOT:
+		return cmp != 0
+	case RuleConditionCompare.MORE:
+		return cmp > 0
+	case RuleConditionCompare.LESS:
+		return cmp < 0
+	case RuleConditionCompare.MORE_EQUAL:
+		return cmp >= 0
+	case RuleConditionCompare.LESS_EQUAL:
+		return cmp <= 0
+	}
+	return false
+}
+
+func (re *ruleEngine) compileModifer(t reflect.Type, rm Modifer) (modiferFunc, error) {
+	switch rm.DataType {
+	case ModiferDataType.JMP:
+		return nil, nil
+	case ModiferDataType.INT:
+		if !slices.Contains(modiferOperands[rm.DataType], rm.Operand) {
+			return nil, RuleSettingError.UNSUPPORTED_OPERATION
+		}
+		target, err := compileAccessor(t, rm.TargetField)
+		if err != nil {
+			return nil, err
+		}
+		return re.compileModiferInt(t, rm, target)
+	case ModiferDataType.STRING:
+		if !slices.Contains(modiferOperands[rm.DataType], rm.Operand) {
+			return nil, RuleSettingError.UNSUPPORTED_OPERATION
+		}
+		target, err := compileAccessor(t, rm.TargetField)
+		if err != nil {
+			return nil, err
+		}
+		return compileModiferString(t, rm, target)
+	}
+	return func(ev *evaluation, _ reflect.Value, rqr interface{}) (bool, error) {
+		return re.applyModifer(ev, nil, rqr, rm)
+	}, nil
+}
+
+func setInt(v reflect.Value, target *accessor, i int64) (bool, error) {
+	err := target.set(v, func(v reflect.Value) error {
+		return assignInt(v, i)
+	})
+	return err == nil, err
+}
+
+func setString(v reflect.Value, target *accessor, s string) (bool, error) {
+	err := target.set(v, func(v reflect.Value) error {
+		return assignString(v, s)
+	})
+	return err == nil, err
+}
+
+func compileModiferString(t reflect.Type, rm Modifer, target *accessor) (modiferFunc, error) {
+	if rm.Operand == RuleOperand.SET {
+		left, err := compileStringSide(t, rm.LeftSide, rm.LeftType, RuleSettingError.MODIFER_SIDE_INVALID)
+		if err != nil {
+			return nil, err
+		}
+		return func(_ *evaluation, v reflect.Value, _ interface{}) (bool, error) {
+			vl, err := left(v)
+			if err != nil {
+				return false, err
+			}
+			return setString(v, target, vl)
+		}, nil
+	}
+
+	// SEL
+	key, err := compileStringSide(t, rm.LeftSide, ModiferSideType.FIELD, RuleSettingError.MODIFER_SIDE_INVALID)
+	if err != nil {
+		return nil, err
+	}
+	right, err := compileComplexSide(t, rm)
+	if err != nil {
+		return nil, err
+	}
+	return func(_ *evaluation, v reflect.Value, _ interface{}) (bool, error) {
+		selectWhat, err := key(v)
+		if err != nil {
+			return false, err
+		}
+		vr, err := right(v)
+		if err != nil {
+			return false, err
+		}
+		if sel := vr.Select(selectWhat); sel != nil {
+			return setString(v, target, sel.Value)
+		}
+		return false, fmt.Errorf("%w: %s", RuleSettingError.MODIFER_FEILD_NOT_EXISTED, selectWhat)
+	}, nil
+}
+
+func (re *ruleEngine) compileModiferInt(t reflect.Type, rm Modifer, target *accessor) (modiferFunc, error) {
+	switch rm.Operand {
+	case RuleOperand.SEL:
+		key, err := compileStringSide(t, rm.LeftSide, ModiferSideType.FIELD, RuleSettingError.MODIFER_SIDE_INVALID)
+		if err != nil {
+			return nil, err
+		}
+		right, err := compileComplexSide(t, rm)
+		if err != nil {
+			return nil, err
+		}
+		return func(_ *evaluation, v reflect.Value, _ interface{}) (bool, error) {
+			selectWhat, err := key(v)
+			if err != nil {
+				return false, err
+			}
+			vr, err := right(v)
+			if err != nil {
+				return false, err
+			}
+			if sel := vr.Select(selectWhat); sel != nil {
+				modifer, _ := strconv.ParseInt(sel.Value, 10, 64)
+				return setInt(v, target, modifer)
+			}
+			return false, fmt.Errorf("%w: %s", RuleSettingError.MODIFER_FEILD_NOT_EXISTED, selectWhat)
+		}, nil
+	case RuleOperand.SUM:
+		keys, err := compileAccessor(t, rm.LeftSide)
+		if err != nil {
+			return nil, err
+		}
+		right, err := compileComplexSide(t, rm)
+		if err != nil {
+			return nil, err
+		}
+		return func(_ *evaluation, v reflect.Value, _ interface{}) (bool, error) {
+			f, err := keys.get(v)
+			if err != nil {
+				return false, err
+			}
+			selectWhat, err := stringsOf(f)
+			if err != nil {
+				return false, err
+			}
+			f, err = target.get(v)
+			if err != nil {
+				return false, err
+			}
+			modifer, err := intOf(f)
+			if err != nil {
+				return false, err
+			}
+			vr, err := right(v)
+			if err != nil {
+				return false, err
+			}
+			for _, key := range selectWhat {
+				if ds := vr.Select(key); ds != nil {
+					value, _ := strconv.ParseInt(ds.Value, 10, 64)
+					modifer = modifer + value
+				}
+			}
+			return setInt(v, target, modifer)
+		}, nil
+	}
+
+	left, err := compileIntSide(t, rm.LeftSide, rm.LeftType, RuleSettingError.MODIFER_SIDE_INVALID)
+	if err != nil {
+		return nil, err
+	}
+	if rm.Operand == RuleOperand.SET {
+		return func(_ *evaluation, v reflect.Value, _ interface{}) (bool, error) {
+			vl, err := left(v)
+			if err != nil {
+				return false, err
+			}
+			return setInt(v, target, vl)
+		}, nil
+	}
+
+	rounding := re.rounding(rm)
+	var right func(v reflect.Value, vl int64) (int64, error)
+	if rm.RightType == ModiferSideType.COMPLEX {
+		temp := new(ModiferComplex)
+		if err := json.Unmarshal([]byte(rm.RightSide), temp); err != nil {
+			return nil, fmt.Errorf("%w: %v", RuleSettingError.MODIFER_SIDE_INVALID, err)
+		}
+		capped := rm.Operand == RuleOperand.ADD || rm.Operand == RuleOperand.SUB
+		right = func(_ reflect.Value, vl int64) (int64, error) {
+			vr, err := rounding.divide(temp.Percent*vl, 100)
+			if err != nil || !capped {
+				return vr, err
+			}
+			return temp.capped(vr), nil
+		}
+	} else {
+		side, err := compileIntSide(t, rm.RightSide, rm.RightType, RuleSettingError.MODIFER_SIDE_INVALID)
+		if err != nil {
+			return nil, err
+		}
+		right = func(v reflect.Value, _ int64) (int64, error) {
+			return side(v)
+		}
+	}
+
+	return func(_ *evaluation, v reflect.Value, _ interface{}) (bool, error) {
+		vl, err := left(v)
+		if err != nil {
+			return false, err
+		}
+		vr, err := right(v, vl)
+		if err != nil {
+			return false, err
+		}
+		switch rm.Operand {
+		case RuleOperand.ADD:
+			return setInt(v, target, vl+vr)
+		case RuleOperand.SUB:
+			return setInt(v, target, vl-vr)
+		case RuleOperand.MLT:
+			return setInt(v, target, vl*vr)
+		}
+		if vr == 0 {
+			return false, RuleSettingError.DIV_BY_ZERO
+		}
+		q, err := rounding.divide(vl, vr)
+		if err != nil {
+			return false, err
+		}
+		return setInt(v, target, q)
+	}, nil
+}
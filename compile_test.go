@@ -0,0 +1,260 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type stay struct {
+	Adults     int64
+	Price      int64
+	Name       string
+	Kind       string
+	CheckIn    time.Time
+	Rate       decimal.Decimal
+	Extras     []string
+	Attributes map[string]string
+	Guest      *guest
+}
+
+func compileSupply() stubSupply {
+	return stubSupply{"extra": {
+		setting("extra", 0, []Condition{intCondition("Adults", RuleConditionCompare.LESS, "4")}, intModifer(RuleOperand.ADD, "Price", "7")),
+	}}
+}
+
+func compileSettings() []RuleSetting {
+	// the days of 2024-05-31 and 2024-06-01, a Friday and a Saturday
+	friday := Condition{Type: RuleConditionType.DAY_OF_WEEK, LeftSide: "CheckIn", LeftType: ConditionSideType.FIELD, Compare: RuleConditionCompare.IN, RightSide: "1717156800,1717243200", RightType: ConditionSideType.VALUE}
+	rs := []RuleSetting{
+		setting("c", 1, []Condition{
+			intCondition("Adults", RuleConditionCompare.MORE, "1"),
+			intCondition("Adults", RuleConditionCompare.IN, "1,2,3"),
+			{Type: RuleConditionType.STRING, LeftSide: "Name", LeftType: ConditionSideType.FIELD, Compare: RuleConditionCompare.NOT, RightSide: "zz", RightType: ConditionSideType.VALUE},
+			{Type: RuleConditionType.DECIMAL, LeftSide: "Price", LeftType: ConditionSideType.FIELD, Compare: RuleConditionCompare.MORE_EQUAL, RightSide: "99.5", RightType: ConditionSideType.VALUE},
+		},
+			Modifer{Sequence: 3, Operand: RuleOperand.SET, DataType: ModiferDataType.STRING, LeftSide: "done", LeftType: ModiferSideType.VALUE, TargetField: "Name"},
+			Modifer{Sequence: 1, Operand: RuleOperand.ADD, DataType: ModiferDataType.INT, LeftSide: "Price", LeftType: ModiferSideType.FIELD, RightSide: `{"flat":5,"percentage":10}`, RightType: ModiferSideType.COMPLEX, TargetField: "Price"},
+			Modifer{Sequence: 2, Operand: RuleOperand.DIV, DataType: ModiferDataType.INT, LeftSide: "Price", LeftType: ModiferSideType.FIELD, RightSide: "Adults", RightType: ModiferSideType.FIELD, TargetField: "Price", Rounding: &Rounding{Mode: RoundingMode.HALF_UP}},
+		),
+		setting("c", 2, []Condition{friday},
+			Modifer{Operand: RuleOperand.MLT, DataType: ModiferDataType.DECIMAL, LeftSide: "Rate", LeftType: ModiferSideType.FIELD, RightSide: "1.25", RightType: ModiferSideType.VALUE, TargetField: "Rate"},
+			Modifer{Sequence: 1, Operand: RuleOperand.SEL, DataType: ModiferDataType.STRING, LeftSide: "Kind", RightSide: `{"select":[{"key":"suite","value":"S"}]}`, RightType: ModiferSideType.COMPLEX, TargetField: `Attributes["code"]`},
+		),
+		setting("c", 3, nil, Modifer{Operand: RuleOperand.SUM, DataType: ModiferDataType.INT, LeftSide: "Extras", RightSide: `{"select":[{"key":"bf","value":"12"},{"key":"spa","value":"30"}]}`, RightType: ModiferSideType.COMPLEX, TargetField: "Price"},
+			Modifer{Sequence: 1, Operand: RuleOperand.SUB, DataType: ModiferDataType.INT, LeftSide: "Price", LeftType: ModiferSideType.FIELD, RightSide: `{"percentage":10}`, RightType: ModiferSideType.COMPLEX, TargetField: "Price"}),
+		setting("c", 4, []Condition{intCondition("Guest.Points", RuleConditionCompare.MORE, "100")}, intModifer(RuleOperand.SUB, "Price", "20")),
+		setting("c", 5, []Condition{{Type: RuleConditionType.DATE, LeftSide: "CheckIn", LeftType: ConditionSideType.FIELD, Compare: RuleConditionCompare.LESS, RightSide: "1717200000", RightType: ConditionSideType.VALUE}},
+			jumpModifer(ModiferDataType.JRT, "extra", 0)),
+		setting("c", 6, nil, intModifer(RuleOperand.MLT, "Price", "2")),
+		setting("c", 7, []Condition{intCondition("Adults", RuleConditionCompare.EQUAL, "3")}, jumpModifer(ModiferDataType.JMP, "extra", 0)),
+		setting("c", 8, nil, intModifer(RuleOperand.ADD, "Price", "1000")),
+	}
+	rs[3].Rule.ConditionTree = &ConditionGroup{Mode: ConditionGroupMode.ANY, Conditions: []Condition{
+		{Type: RuleConditionType.STRING, LeftSide: "Kind", LeftType: ConditionSideType.FIELD, RightSide: "suite", RightType: ConditionSideType.VALUE},
+		intCondition("Adults", RuleConditionCompare.MORE_EQUAL, "3"),
+	}}
+	rs[5].BreakOnFail = false
+	rs[1].BreakOnFail = true
+	return rs
+}
+
+func compileRequests() []stay {
+	friday := time.Date(2024, 5, 31, 12, 0, 0, 0, time.UTC)
+	monday := time.Date(2024, 6, 3, 12, 0, 0, 0, time.UTC)
+	var reqs []stay
+	for _, adults := range []int64{1, 2, 3, 4} {
+		for _, checkIn := range []time.Time{friday, monday} {
+			for _, kind := range []string{"suite", "room"} {
+				reqs = append(reqs, stay{
+					Adults:     adults,
+					Price:      100 + adults*17,
+					Name:       "x",
+					Kind:       kind,
+					CheckIn:    checkIn,
+					Rate:       decimal.RequireFromString("80.4"),
+					Extras:     []string{"bf", "spa", "none"},
+					Attributes: map[string]string{},
+					Guest:      &guest{Points: 50 * adults},
+				})
+			}
+		}
+	}
+	return reqs
+}
+
+func TestCompileMatchesEngine(t *testing.T) {
+	for _, opts := range [][]Option{
+		nil,
+		{WithRounding(Rounding{Mode: RoundingMode.CEIL})},
+		{WithConditionStrategy(ConditionStrategy.PARALLEL)},
+	} {
+		re := NewEngine(compileSupply(), opts...)
+		cs, err := re.Compile(compileSettings(), reflect.TypeOf(stay{}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, req := range compileRequests() {
+			want, got := req, req
+			want.Attributes, got.Attributes = map[string]string{}, map[string]string{}
+			wantResult, wantErr := re.ApplySettings(&want, compileSettings())
+			result, err := cs.Apply(&got)
+			if result != wantResult || !reflect.DeepEqual(err, wantErr) {
+				t.Errorf("%+v: Apply = %v, %v, the engine %v, %v", req, result, err, wantResult, wantErr)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Apply left %+v, the engine %+v", got, want)
+			}
+		}
+	}
+}
+
+func TestCompileMatchesEngineErrors(t *testing.T) {
+	re := NewEngine(stubSupply{})
+	for _, rs := range [][]RuleSetting{
+		{setting("e", 1, []Condition{intCondition("Name", RuleConditionCompare.EQUAL, "1")})},
+		{setting("e", 1, nil, intModifer(RuleOperand.DIV, "Price", "0"))},
+		{setting("e", 1, nil, jumpModifer(ModiferDataType.JMP, "missing", 0))},
+		{setting("e", 1, []Condition{intCondition("Guest.Points", RuleConditionCompare.EQUAL, "1")})},
+		{setting("e", 1, nil, withRounding(intModifer(RuleOperand.DIV, "Price", "3"), &Rounding{Places: -19}))},
+	} {
+		cs, err := re.Compile(rs, reflect.TypeOf(stay{}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantResult, wantErr := re.ApplySettings(&stay{Name: "x"}, rs)
+		result, err := cs.Apply(&stay{Name: "x"})
+		if result != wantResult || errorString(err) != errorString(wantErr) {
+			t.Errorf("Apply = %v, %v, the engine %v, %v", result, err, wantResult, wantErr)
+		}
+	}
+}
+
+func TestCompileDocument(t *testing.T) {
+	rs := compileSettings()[:1]
+	re := NewEngine(stubSupply{})
+	cs, err := re.Compile(rs, reflect.TypeOf(map[string]interface{}{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := json.RawMessage(`{"Adults":2,"Price":100,"Name":"x"}`)
+	want := append(json.RawMessage(nil), raw...)
+	if _, err := cs.Apply(&raw); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := re.ApplySettings(&want, rs); err != nil {
+		t.Fatal(err)
+	}
+	if string(raw) != string(want) {
+		t.Errorf("Apply wrote %s, the engine %s", raw, want)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	re := NewEngine(stubSupply{})
+	if _, err := re.Compile(nil, reflect.TypeOf(0)); !errors.Is(err, RuleSettingError.FIELD_TYPE_MISMATCH) {
+		t.Errorf("Compile for an int = %v, want FIELD_TYPE_MISMATCH", err)
+	}
+	rs := compileSettings()
+	rs[0], rs[1] = rs[1], rs[0]
+	if _, err := re.Compile(rs, reflect.TypeOf(stay{})); err != RuleSettingError.SETTING_NOT_IN_ORDER {
+		t.Errorf("Compile out of order = %v, want SETTING_NOT_IN_ORDER", err)
+	}
+	var rerr *RuleError
+	_, err := re.Compile([]RuleSetting{setting("e", 1, []Condition{intCondition("Missing", RuleConditionCompare.EQUAL, "1")})}, reflect.TypeOf(stay{}))
+	if !errors.As(err, &rerr) || rerr.Condition != 0 || !errors.Is(err, RuleSettingError.MODIFER_FEILD_NOT_EXISTED) {
+		t.Errorf("Compile of a missing field = %v", err)
+	}
+
+	cs, err := re.Compile(compileSettings(), reflect.TypeOf(&stay{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cs.Apply(stay{}); !errors.Is(err, RuleSettingError.FIELD_TYPE_MISMATCH) {
+		t.Errorf("Apply of a value = %v, want FIELD_TYPE_MISMATCH", err)
+	}
+	if _, err := cs.Apply((*stay)(nil)); !errors.Is(err, RuleSettingError.FIELD_NIL) {
+		t.Errorf("Apply of nil = %v, want FIELD_NIL", err)
+	}
+}
+
+func benchmarkRequest() stay {
+	return stay{
+		Adults:     2,
+		Price:      134,
+		Name:       "x",
+		Kind:       "suite",
+		CheckIn:    time.Date(2024, 5, 31, 12, 0, 0, 0, time.UTC),
+		Rate:       decimal.RequireFromString("80.4"),
+		Extras:     []string{"bf", "spa"},
+		Attributes: map[string]string{},
+		Guest:      &guest{Points: 100},
+	}
+}
+
+// benchmarkSettings the INT and STRING part of compileSettings, all of it prepared by Compile
+func benchmarkSettings() []RuleSetting {
+	rs := compileSettings()
+	return []RuleSetting{rs[0], rs[2], rs[3], rs[5]}
+}
+
+func BenchmarkApplySettings(b *testing.B) {
+	re := NewEngine(stubSupply{})
+	rs := benchmarkSettings()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		req := benchmarkRequest()
+		if _, err := re.ApplySettings(&req, rs); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCompiledApply(b *testing.B) {
+	cs, err := NewEngine(stubSupply{}).Compile(benchmarkSettings(), reflect.TypeOf(stay{}))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		req := benchmarkRequest()
+		if _, err := cs.Apply(&req); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkApplySettingsFallback(b *testing.B) {
+	re := NewEngine(compileSupply())
+	rs := compileSettings()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		req := benchmarkRequest()
+		if _, err := re.ApplySettings(&req, rs); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkCompiledApplyFallback with DATE, DAY_OF_WEEK, a tree, DECIMAL and jumps run through the engine
+func BenchmarkCompiledApplyFallback(b *testing.B) {
+	cs, err := NewEngine(compileSupply()).Compile(compileSettings(), reflect.TypeOf(stay{}))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		req := benchmarkRequest()
+		if _, err := cs.Apply(&req); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
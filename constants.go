@@ -10,17 +10,22 @@ import (
 
 const DB_TABLE_RULE string = "rule_settings"
 const DB_TABLE_INFO string = "rule_infos"
+const DB_TABLE_VERSION string = "rule_versions"
+const DB_TABLE_PUBLICATION string = "rule_publications"
+
+// DefaultMaxJumpDepth the JMP and JRT hops allowed in one evaluation unless WithMaxJumpDepth says otherwise
+const DefaultMaxJumpDepth int = 32
 
 type ruleconditioncompare struct {
-	EQUAL      int
-	MORE       int
-	LESS       int
-	MORE_EQUAL int
-	LESS_EQUAL int
-	IN         int
-	NOT        int
-	PACKAGE    int
-	NOT_IN     int
+	EQUAL      CompareCode
+	MORE       CompareCode
+	LESS       CompareCode
+	MORE_EQUAL CompareCode
+	LESS_EQUAL CompareCode
+	IN         CompareCode
+	NOT        CompareCode
+	PACKAGE    CompareCode
+	NOT_IN     CompareCode
 }
 
 var RuleConditionCompare = ruleconditioncompare{
@@ -36,11 +41,12 @@ var RuleConditionCompare = ruleconditioncompare{
 }
 
 type ruleconditiontype struct {
-	DAY_OF_WEEK int
-	DATE        int
-	STRING      int
-	INT         int
-	MUST        int
+	DAY_OF_WEEK ConditionTypeCode
+	DATE        ConditionTypeCode
+	STRING      ConditionTypeCode
+	INT         ConditionTypeCode
+	MUST        ConditionTypeCode
+	DECIMAL     ConditionTypeCode
 }
 
 var RuleConditionType = ruleconditiontype{
@@ -49,6 +55,19 @@ var RuleConditionType = ruleconditiontype{
 	STRING:      2,
 	INT:         3,
 	MUST:        4,
+	DECIMAL:     5,
+}
+
+type conditiongroupmode struct {
+	ALL  GroupModeCode
+	ANY  GroupModeCode
+	NONE GroupModeCode
+}
+
+var ConditionGroupMode = conditiongroupmode{
+	ALL:  0,
+	ANY:  1,
+	NONE: 2,
 }
 
 var DayOfWeek = map[string]time.Time{
@@ -72,13 +91,13 @@ var DayOfWeekUnix = map[string]string{
 }
 
 type ruleoperand struct {
-	SET int
-	ADD int
-	SUB int
-	MLT int
-	DIV int
-	SEL int
-	SUM int
+	SET OperandCode
+	ADD OperandCode
+	SUB OperandCode
+	MLT OperandCode
+	DIV OperandCode
+	SEL OperandCode
+	SUM OperandCode
 }
 
 var RuleOperand = ruleoperand{
@@ -107,9 +126,26 @@ var RuleSelectOperand = ruleselectoperand{
 	DIV: 4,
 }
 
+type roundingmode struct {
+	TRUNCATE  RoundingModeCode
+	HALF_UP   RoundingModeCode
+	HALF_EVEN RoundingModeCode
+	FLOOR     RoundingModeCode
+	CEIL      RoundingModeCode
+}
+
+// RoundingMode how a Rounding settles the discarded digits, HALF_UP rounds ties away from zero
+var RoundingMode = roundingmode{
+	TRUNCATE:  0,
+	HALF_UP:   1,
+	HALF_EVEN: 2,
+	FLOOR:     3,
+	CEIL:      4,
+}
+
 type conditionsidetype struct {
-	FIELD int
-	VALUE int
+	FIELD SideCode
+	VALUE SideCode
 }
 
 var ConditionSideType = conditionsidetype{
@@ -118,9 +154,9 @@ var ConditionSideType = conditionsidetype{
 }
 
 type modifersidetype struct {
-	FIELD   int
-	VALUE   int
-	COMPLEX int
+	FIELD   SideCode
+	VALUE   SideCode
+	COMPLEX SideCode
 }
 
 var ModiferSideType = modifersidetype{
@@ -130,17 +166,69 @@ var ModiferSideType = modifersidetype{
 }
 
 type modiferdatatype struct {
-	STRING int
-	INT    int
-	JMP    int
-	JRT    int
+	STRING  DataTypeCode
+	INT     DataTypeCode
+	DECIMAL DataTypeCode
+	JMP     DataTypeCode
+	JRT     DataTypeCode
 }
 
 var ModiferDataType = modiferdatatype{
-	STRING: 0,
-	INT:    1,
-	JMP:    90,
-	JRT:    91,
+	STRING:  0,
+	INT:     1,
+	DECIMAL: 2,
+	JMP:     90,
+	JRT:     91,
+}
+
+type conditionstrategy struct {
+	SEQUENTIAL int
+	PARALLEL   int
+}
+
+var ConditionStrategy = conditionstrategy{
+	SEQUENTIAL: 0,
+	PARALLEL:   1,
+}
+
+type ruleinfostatus struct {
+	DRAFT    int
+	ACTIVE   int
+	ARCHIVED int
+}
+
+var RuleInfoStatus = ruleinfostatus{
+	DRAFT:    0,
+	ACTIVE:   1,
+	ARCHIVED: 2,
+}
+
+type settingskip struct {
+	NONE          int
+	NOT_YET_VALID int
+	EXPIRED       int
+	DISABLED      int
+}
+
+// SettingSkip why the engine passed over a setting without checking it, see SettingTrace
+var SettingSkip = settingskip{
+	NONE:          0,
+	NOT_YET_VALID: 1,
+	EXPIRED:       2,
+	DISABLED:      3,
+}
+
+type ruleformat struct {
+	JSON int
+	YAML int
+	TOML int
+}
+
+// RuleFormat how MarshalRuleSettings writes a rule set, YAML and TOML write COMPLEX sides as objects
+var RuleFormat = ruleformat{
+	JSON: 0,
+	YAML: 1,
+	TOML: 2,
 }
 
 type rulesettingerror struct {
@@ -151,6 +239,16 @@ type rulesettingerror struct {
 	MODIFER_FEILD_NOT_EXISTED error
 	DIV_BY_ZERO               error
 	UNABLE_TO_FETCH           error
+	JUMP_CYCLE                error
+	JUMP_TOO_DEEP             error
+	VALUE_INVALID             error
+	FIELD_PATH_INVALID        error
+	FIELD_NIL                 error
+	FIELD_TYPE_MISMATCH       error
+	EVALUATION_PANIC          error
+	RULE_NOT_FOUND            error
+	VERSION_NOT_FOUND         error
+	SYNTAX_INVALID            error
 }
 
 var RuleSettingError = rulesettingerror{
@@ -161,6 +259,16 @@ var RuleSettingError = rulesettingerror{
 	SETTING_NOT_IN_ORDER:      errors.New("Rule Settings not in order"),
 	DIV_BY_ZERO:               errors.New("Divide by zero"),
 	UNABLE_TO_FETCH:           errors.New("Unable to fetch next rule set."),
+	JUMP_CYCLE:                errors.New("Jump cycle detected"),
+	JUMP_TOO_DEEP:             errors.New("Jump depth exceeded"),
+	VALUE_INVALID:             errors.New("Invalid value"),
+	FIELD_PATH_INVALID:        errors.New("Invalid field path"),
+	FIELD_NIL:                 errors.New("Nil field in path"),
+	FIELD_TYPE_MISMATCH:       errors.New("Field type mismatch"),
+	EVALUATION_PANIC:          errors.New("Panic during evaluation"),
+	RULE_NOT_FOUND:            errors.New("Rule set not found"),
+	VERSION_NOT_FOUND:         errors.New("Rule set version not found"),
+	SYNTAX_INVALID:            errors.New("Invalid rule syntax"),
 }
 
 type rulesettingstep struct {
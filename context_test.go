@@ -0,0 +1,105 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// contextSupply a ContextSupply recording the context of every fetch
+type contextSupply struct {
+	stubSupply
+	fetched []context.Context
+}
+
+func (sp *contextSupply) SaveRuleSettingsContext(ctx context.Context, rs []RuleSetting) error {
+	return sp.SaveRuleSettings(rs)
+}
+
+func (sp *contextSupply) FetchRuleSettingsContext(ctx context.Context, id string, start int) ([]RuleSetting, error) {
+	sp.fetched = append(sp.fetched, ctx)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return sp.FetchRuleSettings(id, start)
+}
+
+// cancelSupply cancel once a rule set is fetched
+type cancelSupply struct {
+	stubSupply
+	cancel context.CancelFunc
+}
+
+func (sp *cancelSupply) FetchRuleSettings(id string, start int) ([]RuleSetting, error) {
+	sp.cancel()
+	return sp.stubSupply.FetchRuleSettings(id, start)
+}
+
+func TestApplySettingsContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	b := &booking{Price: 100}
+	rs := []RuleSetting{setting("r", 1, nil, intModifer(RuleOperand.ADD, "Price", "1"))}
+
+	re := NewEngine(stubSupply{})
+	if _, err := re.ApplySettingsContext(ctx, b, rs); !errors.Is(err, context.Canceled) {
+		t.Errorf("ApplySettingsContext = %v, want context.Canceled", err)
+	}
+	if _, _, err := re.ApplySettingContext(ctx, b, rs[0]); !errors.Is(err, context.Canceled) {
+		t.Errorf("ApplySettingContext = %v, want context.Canceled", err)
+	}
+	if _, err := re.ApplyModiferContext(ctx, b, rs[0].Rule.ModiferChain[0]); !errors.Is(err, context.Canceled) {
+		t.Errorf("ApplyModiferContext = %v, want context.Canceled", err)
+	}
+	if b.Price != 100 {
+		t.Errorf("Price = %d, nothing should apply once canceled", b.Price)
+	}
+}
+
+func TestApplySettingsContextDeadline(t *testing.T) {
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	_, err := NewEngine(stubSupply{}).ApplySettingsContext(ctx, &booking{}, []RuleSetting{setting("r", 1, nil)})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ApplySettingsContext = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestApplySettingsContextSupply(t *testing.T) {
+	sp := &contextSupply{stubSupply: stubSupply{
+		"extra": {setting("extra", 0, nil, intModifer(RuleOperand.ADD, "Price", "5"))},
+	}}
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "request")
+	rs := []RuleSetting{setting("r", 1, nil, jumpModifer(ModiferDataType.JRT, "extra", 0))}
+
+	b := &booking{Price: 100}
+	if _, err := NewEngine(sp).ApplySettingsContext(ctx, b, rs); err != nil {
+		t.Fatal(err)
+	}
+	if b.Price != 105 || len(sp.fetched) != 1 || sp.fetched[0].Value(key{}) != "request" {
+		t.Errorf("the jump did not fetch through FetchRuleSettingsContext with ctx: Price %d, %d fetches", b.Price, len(sp.fetched))
+	}
+}
+
+// TestApplySettingsContextCanceledDuring cancel from a modifer, the settings after it are not applied
+func TestApplySettingsContextCanceledDuring(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sp := &cancelSupply{stubSupply: stubSupply{"stop": {setting("stop", 0, nil)}}, cancel: cancel}
+	rs := []RuleSetting{
+		setting("r", 1, nil, jumpModifer(ModiferDataType.JRT, "stop", 0)),
+		setting("r", 2, nil, intModifer(RuleOperand.ADD, "Price", "1")),
+	}
+	b := &booking{}
+	if _, err := NewEngine(sp).ApplySettingsContext(ctx, b, rs); !errors.Is(err, context.Canceled) {
+		t.Errorf("ApplySettingsContext = %v, want context.Canceled", err)
+	}
+	if b.Price != 0 {
+		t.Errorf("Price = %d, the setting after the cancel applied", b.Price)
+	}
+}
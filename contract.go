@@ -3,12 +3,26 @@
 // DO NOT EDIT directly
 package rule
 
+import (
+	"context"
+	"reflect"
+)
+
 // Engine the engine that crunch the rules
 type Engine interface {
 	ApplySetting(rqr interface{}, rs RuleSetting) (bool, bool, error)
 	ApplySettings(rqr interface{}, rs []RuleSetting) (bool, error)
+	ExplainSetting(rqr interface{}, rs RuleSetting) (bool, bool, *Trace, error)
+	ExplainSettings(rqr interface{}, rs []RuleSetting) (bool, *Trace, error)
 	ApplyModifer(rqr interface{}, rm Modifer) (bool, error)
+	ApplySettingContext(ctx context.Context, rqr interface{}, rs RuleSetting) (bool, bool, error)
+	ApplySettingsContext(ctx context.Context, rqr interface{}, rs []RuleSetting) (bool, error)
+	ExplainSettingContext(ctx context.Context, rqr interface{}, rs RuleSetting) (bool, bool, *Trace, error)
+	ExplainSettingsContext(ctx context.Context, rqr interface{}, rs []RuleSetting) (bool, *Trace, error)
+	ApplyModiferContext(ctx context.Context, rqr interface{}, rm Modifer) (bool, error)
 	CheckRuleCondition(rqr interface{}, c Condition) (bool, error)
+	CheckConditionGroup(rqr interface{}, g ConditionGroup) (bool, error)
+	Compile(rs []RuleSetting, t reflect.Type) (*CompiledRuleSet, error)
 }
 
 // Supply to fetch and save rules
@@ -16,3 +30,36 @@ type Supply interface {
 	SaveRuleSettings(rs []RuleSetting) error
 	FetchRuleSettings(id string, start int) ([]RuleSetting, error)
 }
+
+// ContextSupply a Supply that honours cancellation and deadlines, preferred by the engine when implemented
+type ContextSupply interface {
+	SaveRuleSettingsContext(ctx context.Context, rs []RuleSetting) error
+	FetchRuleSettingsContext(ctx context.Context, id string, start int) ([]RuleSetting, error)
+}
+
+// DisabledSupply a Supply whose FetchRuleSettings leaves disabled settings out, FetchAllRuleSettings serves them as
+// well and is preferred by the engine under WithIncludeDisabled
+type DisabledSupply interface {
+	Supply
+	FetchAllRuleSettings(id string, start int) ([]RuleSetting, error)
+}
+
+// InfoSupply a Supply that also keeps the RuleInfo of its rule sets, to list them as a catalogue
+type InfoSupply interface {
+	Supply
+	SaveRuleInfo(info RuleInfo) error
+	FetchRuleInfo(id string) (*RuleInfo, error)
+	ListRuleInfos(offset, limit int) ([]RuleInfo, error)
+	SearchRuleInfos(q InfoQuery) ([]RuleInfo, error)
+}
+
+// VersionedSupply a Supply where every save makes an immutable version of the rule sets it touches
+// and FetchRuleSettings serves the version last published
+type VersionedSupply interface {
+	Supply
+	FetchVersion(id string, version int, start int) ([]RuleSetting, error)
+	ListVersions(id string) ([]RuleVersion, error)
+	PublishedVersion(id string) (int, error)
+	Publish(id string, version int) error
+	Rollback(id string) (int, error)
+}
@@ -0,0 +1,305 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+var decimalType = reflect.TypeOf(decimal.Decimal{})
+
+var hundred = decimal.NewFromInt(100)
+
+// decimalComplex ModiferComplex read for DECIMAL modifers, flat and percentage may be fractional
+type decimalComplex struct {
+	Flat      decimal.Decimal `json:"flat"`
+	Percent   decimal.Decimal `json:"percentage"`
+	Selection []JSONmap       `json:"select"`
+	Uncapped  bool            `json:"-"`
+}
+
+func (rm *decimalComplex) UnmarshalJSON(b []byte) error {
+	type plain decimalComplex
+	v := struct {
+		*plain
+		Flat *decimal.Decimal `json:"flat"`
+	}{plain: (*plain)(rm)}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	rm.Uncapped = v.Flat == nil
+	if v.Flat != nil {
+		rm.Flat = *v.Flat
+	}
+	return nil
+}
+
+func (rm *decimalComplex) Select(key string) *JSONmap {
+	for _, item := range rm.Selection {
+		if item.Key == key {
+			return &item
+		}
+	}
+	return nil
+}
+
+// decimalOf read a decimal.Decimal, string, int, uint or float field as an exact decimal
+func decimalOf(v reflect.Value) (decimal.Decimal, error) {
+	v = reflect.Indirect(v)
+	if !v.IsValid() {
+		return decimal.Zero, RuleSettingError.MODIFER_FEILD_NOT_EXISTED
+	}
+	if v.Type() == decimalType && v.CanInterface() {
+		return v.Interface().(decimal.Decimal), nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return decimal.NewFromString(v.String())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return decimal.NewFromInt(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return decimal.NewFromBigInt(new(big.Int).SetUint64(v.Uint()), 0), nil
+	case reflect.Float32, reflect.Float64:
+		return decimal.NewFromFloat(v.Float()), nil
+	}
+	return decimal.Zero, fmt.Errorf("%w: decimal field of kind %s", RuleSettingError.FIELD_TYPE_MISMATCH, v.Kind())
+}
+
+// setDecimal write d into a decimal.Decimal or string field, or into an interface such as a document map element
+func setDecimal(v reflect.Value, d decimal.Decimal) error {
+	if !v.IsValid() {
+		return RuleSettingError.MODIFER_FEILD_NOT_EXISTED
+	}
+	if v.Type() == decimalType {
+		v.Set(reflect.ValueOf(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(d.String())
+		return nil
+	case reflect.Interface:
+		// documents keep decimals as JSON numbers
+		v.Set(reflect.ValueOf(json.Number(d.String())))
+		return nil
+	}
+	return fmt.Errorf("%w: decimal target field of kind %s", RuleSettingError.FIELD_TYPE_MISMATCH, v.Kind())
+}
+
+func setFieldDecimal(rqr interface{}, path string, d decimal.Decimal) error {
+	return setField(rqr, path, func(v reflect.Value) error {
+		return setDecimal(v, d)
+	})
+}
+
+func (re *ruleEngine) decimalModiferSide(rqr interface{}, side string, sideType SideCode) (decimal.Decimal, error) {
+	switch sideType {
+	case ModiferSideType.VALUE:
+		temp, err := decimal.NewFromString(side)
+		if err != nil {
+			return decimal.Zero, fmt.Errorf("%w: value %s", RuleSettingError.MODIFER_SIDE_INVALID, side)
+		}
+		return temp, nil
+	case ModiferSideType.FIELD:
+		temp, err := fieldValue(rqr, side)
+		if err != nil {
+			return decimal.Zero, err
+		}
+		return decimalOf(temp)
+	}
+	return decimal.Zero, RuleSettingError.MODIFER_SIDE_INVALID
+}
+
+func (re *ruleEngine) decimalModiferComplex(rqr interface{}, rm Modifer) (*decimalComplex, error) {
+	switch rm.RightType {
+	case ModiferSideType.FIELD:
+		temp, err := fieldComplex(rqr, rm.RightSide)
+		if err != nil {
+			return nil, err
+		}
+		return &decimalComplex{
+			Flat:      decimal.NewFromInt(temp.Flat),
+			Percent:   decimal.NewFromInt(temp.Percent),
+			Selection: temp.Selection,
+			Uncapped:  temp.Uncapped,
+		}, nil
+	case ModiferSideType.COMPLEX:
+		temp := new(decimalComplex)
+		if err := json.Unmarshal([]byte(rm.RightSide), temp); err != nil {
+			return nil, err
+		}
+		return temp, nil
+	}
+	return nil, RuleSettingError.MODIFER_SIDE_INVALID
+}
+
+// applyModiferDecimal apply rm in exact decimal arithmetic but for DIV, whose quotient is rounded half up to
+// decimal.DivisionPrecision (16) places before any Rounding, so 1/3 is 0.3333333333333333
+func (re *ruleEngine) applyModiferDecimal(rqr interface{}, rm Modifer) error {
+	switch rm.Operand {
+	case RuleOperand.SEL:
+		selectWhat, err := fieldString(rqr, rm.LeftSide)
+		if err != nil {
+			return err
+		}
+		vr, err := re.decimalModiferComplex(rqr, rm)
+		if err != nil {
+			return err
+		}
+		if sel := vr.Select(selectWhat); sel != nil {
+			modifer, err := decimal.NewFromString(sel.Value)
+			if err != nil {
+				return fmt.Errorf("%w: selection %s", RuleSettingError.VALUE_INVALID, sel.Value)
+			}
+			return setFieldDecimal(rqr, rm.TargetField, modifer)
+		}
+		return fmt.Errorf("%w: %s", RuleSettingError.MODIFER_FEILD_NOT_EXISTED, selectWhat)
+	case RuleOperand.SUM:
+		selectWhat, err := fieldStrings(rqr, rm.LeftSide)
+		if err != nil {
+			return err
+		}
+		temp, err := fieldValue(rqr, rm.TargetField)
+		if err != nil {
+			return err
+		}
+		modifer, err := decimalOf(temp)
+		if err != nil {
+			return err
+		}
+		vr, err := re.decimalModiferComplex(rqr, rm)
+		if err != nil {
+			return err
+		}
+		for _, key := range selectWhat {
+			if ds := vr.Select(key); ds != nil {
+				value, err := decimal.NewFromString(ds.Value)
+				if err != nil {
+					return fmt.Errorf("%w: selection %s", RuleSettingError.VALUE_INVALID, ds.Value)
+				}
+				modifer = modifer.Add(value)
+			}
+		}
+		return setFieldDecimal(rqr, rm.TargetField, modifer)
+	}
+
+	vl, err := re.decimalModiferSide(rqr, rm.LeftSide, rm.LeftType)
+	if err != nil {
+		return err
+	}
+	if rm.Operand == RuleOperand.SET {
+		return setFieldDecimal(rqr, rm.TargetField, vl)
+	}
+
+	var vr decimal.Decimal
+	if rm.RightType == ModiferSideType.COMPLEX {
+		temp, err := re.decimalModiferComplex(rqr, rm)
+		if err != nil {
+			return err
+		}
+		vr = re.rounding(rm).decimal(temp.Percent.Mul(vl).Div(hundred))
+		if (rm.Operand == RuleOperand.ADD || rm.Operand == RuleOperand.SUB) && !temp.Uncapped {
+			vr = decimal.Min(temp.Flat, vr)
+		}
+	} else {
+		vr, err = re.decimalModiferSide(rqr, rm.RightSide, rm.RightType)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch rm.Operand {
+	case RuleOperand.ADD:
+		return setFieldDecimal(rqr, rm.TargetField, vl.Add(vr))
+	case RuleOperand.SUB:
+		return setFieldDecimal(rqr, rm.TargetField, vl.Sub(vr))
+	case RuleOperand.MLT:
+		return setFieldDecimal(rqr, rm.TargetField, vl.Mul(vr))
+	case RuleOperand.DIV:
+		if vr.IsZero() {
+			return RuleSettingError.DIV_BY_ZERO
+		}
+		return setFieldDecimal(rqr, rm.TargetField, re.rounding(rm).decimal(vl.Div(vr)))
+	}
+	return RuleSettingError.UNSUPPORTED_OPERATION
+}
+
+func (re *ruleEngine) decimalConditionSide(rqr interface{}, side string, sideType SideCode) (decimal.Decimal, error) {
+	switch sideType {
+	case ConditionSideType.FIELD:
+		temp, err := fieldValue(rqr, side)
+		if err != nil {
+			return decimal.Zero, err
+		}
+		return decimalOf(temp)
+	case ConditionSideType.VALUE:
+		return decimal.NewFromString(side)
+	}
+	return decimal.Zero, RuleSettingError.CONDITION_SIDE_INVALID
+}
+
+func (re *ruleEngine) compareDecimal(rqr interface{}, c Condition, ct *ConditionTrace) (bool, error) {
+	vl, err := re.decimalConditionSide(rqr, c.LeftSide, c.LeftType)
+	if err != nil {
+		return false, err
+	}
+	ct.left(vl)
+
+	// handle In array
+	if c.Compare == RuleConditionCompare.IN || c.Compare == RuleConditionCompare.NOT_IN {
+		var vr []string
+		switch c.RightType {
+		case ConditionSideType.FIELD:
+			temp, err := fieldString(rqr, c.RightSide)
+			if err != nil {
+				return false, err
+			}
+			vr = strings.Split(temp, ",")
+		case ConditionSideType.VALUE:
+			vr = strings.Split(c.RightSide, ",")
+		default:
+			return false, RuleSettingError.CONDITION_SIDE_INVALID
+		}
+		ct.right(vr)
+
+		for _, v := range vr {
+			d, err := decimal.NewFromString(strings.TrimSpace(v))
+			if err != nil {
+				return false, err
+			}
+			if d.Equal(vl) {
+				return c.Compare == RuleConditionCompare.IN, nil
+			}
+		}
+		return c.Compare == RuleConditionCompare.NOT_IN, nil
+	}
+
+	vr, err := re.decimalConditionSide(rqr, c.RightSide, c.RightType)
+	if err != nil {
+		return false, err
+	}
+	ct.right(vr)
+
+	switch c.Compare {
+	case RuleConditionCompare.EQUAL:
+		return vl.Cmp(vr) == 0, nil
+	case RuleConditionCompare.NOT:
+		return vl.Cmp(vr) != 0, nil
+	case RuleConditionCompare.MORE:
+		return vl.Cmp(vr) > 0, nil
+	case RuleConditionCompare.LESS:
+		return vl.Cmp(vr) < 0, nil
+	case RuleConditionCompare.MORE_EQUAL:
+		return vl.Cmp(vr) >= 0, nil
+	case RuleConditionCompare.LESS_EQUAL:
+		return vl.Cmp(vr) <= 0, nil
+	}
+	return false, RuleSettingError.UNSUPPORTED_OPERATION
+}
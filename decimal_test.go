@@ -0,0 +1,143 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+type room struct {
+	Rate   decimal.Decimal
+	Total  string
+	Nights int64
+	Tax    float64
+	Kind   string
+	Extras []string
+	Count  int
+}
+
+func decimalModifer(operand OperandCode, left string, leftType SideCode, right string, rightType SideCode, target string) Modifer {
+	return Modifer{
+		Operand:     operand,
+		DataType:    ModiferDataType.DECIMAL,
+		LeftSide:    left,
+		LeftType:    leftType,
+		RightSide:   right,
+		RightType:   rightType,
+		TargetField: target,
+	}
+}
+
+func TestApplyModiferDecimal(t *testing.T) {
+	field, value, complex := ModiferSideType.FIELD, ModiferSideType.VALUE, ModiferSideType.COMPLEX
+	cases := []struct {
+		name  string
+		m     Modifer
+		rate  string
+		total string
+	}{
+		{"ADD", decimalModifer(RuleOperand.ADD, "Rate", field, "0.1", value, "Rate"), "100.1", "0"},
+		{"SUB below zero", decimalModifer(RuleOperand.SUB, "Rate", field, "100.01", value, "Rate"), "-0.01", "0"},
+		{"MLT", decimalModifer(RuleOperand.MLT, "Rate", field, "1.075", value, "Rate"), "107.5", "0"},
+		{"DIV into a string", decimalModifer(RuleOperand.DIV, "Rate", field, "3", value, "Total"), "100", "33.3333333333333333"},
+		{"DIV by a float field", decimalModifer(RuleOperand.DIV, "Rate", field, "Tax", field, "Total"), "100", "80"},
+		{"DIV by an int field", decimalModifer(RuleOperand.DIV, "Rate", field, "Nights", field, "Rate"), "25", "0"},
+		{"SET from a string field", decimalModifer(RuleOperand.SET, "Total", field, "", value, "Rate"), "0", "0"},
+		{"SET a value", decimalModifer(RuleOperand.SET, "0.1", value, "", value, "Total"), "100", "0.1"},
+		{"ADD the lower of flat and percentage", decimalModifer(RuleOperand.ADD, "Rate", field, `{"flat": 5, "percentage": 12.5}`, complex, "Rate"), "105", "0"},
+		{"SUB the lower of flat and percentage", decimalModifer(RuleOperand.SUB, "Rate", field, `{"flat": "1000", "percentage": 12.5}`, complex, "Rate"), "87.5", "0"},
+		{"ADD a percentage without flat", decimalModifer(RuleOperand.ADD, "Rate", field, `{"percentage": 12.5}`, complex, "Rate"), "112.5", "0"},
+		{"MLT by a percentage", decimalModifer(RuleOperand.MLT, "Rate", field, `{"percentage": 0.5}`, complex, "Total"), "100", "50"},
+		{"SEL", decimalModifer(RuleOperand.SEL, "Kind", field, `{"select":[{"key":"suite","value":"249.99"}]}`, complex, "Rate"), "249.99", "0"},
+		{"SUM", decimalModifer(RuleOperand.SUM, "Extras", field, `{"select":[{"key":"bf","value":"12.25"},{"key":"spa","value":"0.75"},{"key":"gym","value":"9"}]}`, complex, "Rate"), "113", "0"},
+	}
+	re := NewEngine(stubSupply{})
+	for _, c := range cases {
+		r := &room{Rate: decimal.NewFromInt(100), Total: "0", Nights: 4, Tax: 1.25, Kind: "suite", Extras: []string{"bf", "spa"}}
+		result, err := re.ApplyModifer(r, c.m)
+		if err != nil || !result {
+			t.Errorf("%s: ApplyModifer = %v, %v", c.name, result, err)
+			continue
+		}
+		if r.Rate.String() != c.rate || r.Total != c.total {
+			t.Errorf("%s: Rate %s, Total %s, want %s, %s", c.name, r.Rate, r.Total, c.rate, c.total)
+		}
+	}
+}
+
+func TestApplyModiferDecimalExact(t *testing.T) {
+	// 0.1 ten times is 1, where float64 is not
+	r := &room{Rate: decimal.Zero}
+	re := NewEngine(stubSupply{})
+	for i := 0; i < 10; i++ {
+		if _, err := re.ApplyModifer(r, decimalModifer(RuleOperand.ADD, "Rate", ModiferSideType.FIELD, "0.1", ModiferSideType.VALUE, "Rate")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !r.Rate.Equal(decimal.NewFromInt(1)) {
+		t.Errorf("Rate = %s, want 1", r.Rate)
+	}
+}
+
+func TestApplyModiferDecimalErrors(t *testing.T) {
+	field, value := ModiferSideType.FIELD, ModiferSideType.VALUE
+	cases := []struct {
+		name string
+		m    Modifer
+		err  error
+	}{
+		{"DIV by zero", decimalModifer(RuleOperand.DIV, "Rate", field, "0.00", value, "Rate"), RuleSettingError.DIV_BY_ZERO},
+		{"not a number", decimalModifer(RuleOperand.ADD, "Rate", field, "1,5", value, "Rate"), RuleSettingError.MODIFER_SIDE_INVALID},
+		{"a slice is no decimal", decimalModifer(RuleOperand.ADD, "Extras", field, "1", value, "Rate"), RuleSettingError.FIELD_TYPE_MISMATCH},
+		{"an int target", decimalModifer(RuleOperand.ADD, "Rate", field, "1", value, "Count"), RuleSettingError.FIELD_TYPE_MISMATCH},
+		{"SEL without the key", decimalModifer(RuleOperand.SEL, "Kind", field, `{"select":[]}`, ModiferSideType.COMPLEX, "Rate"), RuleSettingError.MODIFER_FEILD_NOT_EXISTED},
+		{"SEL of no decimal", decimalModifer(RuleOperand.SEL, "Kind", field, `{"select":[{"key":"suite","value":"x"}]}`, ModiferSideType.COMPLEX, "Rate"), RuleSettingError.VALUE_INVALID},
+		{"unknown operand", decimalModifer(99, "Rate", field, "1", value, "Rate"), RuleSettingError.UNSUPPORTED_OPERATION},
+	}
+	re := NewEngine(stubSupply{})
+	for _, c := range cases {
+		r := &room{Rate: decimal.NewFromInt(100), Kind: "suite"}
+		if _, err := re.ApplyModifer(r, c.m); !errors.Is(err, c.err) {
+			t.Errorf("%s: ApplyModifer = %v, want %v", c.name, err, c.err)
+		}
+		if !r.Rate.Equal(decimal.NewFromInt(100)) {
+			t.Errorf("%s: Rate changed to %s", c.name, r.Rate)
+		}
+	}
+}
+
+func TestCompareDecimal(t *testing.T) {
+	cases := []struct {
+		compare CompareCode
+		right   string
+		want    bool
+	}{
+		{RuleConditionCompare.EQUAL, "188.000", true},
+		{RuleConditionCompare.MORE, "187.99", true},
+		{RuleConditionCompare.LESS, "188.01", true},
+		{RuleConditionCompare.LESS_EQUAL, "188", true},
+		{RuleConditionCompare.MORE_EQUAL, "188.0001", false},
+		{RuleConditionCompare.IN, "1.5, 188.00", true},
+		{RuleConditionCompare.NOT_IN, "1.5, 188.00", false},
+		{RuleConditionCompare.NOT_IN, "1.5", true},
+	}
+	re := NewEngine(stubSupply{})
+	r := room{Rate: decimal.RequireFromString("188")}
+	for _, c := range cases {
+		ok, err := re.CheckRuleCondition(r, Condition{
+			Type:      RuleConditionType.DECIMAL,
+			LeftSide:  "Rate",
+			LeftType:  ConditionSideType.FIELD,
+			Compare:   c.compare,
+			RightSide: c.right,
+			RightType: ConditionSideType.VALUE,
+		})
+		if err != nil || ok != c.want {
+			t.Errorf("188 %s %s = %v, %v, want %v", c.compare, c.right, ok, err, c.want)
+		}
+	}
+}
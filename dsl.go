@@ -0,0 +1,1511 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// The rule DSL writes settings the way they read, for example
+//
+//	setting "weekend" rule "pricing" sequence 10 break
+//	when weekday CheckIn in [Saturday, Sunday]
+//	  and Adults >= 2
+//	match any(Channel = "web", Channel = "app")
+//	then
+//	  Price = Price + 10% max 500 round half_up -2
+//	  jump "fees"
+//
+// A condition is [type] side compare side, or always. The type is one of int, decimal, string,
+// weekday, date or must and can be left out when a literal tells it: "text" is a string,
+// 2 an int, 2.5 a decimal, Saturday a weekday and 2026-06-01 a date, with neither side a literal
+// it is int. Compares are = != > < >= <= in, not in and package, in takes a [list], a field
+// or a "raw,comma,separated" string.
+//
+// A modifer is [#sequence] [type] target = side, target = side op side with op one of + - * /,
+// target = select field from complex, target += sum field from complex, jump "rule" [start]
+// or call "rule" [start] which returns after the rule set. The type is int, decimal or string
+// and is told by a literal the same way, int otherwise. A complex side is a JSON object,
+// json "raw" or P% [max F] standing for {"percentage":P} and {"flat":F,"percentage":P}: + and -
+// add or take P percent of the left side, no more than F when there is a max.
+// Any modifer but a jump can end with round mode [places]. Without #sequence a modifer takes
+// its position in the rule, counting from 0, as its sequence.
+//
+// Fields are paths such as Guest.Tier, Rooms[0].Adults or Attributes["channel"], written
+// in `backquotes` when they would read as something else. // starts a comment.
+
+// SyntaxError a problem in rule DSL source at Line and Column, both from 1
+type SyntaxError struct {
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Msg)
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return RuleSettingError.SYNTAX_INVALID
+}
+
+// ParseSettings read every setting in src
+func ParseSettings(src string) ([]RuleSetting, error) {
+	p, err := newDSLParser(src)
+	if err != nil {
+		return nil, err
+	}
+	rs := []RuleSetting{}
+	for p.tok.kind != dslEOF {
+		setting, err := p.setting()
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, setting)
+	}
+	return rs, nil
+}
+
+// ParseRule read the when, match and then parts of one setting
+func ParseRule(src string) (*Rule, error) {
+	p, err := newDSLParser(src)
+	if err != nil {
+		return nil, err
+	}
+	r, err := p.rule()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.end(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// ParseCondition read one condition
+func ParseCondition(src string) (*Condition, error) {
+	p, err := newDSLParser(src)
+	if err != nil {
+		return nil, err
+	}
+	c, err := p.condition()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.end(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// ParseModifer read one modifer
+func ParseModifer(src string) (*Modifer, error) {
+	p, err := newDSLParser(src)
+	if err != nil {
+		return nil, err
+	}
+	m, err := p.modifer(0)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.end(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+const (
+	dslEOF    = iota
+	dslIdent  // a field path or a keyword
+	dslField  // a `quoted` field path
+	dslString // a "quoted" string, text is unquoted
+	dslNumber
+	dslDate
+	dslPunct
+)
+
+type dslToken struct {
+	kind int
+	text string
+	off  int
+}
+
+// dslKeywords words a field path must be `quoted` to use
+var dslKeywords = map[string]bool{
+	"setting": true, "rule": true, "sequence": true, "type": true, "disabled": true, "break": true,
+	"from": true, "until": true, "when": true, "and": true, "match": true, "then": true,
+	"all": true, "any": true, "none": true, "in": true, "not": true, "package": true, "always": true,
+	"int": true, "decimal": true, "string": true, "weekday": true, "date": true, "must": true,
+	"jump": true, "call": true, "select": true, "sum": true, "round": true, "max": true, "json": true,
+}
+
+// dslDays day names and their short forms to the day they stand for in DayOfWeekUnix
+var dslDays = map[string]string{
+	"Sunday": "Sunday", "Monday": "Monday", "Tuesday": "Tuesday", "Wednesday": "Wednesday",
+	"Thursday": "Thursday", "Friday": "Friday", "Saturday": "Saturday",
+	"Sun": "Sunday", "Mon": "Monday", "Tue": "Tuesday", "Wed": "Wednesday",
+	"Thu": "Thursday", "Fri": "Friday", "Sat": "Saturday",
+}
+
+var dslConditionTypes = map[string]ConditionTypeCode{
+	"int":     RuleConditionType.INT,
+	"decimal": RuleConditionType.DECIMAL,
+	"string":  RuleConditionType.STRING,
+	"weekday": RuleConditionType.DAY_OF_WEEK,
+	"date":    RuleConditionType.DATE,
+	"must":    RuleConditionType.MUST,
+}
+
+var dslModiferTypes = map[string]DataTypeCode{
+	"int":     ModiferDataType.INT,
+	"decimal": ModiferDataType.DECIMAL,
+	"string":  ModiferDataType.STRING,
+}
+
+var dslCompares = map[string]CompareCode{
+	"=":       RuleConditionCompare.EQUAL,
+	"!=":      RuleConditionCompare.NOT,
+	">":       RuleConditionCompare.MORE,
+	"<":       RuleConditionCompare.LESS,
+	">=":      RuleConditionCompare.MORE_EQUAL,
+	"<=":      RuleConditionCompare.LESS_EQUAL,
+	"in":      RuleConditionCompare.IN,
+	"not in":  RuleConditionCompare.NOT_IN,
+	"package": RuleConditionCompare.PACKAGE,
+}
+
+var dslGroupModes = map[string]GroupModeCode{
+	"all":  ConditionGroupMode.ALL,
+	"any":  ConditionGroupMode.ANY,
+	"none": ConditionGroupMode.NONE,
+}
+
+var dslOperands = map[string]OperandCode{
+	"+": RuleOperand.ADD,
+	"-": RuleOperand.SUB,
+	"*": RuleOperand.MLT,
+	"/": RuleOperand.DIV,
+}
+
+var dslRoundingModes = map[string]RoundingModeCode{
+	"truncate":  RoundingMode.TRUNCATE,
+	"half_up":   RoundingMode.HALF_UP,
+	"half_even": RoundingMode.HALF_EVEN,
+	"floor":     RoundingMode.FLOOR,
+	"ceil":      RoundingMode.CEIL,
+}
+
+// literal kinds, they decide the type of a condition or modifer written without one
+const (
+	dslNoLiteral = iota
+	dslStringLiteral
+	dslIntLiteral
+	dslDecimalLiteral
+	dslDayLiteral
+	dslDateLiteral
+)
+
+type dslParser struct {
+	src string
+	pos int
+	tok dslToken
+}
+
+func newDSLParser(src string) (*dslParser, error) {
+	p := &dslParser{src: src}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *dslParser) errorAt(off int, format string, args ...interface{}) error {
+	line, col := 1, 1
+	for _, r := range p.src[:off] {
+		if r == '\n' {
+			line, col = line+1, 1
+		} else {
+			col++
+		}
+	}
+	return &SyntaxError{Line: line, Column: col, Msg: fmt.Sprintf(format, args...)}
+}
+
+// unexpected an error for the current token
+func (p *dslParser) unexpected(want string) error {
+	if p.tok.kind == dslEOF {
+		return p.errorAt(p.tok.off, "expected %s, found end of input", want)
+	}
+	return p.errorAt(p.tok.off, "expected %s, found %s", want, p.describe(p.tok))
+}
+
+func (p *dslParser) describe(tok dslToken) string {
+	if tok.kind == dslField {
+		return "`" + tok.text + "`"
+	}
+	return strconv.Quote(tok.text)
+}
+
+func (p *dslParser) next() error {
+	tok, err := p.scan()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *dslParser) scan() (dslToken, error) {
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' {
+			p.pos++
+			continue
+		}
+		if strings.HasPrefix(p.src[p.pos:], "//") {
+			end := strings.IndexByte(p.src[p.pos:], '\n')
+			if end < 0 {
+				end = len(p.src) - p.pos
+			}
+			p.pos += end
+			continue
+		}
+		break
+	}
+	off := p.pos
+	if off == len(p.src) {
+		return dslToken{kind: dslEOF, off: off}, nil
+	}
+	c := p.src[off]
+	switch {
+	case isDSLIdentStart(c):
+		end, err := scanDSLPath(p.src, off)
+		if err != nil {
+			return dslToken{}, p.errorAt(off, "%s", err.Error())
+		}
+		p.pos = end
+		return dslToken{kind: dslIdent, text: p.src[off:end], off: off}, nil
+	case c == '`':
+		end := strings.IndexByte(p.src[off+1:], '`')
+		if end < 0 {
+			return dslToken{}, p.errorAt(off, "unterminated `field`")
+		}
+		p.pos = off + end + 2
+		return dslToken{kind: dslField, text: p.src[off+1 : off+1+end], off: off}, nil
+	case c == '"':
+		text, tail, err := unquotePrefix(p.src[off:])
+		if err != nil {
+			return dslToken{}, p.errorAt(off, "invalid string")
+		}
+		p.pos = len(p.src) - len(tail)
+		return dslToken{kind: dslString, text: text, off: off}, nil
+	case isDSLDigit(c):
+		if end, ok := scanDSLDate(p.src, off); ok {
+			p.pos = end
+			return dslToken{kind: dslDate, text: p.src[off:end], off: off}, nil
+		}
+		end := scanDSLNumber(p.src, off)
+		if end < len(p.src) && (isDSLIdentStart(p.src[end]) || isDSLDigit(p.src[end]) || p.src[end] == '.') {
+			return dslToken{}, p.errorAt(off, "malformed number")
+		}
+		p.pos = end
+		return dslToken{kind: dslNumber, text: p.src[off:end], off: off}, nil
+	}
+	for _, punct := range []string{"!=", ">=", "<=", "+="} {
+		if strings.HasPrefix(p.src[off:], punct) {
+			p.pos = off + len(punct)
+			return dslToken{kind: dslPunct, text: punct, off: off}, nil
+		}
+	}
+	if strings.IndexByte("=<>+-*/%()[]{},#", c) >= 0 {
+		p.pos = off + 1
+		return dslToken{kind: dslPunct, text: string(c), off: off}, nil
+	}
+	r, _ := utf8.DecodeRuneInString(p.src[off:])
+	return dslToken{}, p.errorAt(off, "unexpected %q", r)
+}
+
+func isDSLIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDSLDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// scanDSLPath the end of the field path or keyword starting at off
+func scanDSLPath(src string, off int) (int, error) {
+	end := off
+	for end < len(src) {
+		c := src[end]
+		switch {
+		case isDSLIdentStart(c) || isDSLDigit(c):
+			end++
+		case c == '.' && end+1 < len(src) && isDSLIdentStart(src[end+1]):
+			end++
+		case c == '[':
+			if strings.HasPrefix(src[end+1:], `"`) {
+				_, tail, err := unquotePrefix(src[end+1:])
+				if err != nil || !strings.HasPrefix(tail, "]") {
+					return 0, fmt.Errorf("invalid key in field %s", src[off:end])
+				}
+				end = len(src) - len(tail) + 1
+				continue
+			}
+			close := strings.IndexByte(src[end:], ']')
+			if close < 0 {
+				return 0, fmt.Errorf("unterminated index in field %s", src[off:end])
+			}
+			end += close + 1
+		default:
+			return end, nil
+		}
+	}
+	return end, nil
+}
+
+func scanDSLNumber(src string, off int) int {
+	end := off
+	for end < len(src) && isDSLDigit(src[end]) {
+		end++
+	}
+	if end+1 < len(src) && src[end] == '.' && isDSLDigit(src[end+1]) {
+		end++
+		for end < len(src) && isDSLDigit(src[end]) {
+			end++
+		}
+	}
+	return end
+}
+
+// scanDSLDate a yyyy-mm-dd date at off
+func scanDSLDate(src string, off int) (int, bool) {
+	end := off + len("2006-01-02")
+	if end > len(src) {
+		return 0, false
+	}
+	if end < len(src) && (isDSLDigit(src[end]) || isDSLIdentStart(src[end])) {
+		return 0, false
+	}
+	for i, c := range []byte(src[off:end]) {
+		if (i == 4 || i == 7) != (c == '-') || (c != '-' && !isDSLDigit(c)) {
+			return 0, false
+		}
+	}
+	return end, true
+}
+
+func (p *dslParser) isKeyword(word string) bool {
+	return p.tok.kind == dslIdent && p.tok.text == word
+}
+
+func (p *dslParser) isPunct(punct string) bool {
+	return p.tok.kind == dslPunct && p.tok.text == punct
+}
+
+func (p *dslParser) expectKeyword(word string) error {
+	if !p.isKeyword(word) {
+		return p.unexpected(word)
+	}
+	return p.next()
+}
+
+func (p *dslParser) expectPunct(punct string) error {
+	if !p.isPunct(punct) {
+		return p.unexpected(strconv.Quote(punct))
+	}
+	return p.next()
+}
+
+func (p *dslParser) end() error {
+	if p.tok.kind != dslEOF {
+		return p.unexpected("end of input")
+	}
+	return nil
+}
+
+// isField whether the current token names a field
+func (p *dslParser) isField() bool {
+	return p.tok.kind == dslField || (p.tok.kind == dslIdent && !dslKeywords[p.tok.text] && dslDays[p.tok.text] == "")
+}
+
+func (p *dslParser) field() (string, error) {
+	if !p.isField() {
+		return "", p.unexpected("a field")
+	}
+	path := p.tok.text
+	if _, err := parsePath(path); err != nil {
+		return "", p.errorAt(p.tok.off, "invalid field %s", path)
+	}
+	return path, p.next()
+}
+
+func (p *dslParser) stringLiteral(what string) (string, error) {
+	if p.tok.kind != dslString {
+		return "", p.unexpected(what)
+	}
+	text := p.tok.text
+	return text, p.next()
+}
+
+// integer an optionally negative whole number
+func (p *dslParser) integer(what string, bits int) (int64, error) {
+	off := p.tok.off
+	text, ok, err := p.number()
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, p.unexpected(what)
+	}
+	n, err := strconv.ParseInt(text, 10, bits)
+	if err != nil {
+		return 0, p.errorAt(off, "invalid %s %s", what, text)
+	}
+	return n, nil
+}
+
+// number the text of an optionally negative number, ok is false when there is none
+func (p *dslParser) number() (string, bool, error) {
+	if p.isPunct("-") {
+		off := p.tok.off
+		if err := p.next(); err != nil {
+			return "", false, err
+		}
+		if p.tok.kind != dslNumber {
+			return "", false, p.errorAt(off, "expected a number after -")
+		}
+		text := "-" + p.tok.text
+		return text, true, p.next()
+	}
+	if p.tok.kind != dslNumber {
+		return "", false, nil
+	}
+	text := p.tok.text
+	return text, true, p.next()
+}
+
+func (p *dslParser) setting() (RuleSetting, error) {
+	rs := RuleSetting{Enable: true}
+	if err := p.expectKeyword("setting"); err != nil {
+		return rs, err
+	}
+	if p.tok.kind == dslString {
+		rs.ID = p.tok.text
+		if err := p.next(); err != nil {
+			return rs, err
+		}
+	}
+	for {
+		var err error
+		switch {
+		case p.isKeyword("rule"):
+			if err = p.next(); err == nil {
+				rs.RuleID, err = p.stringLiteral("a rule set id")
+			}
+		case p.isKeyword("sequence"):
+			if err = p.next(); err == nil {
+				rs.Sequence, err = p.integer("sequence", 64)
+			}
+		case p.isKeyword("type"):
+			var n int64
+			if err = p.next(); err == nil {
+				n, err = p.integer("rule type", 0)
+				rs.RuleType = int(n)
+			}
+		case p.isKeyword("disabled"):
+			rs.Enable = false
+			err = p.next()
+		case p.isKeyword("break"):
+			rs.BreakOnFail = true
+			err = p.next()
+		case p.isKeyword("from"):
+			if err = p.next(); err == nil {
+				rs.ValidFrom, err = p.timestamp()
+			}
+		case p.isKeyword("until"):
+			if err = p.next(); err == nil {
+				rs.ValidTo, err = p.timestamp()
+			}
+		default:
+			r, err := p.rule()
+			if err != nil {
+				return rs, err
+			}
+			rs.Rule = *r
+			return rs, nil
+		}
+		if err != nil {
+			return rs, err
+		}
+	}
+}
+
+func (p *dslParser) timestamp() (*time.Time, error) {
+	off := p.tok.off
+	text, err := p.stringLiteral("an RFC 3339 time")
+	if err != nil {
+		return nil, err
+	}
+	t, err := time.Parse(time.RFC3339Nano, text)
+	if err != nil {
+		return nil, p.errorAt(off, "invalid time %q, expected RFC 3339", text)
+	}
+	return &t, nil
+}
+
+func (p *dslParser) rule() (*Rule, error) {
+	r := &Rule{ConditionChain: []Condition{}, ModiferChain: []Modifer{}}
+	if p.isKeyword("when") {
+		for {
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			c, err := p.condition()
+			if err != nil {
+				return nil, err
+			}
+			r.ConditionChain = append(r.ConditionChain, *c)
+			if !p.isKeyword("and") {
+				break
+			}
+		}
+	}
+	if p.isKeyword("match") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		g, err := p.group()
+		if err != nil {
+			return nil, err
+		}
+		r.ConditionTree = g
+	}
+	if err := p.expectKeyword("then"); err != nil {
+		return nil, err
+	}
+	for p.tok.kind != dslEOF && !p.isKeyword("setting") {
+		m, err := p.modifer(len(r.ModiferChain))
+		if err != nil {
+			return nil, err
+		}
+		r.ModiferChain = append(r.ModiferChain, *m)
+	}
+	return r, nil
+}
+
+func (p *dslParser) group() (*ConditionGroup, error) {
+	mode, ok := dslGroupModes[p.tok.text]
+	if p.tok.kind != dslIdent || !ok {
+		return nil, p.unexpected("all, any or none")
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if err := p.expectPunct("("); err != nil {
+		return nil, err
+	}
+	g := &ConditionGroup{Mode: mode}
+	for !p.isPunct(")") {
+		if len(g.Conditions)+len(g.Groups) > 0 {
+			if err := p.expectPunct(","); err != nil {
+				return nil, err
+			}
+		}
+		if _, ok := dslGroupModes[p.tok.text]; ok && p.tok.kind == dslIdent {
+			sub, err := p.group()
+			if err != nil {
+				return nil, err
+			}
+			g.Groups = append(g.Groups, *sub)
+			continue
+		}
+		if len(g.Groups) > 0 {
+			return nil, p.errorAt(p.tok.off, "conditions of a group come before its groups")
+		}
+		c, err := p.condition()
+		if err != nil {
+			return nil, err
+		}
+		g.Conditions = append(g.Conditions, *c)
+	}
+	return g, p.next()
+}
+
+// dslSide a condition or modifer side as written
+type dslSide struct {
+	off     int
+	field   bool
+	text    string // the field path or the literal, unquoted
+	kind    int    // of the literal
+	list    bool
+	items   []dslSide
+	complex bool // text is the JSON of a ModiferComplex
+}
+
+// literalKind the kind telling the type of whatever holds the side
+func (s dslSide) literalKind() int {
+	if s.list {
+		if len(s.items) == 0 {
+			return dslNoLiteral
+		}
+		return s.items[0].kind
+	}
+	if s.field || s.complex {
+		return dslNoLiteral
+	}
+	return s.kind
+}
+
+func (p *dslParser) condition() (*Condition, error) {
+	if p.isKeyword("always") {
+		return &Condition{Type: RuleConditionType.MUST}, p.next()
+	}
+	conditionType, typed := ConditionTypeCode(-1), false
+	if t, ok := dslConditionTypes[p.tok.text]; ok && p.tok.kind == dslIdent {
+		conditionType, typed = t, true
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+	left, err := p.conditionSide(false)
+	if err != nil {
+		return nil, err
+	}
+	compareOff := p.tok.off
+	compare, err := p.compare()
+	if err != nil {
+		return nil, err
+	}
+	inList := compare == RuleConditionCompare.IN || compare == RuleConditionCompare.NOT_IN
+	right, err := p.conditionSide(inList)
+	if err != nil {
+		return nil, err
+	}
+	if !typed {
+		conditionType = inferConditionType(left, right)
+	}
+	if right.list && !inList {
+		return nil, p.errorAt(compareOff, "a list needs in or not in")
+	}
+
+	c := &Condition{Type: conditionType, Compare: compare}
+	if c.LeftSide, c.LeftType, err = p.conditionValue(conditionType, left); err != nil {
+		return nil, err
+	}
+	if c.RightSide, c.RightType, err = p.conditionValue(conditionType, right); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (p *dslParser) compare() (CompareCode, error) {
+	if p.isKeyword("not") {
+		if err := p.next(); err != nil {
+			return 0, err
+		}
+		if !p.isKeyword("in") {
+			return 0, p.unexpected("in")
+		}
+		return RuleConditionCompare.NOT_IN, p.next()
+	}
+	compare, ok := dslCompares[p.tok.text]
+	if !ok || (p.tok.kind != dslPunct && p.tok.kind != dslIdent) {
+		return 0, p.unexpected("a compare")
+	}
+	return compare, p.next()
+}
+
+func (p *dslParser) conditionSide(list bool) (dslSide, error) {
+	if list && p.isPunct("[") {
+		side := dslSide{off: p.tok.off, list: true}
+		if err := p.next(); err != nil {
+			return side, err
+		}
+		for !p.isPunct("]") {
+			if len(side.items) > 0 {
+				if err := p.expectPunct(","); err != nil {
+					return side, err
+				}
+			}
+			item, err := p.literal()
+			if err != nil {
+				return side, err
+			}
+			side.items = append(side.items, item)
+		}
+		return side, p.next()
+	}
+	if p.isField() {
+		side := dslSide{off: p.tok.off, field: true}
+		var err error
+		side.text, err = p.field()
+		return side, err
+	}
+	return p.literal()
+}
+
+func (p *dslParser) literal() (dslSide, error) {
+	side := dslSide{off: p.tok.off, text: p.tok.text}
+	switch p.tok.kind {
+	case dslString:
+		side.kind = dslStringLiteral
+	case dslDate:
+		side.kind = dslDateLiteral
+	case dslIdent:
+		day, ok := dslDays[p.tok.text]
+		if !ok {
+			return side, p.unexpected("a value")
+		}
+		side.kind, side.text = dslDayLiteral, day
+	default:
+		text, ok, err := p.number()
+		if err != nil {
+			return side, err
+		}
+		if !ok {
+			return side, p.unexpected("a value")
+		}
+		side.text, side.kind = text, dslIntLiteral
+		if strings.Contains(text, ".") {
+			side.kind = dslDecimalLiteral
+		}
+		return side, nil
+	}
+	return side, p.next()
+}
+
+func inferConditionType(sides ...dslSide) ConditionTypeCode {
+	for _, side := range sides {
+		switch side.literalKind() {
+		case dslStringLiteral:
+			return RuleConditionType.STRING
+		case dslIntLiteral:
+			return RuleConditionType.INT
+		case dslDecimalLiteral:
+			return RuleConditionType.DECIMAL
+		case dslDayLiteral:
+			return RuleConditionType.DAY_OF_WEEK
+		case dslDateLiteral:
+			return RuleConditionType.DATE
+		}
+	}
+	return RuleConditionType.INT
+}
+
+// conditionValue the stored form of side in a condition of conditionType
+func (p *dslParser) conditionValue(conditionType ConditionTypeCode, side dslSide) (string, SideCode, error) {
+	if side.field {
+		return side.text, ConditionSideType.FIELD, nil
+	}
+	if !side.list {
+		text, err := p.literalValue(conditionType, side)
+		return text, ConditionSideType.VALUE, err
+	}
+	items := make([]string, len(side.items))
+	for i, item := range side.items {
+		text, err := p.literalValue(conditionType, item)
+		if err != nil {
+			return "", 0, err
+		}
+		if strings.Contains(text, ",") {
+			return "", 0, p.errorAt(item.off, "list items cannot hold a comma")
+		}
+		items[i] = text
+	}
+	return strings.Join(items, ","), ConditionSideType.VALUE, nil
+}
+
+func (p *dslParser) literalValue(conditionType ConditionTypeCode, side dslSide) (string, error) {
+	switch side.kind {
+	case dslDayLiteral:
+		if conditionType != RuleConditionType.DAY_OF_WEEK {
+			return "", p.errorAt(side.off, "day %s in a condition that is not a weekday", side.text)
+		}
+		return DayOfWeekUnix[side.text], nil
+	case dslDateLiteral:
+		if conditionType != RuleConditionType.DATE {
+			return "", p.errorAt(side.off, "date %s in a condition that is not a date", side.text)
+		}
+		t, err := time.Parse("2006-01-02", side.text)
+		if err != nil {
+			return "", p.errorAt(side.off, "invalid date %s", side.text)
+		}
+		return strconv.FormatInt(t.Unix(), 10), nil
+	}
+	return side.text, nil
+}
+
+// modifer the modifer at position in its rule, whose Sequence it takes unless one is written
+func (p *dslParser) modifer(position int) (*Modifer, error) {
+	m := &Modifer{Sequence: position}
+	if p.isPunct("#") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		n, err := p.integer("modifer sequence", 0)
+		if err != nil {
+			return nil, err
+		}
+		m.Sequence = int(n)
+	}
+
+	if p.isKeyword("jump") || p.isKeyword("call") {
+		m.DataType = ModiferDataType.JMP
+		if p.isKeyword("call") {
+			m.DataType = ModiferDataType.JRT
+		}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		var err error
+		if m.LeftSide, err = p.stringLiteral("a rule set id"); err != nil {
+			return nil, err
+		}
+		m.RightSide = "0"
+		if p.tok.kind == dslString {
+			m.RightSide = p.tok.text
+			return m, p.next()
+		}
+		if text, ok, err := p.number(); err != nil {
+			return nil, err
+		} else if ok {
+			m.RightSide = text
+		}
+		return m, nil
+	}
+
+	dataType, typed := DataTypeCode(-1), false
+	if t, ok := dslModiferTypes[p.tok.text]; ok && p.tok.kind == dslIdent {
+		dataType, typed = t, true
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+	target, err := p.field()
+	if err != nil {
+		return nil, err
+	}
+	m.TargetField = target
+
+	var left, right dslSide
+	switch {
+	case p.isPunct("+="):
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if err := p.expectKeyword("sum"); err != nil {
+			return nil, err
+		}
+		m.Operand = RuleOperand.SUM
+		if left, right, err = p.selection(); err != nil {
+			return nil, err
+		}
+	case p.isPunct("="):
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if p.isKeyword("select") {
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			m.Operand = RuleOperand.SEL
+			if left, right, err = p.selection(); err != nil {
+				return nil, err
+			}
+			break
+		}
+		if left, err = p.modiferSide(false); err != nil {
+			return nil, err
+		}
+		operand, ok := dslOperands[p.tok.text]
+		if !ok || p.tok.kind != dslPunct {
+			m.Operand = RuleOperand.SET
+			break
+		}
+		m.Operand = operand
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if right, err = p.modiferSide(true); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, p.unexpected(`"=" or "+="`)
+	}
+
+	if !typed {
+		dataType = inferModiferType(left, right)
+	}
+	m.DataType = dataType
+	m.LeftSide, m.LeftType = modiferValue(left)
+	if m.Operand != RuleOperand.SET {
+		m.RightSide, m.RightType = modiferValue(right)
+	}
+
+	if p.isKeyword("round") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		r := &Rounding{}
+		if mode, ok := dslRoundingModes[p.tok.text]; ok && p.tok.kind == dslIdent {
+			r.Mode = mode
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+		} else {
+			n, err := p.integer("rounding mode", 0)
+			if err != nil {
+				return nil, err
+			}
+			r.Mode = RoundingModeCode(n)
+		}
+		if p.tok.kind == dslNumber || p.isPunct("-") {
+			n, err := p.integer("rounding places", 32)
+			if err != nil {
+				return nil, err
+			}
+			r.Places = int32(n)
+		}
+		m.Rounding = r
+	}
+	return m, nil
+}
+
+// selection the field from complex of SEL and SUM
+func (p *dslParser) selection() (dslSide, dslSide, error) {
+	var left, right dslSide
+	left.off, left.field = p.tok.off, true
+	var err error
+	if left.text, err = p.field(); err != nil {
+		return left, right, err
+	}
+	if err := p.expectKeyword("from"); err != nil {
+		return left, right, err
+	}
+	right, err = p.modiferSide(true)
+	return left, right, err
+}
+
+func (p *dslParser) modiferSide(complex bool) (dslSide, error) {
+	side := dslSide{off: p.tok.off}
+	switch {
+	case complex && p.isPunct("{"):
+		end, err := scanJSONObject(p.src, p.tok.off)
+		if err != nil {
+			return side, p.errorAt(p.tok.off, "%s", err.Error())
+		}
+		side.complex, side.text = true, p.src[p.tok.off:end]
+		p.pos = end
+		return side, p.next()
+	case complex && p.isKeyword("json"):
+		if err := p.next(); err != nil {
+			return side, err
+		}
+		side.complex = true
+		var err error
+		side.text, err = p.stringLiteral("the JSON of a complex side")
+		return side, err
+	case p.isField():
+		side.field = true
+		var err error
+		side.text, err = p.field()
+		return side, err
+	case p.tok.kind == dslString:
+		side.kind, side.text = dslStringLiteral, p.tok.text
+		return side, p.next()
+	}
+
+	text, ok, err := p.number()
+	if err != nil {
+		return side, err
+	}
+	if !ok {
+		return side, p.unexpected("a value")
+	}
+	if complex && p.isPunct("%") {
+		if err := p.next(); err != nil {
+			return side, err
+		}
+		side.complex, side.text = true, `{"percentage":`+text+`}`
+		if p.isKeyword("max") {
+			if err := p.next(); err != nil {
+				return side, err
+			}
+			flat, ok, err := p.number()
+			if err != nil {
+				return side, err
+			}
+			if !ok {
+				return side, p.unexpected("a number")
+			}
+			side.text = `{"flat":` + flat + `,"percentage":` + text + `}`
+		}
+		return side, nil
+	}
+	side.text, side.kind = text, dslIntLiteral
+	if strings.Contains(text, ".") {
+		side.kind = dslDecimalLiteral
+	}
+	return side, nil
+}
+
+// scanJSONObject the end of the JSON object starting at off, strings may hold braces
+func scanJSONObject(src string, off int) (int, error) {
+	depth := 0
+	for i := off; i < len(src); i++ {
+		switch src[i] {
+		case '"':
+			_, tail, err := unquotePrefix(src[i:])
+			if err != nil {
+				return 0, fmt.Errorf("invalid string in JSON")
+			}
+			i = len(src) - len(tail) - 1
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i + 1, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("unterminated JSON object")
+}
+
+func inferModiferType(sides ...dslSide) DataTypeCode {
+	for _, side := range sides {
+		switch side.literalKind() {
+		case dslStringLiteral:
+			return ModiferDataType.STRING
+		case dslIntLiteral:
+			return ModiferDataType.INT
+		case dslDecimalLiteral:
+			return ModiferDataType.DECIMAL
+		}
+	}
+	return ModiferDataType.INT
+}
+
+func modiferValue(side dslSide) (string, SideCode) {
+	switch {
+	case side.field:
+		return side.text, ModiferSideType.FIELD
+	case side.complex:
+		return side.text, ModiferSideType.COMPLEX
+	}
+	return side.text, ModiferSideType.VALUE
+}
+
+// FormatSettings write rs in the rule DSL. ParseSettings gives them back, apart from what the engine never reads:
+// the sides of an always condition, the right side of a SET, the operand, side types and target of a jump,
+// and the left side type of SEL and SUM which comes back as a field. Enable reads back as written
+func FormatSettings(rs []RuleSetting) (string, error) {
+	var b strings.Builder
+	for i, setting := range rs {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		if err := formatSetting(&b, setting); err != nil {
+			return "", fmt.Errorf("setting %d: %w", i, err)
+		}
+	}
+	return b.String(), nil
+}
+
+// FormatRule write the when, match and then parts of r as ParseRule reads them
+func FormatRule(r Rule) (string, error) {
+	var b strings.Builder
+	if err := formatRule(&b, r); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func formatSetting(b *strings.Builder, rs RuleSetting) error {
+	b.WriteString("setting")
+	if rs.ID != "" {
+		b.WriteString(" " + strconv.Quote(rs.ID))
+	}
+	if rs.RuleID != "" {
+		b.WriteString(" rule " + strconv.Quote(rs.RuleID))
+	}
+	if rs.Sequence != 0 {
+		b.WriteString(" sequence " + strconv.FormatInt(rs.Sequence, 10))
+	}
+	if rs.RuleType != 0 {
+		b.WriteString(" type " + strconv.Itoa(rs.RuleType))
+	}
+	if !rs.Enable {
+		b.WriteString(" disabled")
+	}
+	if rs.BreakOnFail {
+		b.WriteString(" break")
+	}
+	if rs.ValidFrom != nil {
+		b.WriteString(" from " + strconv.Quote(rs.ValidFrom.Format(time.RFC3339Nano)))
+	}
+	if rs.ValidTo != nil {
+		b.WriteString(" until " + strconv.Quote(rs.ValidTo.Format(time.RFC3339Nano)))
+	}
+	b.WriteString("\n")
+	return formatRule(b, rs.Rule)
+}
+
+func formatRule(b *strings.Builder, r Rule) error {
+	for i, c := range r.ConditionChain {
+		text, err := FormatCondition(c)
+		if err != nil {
+			return fmt.Errorf("condition %d: %w", i, err)
+		}
+		if i == 0 {
+			b.WriteString("when " + text + "\n")
+		} else {
+			b.WriteString("  and " + text + "\n")
+		}
+	}
+	if r.ConditionTree != nil {
+		text, err := formatGroup(*r.ConditionTree)
+		if err != nil {
+			return fmt.Errorf("condition tree: %w", err)
+		}
+		b.WriteString("match " + text + "\n")
+	}
+	b.WriteString("then\n")
+	for i, m := range r.ModiferChain {
+		text, err := formatModifer(m, i)
+		if err != nil {
+			return fmt.Errorf("modifer %d: %w", i, err)
+		}
+		b.WriteString("  " + text + "\n")
+	}
+	return nil
+}
+
+func formatGroup(g ConditionGroup) (string, error) {
+	var mode string
+	for name, m := range dslGroupModes {
+		if m == g.Mode {
+			mode = name
+		}
+	}
+	if mode == "" {
+		return "", fmt.Errorf("%w: group mode %d", RuleSettingError.UNSUPPORTED_OPERATION, g.Mode)
+	}
+	var items []string
+	for _, c := range g.Conditions {
+		text, err := FormatCondition(c)
+		if err != nil {
+			return "", err
+		}
+		items = append(items, text)
+	}
+	for _, sub := range g.Groups {
+		text, err := formatGroup(sub)
+		if err != nil {
+			return "", err
+		}
+		items = append(items, text)
+	}
+	return mode + "(" + strings.Join(items, ", ") + ")", nil
+}
+
+// FormatCondition write c as ParseCondition reads it
+func FormatCondition(c Condition) (string, error) {
+	if c.Type == RuleConditionType.MUST {
+		return "always", nil
+	}
+	var typeName string
+	for name, t := range dslConditionTypes {
+		if t == c.Type {
+			typeName = name
+		}
+	}
+	if typeName == "" {
+		return "", fmt.Errorf("%w: condition type %d", RuleSettingError.UNSUPPORTED_OPERATION, c.Type)
+	}
+	var compare string
+	for text, cmp := range dslCompares {
+		if cmp == c.Compare {
+			compare = text
+		}
+	}
+	if compare == "" {
+		return "", fmt.Errorf("%w: compare %d", RuleSettingError.UNSUPPORTED_OPERATION, c.Compare)
+	}
+
+	inList := c.Compare == RuleConditionCompare.IN || c.Compare == RuleConditionCompare.NOT_IN
+	left, leftKind, leftExact, err := formatConditionSide(c.Type, c.LeftSide, c.LeftType, false)
+	if err != nil {
+		return "", fmt.Errorf("left side: %w", err)
+	}
+	right, rightKind, rightExact, err := formatConditionSide(c.Type, c.RightSide, c.RightType, inList)
+	if err != nil {
+		return "", fmt.Errorf("right side: %w", err)
+	}
+	text := left + " " + compare + " " + right
+	inferred := inferConditionType(dslSide{kind: leftKind}, dslSide{kind: rightKind})
+	if !leftExact || !rightExact || inferred != c.Type {
+		text = typeName + " " + text
+	}
+	return text, nil
+}
+
+// formatConditionSide the side as written, the kind of its literal and whether that literal holds its own type
+func formatConditionSide(conditionType ConditionTypeCode, value string, sideType SideCode, list bool) (string, int, bool, error) {
+	switch sideType {
+	case ConditionSideType.FIELD:
+		text, err := formatField(value)
+		return text, dslNoLiteral, true, err
+	case ConditionSideType.VALUE:
+	default:
+		return "", 0, false, fmt.Errorf("%w: side type %d", RuleSettingError.CONDITION_SIDE_INVALID, sideType)
+	}
+	if !list {
+		text, kind, exact := formatConditionLiteral(conditionType, value)
+		return text, kind, exact, nil
+	}
+	if value == "" {
+		return "[]", dslNoLiteral, true, nil
+	}
+	items := strings.Split(value, ",")
+	texts := make([]string, len(items))
+	kind := dslNoLiteral
+	for i, item := range items {
+		text, itemKind, exact := formatConditionLiteral(conditionType, item)
+		if !exact || (i > 0 && itemKind != kind) {
+			// written whole as a string under an explicit type
+			return strconv.Quote(value), dslStringLiteral, false, nil
+		}
+		texts[i], kind = text, itemKind
+	}
+	return "[" + strings.Join(texts, ", ") + "]", kind, true, nil
+}
+
+// formatConditionLiteral exact is false when value can only be written as a string under an explicit type
+func formatConditionLiteral(conditionType ConditionTypeCode, value string) (string, int, bool) {
+	switch conditionType {
+	case RuleConditionType.STRING:
+		return strconv.Quote(value), dslStringLiteral, true
+	case RuleConditionType.DAY_OF_WEEK:
+		for day, unix := range DayOfWeekUnix {
+			if unix == value {
+				return day, dslDayLiteral, true
+			}
+		}
+	case RuleConditionType.DATE:
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil && strconv.FormatInt(n, 10) == value && n%86400 == 0 {
+			if t := time.Unix(n, 0).UTC(); t.Year() >= 1000 && t.Year() <= 9999 {
+				return t.Format("2006-01-02"), dslDateLiteral, true
+			}
+		}
+	}
+	if kind, ok := dslNumberKind(value); ok {
+		if conditionType == RuleConditionType.DECIMAL || kind == dslIntLiteral {
+			return value, kind, true
+		}
+	}
+	return strconv.Quote(value), dslStringLiteral, false
+}
+
+// dslNumberKind whether value reads back as the same number literal, and its kind
+func dslNumberKind(value string) (int, bool) {
+	digits := strings.TrimPrefix(value, "-")
+	if digits == "" || !isDSLDigit(digits[0]) || scanDSLNumber(digits, 0) != len(digits) {
+		return 0, false
+	}
+	if _, ok := scanDSLDate(digits, 0); ok {
+		return 0, false
+	}
+	if strings.Contains(digits, ".") {
+		return dslDecimalLiteral, true
+	}
+	return dslIntLiteral, true
+}
+
+// isDSLNumber whether value is written as it is, a number of JSON is not always one of the DSL
+func isDSLNumber(value string) bool {
+	_, ok := dslNumberKind(value)
+	return ok
+}
+
+// formatField path as written, in backquotes when it would read as something else
+func formatField(path string) (string, error) {
+	if path != "" && isDSLIdentStart(path[0]) && !dslKeywords[path] && dslDays[path] == "" {
+		if end, err := scanDSLPath(path, 0); err == nil && end == len(path) {
+			return path, nil
+		}
+	}
+	if path == "" || strings.Contains(path, "`") {
+		return "", fmt.Errorf("%w: field %q cannot be written", RuleSettingError.FIELD_PATH_INVALID, path)
+	}
+	return "`" + path + "`", nil
+}
+
+// FormatModifer write m as ParseModifer reads it
+func FormatModifer(m Modifer) (string, error) {
+	return formatModifer(m, 0)
+}
+
+// formatModifer write m, at position in its rule, leaving out a Sequence the parser would give it
+func formatModifer(m Modifer, position int) (string, error) {
+	var b strings.Builder
+	if m.Sequence != position {
+		b.WriteString("#" + strconv.Itoa(m.Sequence) + " ")
+	}
+	if m.DataType == ModiferDataType.JMP || m.DataType == ModiferDataType.JRT {
+		if m.DataType == ModiferDataType.JMP {
+			b.WriteString("jump ")
+		} else {
+			b.WriteString("call ")
+		}
+		b.WriteString(strconv.Quote(m.LeftSide))
+		if m.RightSide != "0" {
+			if kind, ok := dslNumberKind(m.RightSide); ok && kind == dslIntLiteral {
+				b.WriteString(" " + m.RightSide)
+			} else {
+				b.WriteString(" " + strconv.Quote(m.RightSide))
+			}
+		}
+		return b.String(), nil
+	}
+
+	var typeName string
+	for name, t := range dslModiferTypes {
+		if t == m.DataType {
+			typeName = name
+		}
+	}
+	if typeName == "" {
+		return "", fmt.Errorf("%w: modifer data type %d", RuleSettingError.UNSUPPORTED_OPERATION, m.DataType)
+	}
+	target, err := formatField(m.TargetField)
+	if err != nil {
+		return "", fmt.Errorf("target: %w", err)
+	}
+
+	var expr string
+	exact := true
+	var kinds []int
+	side := func(value string, sideType SideCode, complex bool) (string, error) {
+		text, kind, ok, err := formatModiferSide(m.DataType, value, sideType, complex)
+		exact = exact && ok
+		kinds = append(kinds, kind)
+		return text, err
+	}
+	switch m.Operand {
+	case RuleOperand.SET:
+		left, err := side(m.LeftSide, m.LeftType, false)
+		if err != nil {
+			return "", fmt.Errorf("left side: %w", err)
+		}
+		expr = target + " = " + left
+	case RuleOperand.ADD, RuleOperand.SUB, RuleOperand.MLT, RuleOperand.DIV:
+		left, err := side(m.LeftSide, m.LeftType, false)
+		if err != nil {
+			return "", fmt.Errorf("left side: %w", err)
+		}
+		right, err := side(m.RightSide, m.RightType, true)
+		if err != nil {
+			return "", fmt.Errorf("right side: %w", err)
+		}
+		var op string
+		for text, operand := range dslOperands {
+			if operand == m.Operand {
+				op = text
+			}
+		}
+		expr = target + " = " + left + " " + op + " " + right
+	case RuleOperand.SEL, RuleOperand.SUM:
+		from, err := formatField(m.LeftSide)
+		if err != nil {
+			return "", fmt.Errorf("left side: %w", err)
+		}
+		right, err := side(m.RightSide, m.RightType, true)
+		if err != nil {
+			return "", fmt.Errorf("right side: %w", err)
+		}
+		if m.Operand == RuleOperand.SEL {
+			expr = target + " = select " + from + " from " + right
+		} else {
+			expr = target + " += sum " + from + " from " + right
+		}
+	default:
+		return "", fmt.Errorf("%w: operand %d", RuleSettingError.UNSUPPORTED_OPERATION, m.Operand)
+	}
+
+	inferred := ModiferDataType.INT
+	for _, kind := range kinds {
+		if t := inferModiferType(dslSide{kind: kind}); kind != dslNoLiteral {
+			inferred = t
+			break
+		}
+	}
+	if !exact || inferred != m.DataType {
+		b.WriteString(typeName + " ")
+	}
+	b.WriteString(expr)
+
+	if m.Rounding != nil {
+		mode := strconv.Itoa(int(m.Rounding.Mode))
+		for name, rm := range dslRoundingModes {
+			if rm == m.Rounding.Mode {
+				mode = name
+			}
+		}
+		b.WriteString(" round " + mode)
+		if m.Rounding.Places != 0 {
+			b.WriteString(" " + strconv.Itoa(int(m.Rounding.Places)))
+		}
+	}
+	return b.String(), nil
+}
+
+// formatModiferSide the side as written, the kind of its literal and whether that literal holds its own type
+func formatModiferSide(dataType DataTypeCode, value string, sideType SideCode, complex bool) (string, int, bool, error) {
+	switch sideType {
+	case ModiferSideType.FIELD:
+		text, err := formatField(value)
+		return text, dslNoLiteral, true, err
+	case ModiferSideType.COMPLEX:
+		if !complex {
+			break
+		}
+		return formatComplex(value), dslNoLiteral, true, nil
+	case ModiferSideType.VALUE:
+		if dataType == ModiferDataType.STRING {
+			return strconv.Quote(value), dslStringLiteral, true, nil
+		}
+		if kind, ok := dslNumberKind(value); ok && (dataType == ModiferDataType.DECIMAL || kind == dslIntLiteral) {
+			return value, kind, true, nil
+		}
+		return strconv.Quote(value), dslStringLiteral, false, nil
+	}
+	return "", 0, false, fmt.Errorf("%w: side type %d", RuleSettingError.MODIFER_SIDE_INVALID, sideType)
+}
+
+// formatComplex the shortest form that reads back as the same JSON text
+func formatComplex(value string) string {
+	if rest, ok := strings.CutPrefix(value, `{"percentage":`); ok {
+		if pct, ok := strings.CutSuffix(rest, "}"); ok && isDSLNumber(pct) {
+			return pct + "%"
+		}
+	}
+	if rest, ok := strings.CutPrefix(value, `{"flat":`); ok {
+		flat, rest, _ := strings.Cut(rest, `,"percentage":`)
+		if pct, ok := strings.CutSuffix(rest, "}"); ok && isDSLNumber(pct) && isDSLNumber(flat) {
+			return pct + "% max " + flat
+		}
+	}
+	if strings.HasPrefix(value, "{") {
+		if end, err := scanJSONObject(value, 0); err == nil && end == len(value) {
+			return value
+		}
+	}
+	return "json " + strconv.Quote(value)
+}
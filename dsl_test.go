@@ -0,0 +1,399 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func dslCondition(conditionType ConditionTypeCode, left string, compare CompareCode, right string, rightType SideCode) Condition {
+	return Condition{
+		Type:      conditionType,
+		LeftSide:  left,
+		LeftType:  ConditionSideType.FIELD,
+		Compare:   compare,
+		RightSide: right,
+		RightType: rightType,
+	}
+}
+
+func dslModifer(dataType DataTypeCode, target string, operand OperandCode, right string, rightType SideCode) Modifer {
+	return Modifer{
+		Operand:     operand,
+		DataType:    dataType,
+		LeftSide:    target,
+		LeftType:    ModiferSideType.FIELD,
+		RightSide:   right,
+		RightType:   rightType,
+		TargetField: target,
+	}
+}
+
+func dslConditions() map[string]Condition {
+	value, field := ConditionSideType.VALUE, ConditionSideType.FIELD
+	weekend := DayOfWeekUnix["Saturday"] + "," + DayOfWeekUnix["Sunday"]
+	june := "1780272000" // 2026-06-01
+	return map[string]Condition{
+		"IntEqual":        dslCondition(RuleConditionType.INT, "Adults", RuleConditionCompare.EQUAL, "2", value),
+		"IntNot":          dslCondition(RuleConditionType.INT, "Adults", RuleConditionCompare.NOT, "-2", value),
+		"IntMore":         dslCondition(RuleConditionType.INT, "Adults", RuleConditionCompare.MORE, "2", value),
+		"IntLess":         dslCondition(RuleConditionType.INT, "Adults", RuleConditionCompare.LESS, "2", value),
+		"IntMoreEqual":    dslCondition(RuleConditionType.INT, "Adults", RuleConditionCompare.MORE_EQUAL, "2", value),
+		"IntLessEqual":    dslCondition(RuleConditionType.INT, "Adults", RuleConditionCompare.LESS_EQUAL, "2", value),
+		"IntIn":           dslCondition(RuleConditionType.INT, "Adults", RuleConditionCompare.IN, "1,2", value),
+		"IntNotIn":        dslCondition(RuleConditionType.INT, "Adults", RuleConditionCompare.NOT_IN, "1,2", value),
+		"IntEmptyIn":      dslCondition(RuleConditionType.INT, "Adults", RuleConditionCompare.IN, "", value),
+		"IntFields":       dslCondition(RuleConditionType.INT, "Adults", RuleConditionCompare.MORE, "Children", field),
+		"IntRawList":      dslCondition(RuleConditionType.INT, "Adults", RuleConditionCompare.IN, "1, 2", value),
+		"IntInField":      dslCondition(RuleConditionType.INT, "Adults", RuleConditionCompare.IN, "Allowed", field),
+		"Decimal":         dslCondition(RuleConditionType.DECIMAL, "Price", RuleConditionCompare.MORE_EQUAL, "2.5", value),
+		"DecimalWhole":    dslCondition(RuleConditionType.DECIMAL, "Price", RuleConditionCompare.LESS, "2", value),
+		"DecimalFields":   dslCondition(RuleConditionType.DECIMAL, "Price", RuleConditionCompare.LESS, "Budget", field),
+		"String":          dslCondition(RuleConditionType.STRING, "Channel", RuleConditionCompare.EQUAL, "web", value),
+		"StringQuoted":    dslCondition(RuleConditionType.STRING, "Channel", RuleConditionCompare.NOT, `say "hi"`, value),
+		"StringIn":        dslCondition(RuleConditionType.STRING, "Channel", RuleConditionCompare.IN, "web,app", value),
+		"StringNumber":    dslCondition(RuleConditionType.STRING, "Code", RuleConditionCompare.EQUAL, "42", value),
+		"Package":         dslCondition(RuleConditionType.STRING, "Codes", RuleConditionCompare.PACKAGE, "A,B", value),
+		"Weekday":         dslCondition(RuleConditionType.DAY_OF_WEEK, "CheckIn", RuleConditionCompare.EQUAL, DayOfWeekUnix["Friday"], value),
+		"WeekdayIn":       dslCondition(RuleConditionType.DAY_OF_WEEK, "CheckIn", RuleConditionCompare.IN, weekend, value),
+		"WeekdayNotIn":    dslCondition(RuleConditionType.DAY_OF_WEEK, "CheckIn", RuleConditionCompare.NOT_IN, weekend, value),
+		"WeekdayFields":   dslCondition(RuleConditionType.DAY_OF_WEEK, "CheckIn", RuleConditionCompare.EQUAL, "CheckOut", field),
+		"Date":            dslCondition(RuleConditionType.DATE, "CheckIn", RuleConditionCompare.MORE_EQUAL, june, value),
+		"DateIn":          dslCondition(RuleConditionType.DATE, "CheckIn", RuleConditionCompare.IN, june+",1780358400", value),
+		"DateNotMidnight": dslCondition(RuleConditionType.DATE, "CheckIn", RuleConditionCompare.LESS, "1780272001", value),
+		"DateFields":      dslCondition(RuleConditionType.DATE, "CheckIn", RuleConditionCompare.LESS, "CheckOut", field),
+		"Always":          {Type: RuleConditionType.MUST},
+		"QuotedField":     dslCondition(RuleConditionType.INT, "when", RuleConditionCompare.EQUAL, "Rooms[0].Adults", field),
+		"MapField":        dslCondition(RuleConditionType.STRING, `Attributes["channel"]`, RuleConditionCompare.EQUAL, "web", value),
+	}
+}
+
+func dslModifers() map[string]Modifer {
+	value, field, complex := ModiferSideType.VALUE, ModiferSideType.FIELD, ModiferSideType.COMPLEX
+	rounded := func(m Modifer, mode RoundingModeCode, places int32) Modifer {
+		m.Rounding = &Rounding{Mode: mode, Places: places}
+		return m
+	}
+	set := func(dataType DataTypeCode, target, left string, leftType SideCode) Modifer {
+		return Modifer{Operand: RuleOperand.SET, DataType: dataType, LeftSide: left, LeftType: leftType, TargetField: target}
+	}
+	sequenced := dslModifer(ModiferDataType.INT, "Price", RuleOperand.ADD, "10", value)
+	sequenced.Sequence = 3
+
+	return map[string]Modifer{
+		"IntSet":          set(ModiferDataType.INT, "Price", "100", value),
+		"IntSetField":     set(ModiferDataType.INT, "Price", "Base", field),
+		"IntAdd":          dslModifer(ModiferDataType.INT, "Price", RuleOperand.ADD, "10", value),
+		"IntSub":          dslModifer(ModiferDataType.INT, "Price", RuleOperand.SUB, "Discount", field),
+		"IntMlt":          dslModifer(ModiferDataType.INT, "Price", RuleOperand.MLT, "-2", value),
+		"IntDiv":          dslModifer(ModiferDataType.INT, "Price", RuleOperand.DIV, "3", value),
+		"Percentage":      dslModifer(ModiferDataType.INT, "Price", RuleOperand.ADD, `{"flat":500,"percentage":10}`, complex),
+		"PercentageSub":   dslModifer(ModiferDataType.INT, "Price", RuleOperand.SUB, `{"flat":50,"percentage":2.5}`, complex),
+		"PercentageBare":  dslModifer(ModiferDataType.INT, "Price", RuleOperand.ADD, `{"percentage":10}`, complex),
+		"ComplexJSON":     dslModifer(ModiferDataType.INT, "Price", RuleOperand.ADD, `{"percentage":10,"flat":500}`, complex),
+		"ComplexRaw":      dslModifer(ModiferDataType.INT, "Price", RuleOperand.ADD, `[1,2]`, complex),
+		"ComplexField":    dslModifer(ModiferDataType.INT, "Price", RuleOperand.ADD, "Fee", complex),
+		"Sequence":        sequenced,
+		"Truncate":        rounded(dslModifer(ModiferDataType.INT, "Price", RuleOperand.DIV, "3", value), RoundingMode.TRUNCATE, 0),
+		"HalfUp":          rounded(dslModifer(ModiferDataType.INT, "Price", RuleOperand.DIV, "3", value), RoundingMode.HALF_UP, -2),
+		"HalfEven":        rounded(dslModifer(ModiferDataType.INT, "Price", RuleOperand.DIV, "3", value), RoundingMode.HALF_EVEN, 1),
+		"Floor":           rounded(dslModifer(ModiferDataType.INT, "Price", RuleOperand.DIV, "3", value), RoundingMode.FLOOR, -1),
+		"Ceil":            rounded(dslModifer(ModiferDataType.INT, "Price", RuleOperand.DIV, "3", value), RoundingMode.CEIL, -3),
+		"UnknownRounding": rounded(dslModifer(ModiferDataType.INT, "Price", RuleOperand.DIV, "3", value), 9, 0),
+		"DecimalAdd":      dslModifer(ModiferDataType.DECIMAL, "Rate", RuleOperand.ADD, "2.5", value),
+		"DecimalWhole":    dslModifer(ModiferDataType.DECIMAL, "Rate", RuleOperand.MLT, "2", value),
+		"DecimalRounded":  rounded(dslModifer(ModiferDataType.DECIMAL, "Rate", RuleOperand.DIV, "3", value), RoundingMode.HALF_EVEN, 2),
+		"DecimalPercent":  dslModifer(ModiferDataType.DECIMAL, "Rate", RuleOperand.SUB, `{"flat":5,"percentage":10}`, complex),
+		"StringSet":       set(ModiferDataType.STRING, "Tag", "vip", value),
+		"StringSetNumber": set(ModiferDataType.STRING, "Tag", "42", value),
+		"StringSetField":  set(ModiferDataType.STRING, "Tag", "Guest.Tier", field),
+		"Select": {Operand: RuleOperand.SEL, DataType: ModiferDataType.STRING, LeftSide: "Guest.Tier", LeftType: ModiferSideType.FIELD,
+			RightSide: `{"select":[{"key":"gold","value":"vip"}]}`, RightType: complex, TargetField: "Tag"},
+		"SelectField": {Operand: RuleOperand.SEL, DataType: ModiferDataType.INT, LeftSide: "Guest.Tier", LeftType: ModiferSideType.FIELD,
+			RightSide: "Fees", RightType: field, TargetField: "Price"},
+		"Sum": {Operand: RuleOperand.SUM, DataType: ModiferDataType.INT, LeftSide: "Extras", LeftType: ModiferSideType.FIELD,
+			RightSide: `{"select":[{"key":"breakfast","value":"15"}]}`, RightType: complex, TargetField: "Price"},
+		"Jump":         {DataType: ModiferDataType.JMP, LeftSide: "fees", RightSide: "0"},
+		"JumpStart":    {DataType: ModiferDataType.JMP, LeftSide: "fees", RightSide: "10"},
+		"Call":         {DataType: ModiferDataType.JRT, LeftSide: "pricing/weekend", RightSide: "0"},
+		"CallStart":    {DataType: ModiferDataType.JRT, LeftSide: "fees", RightSide: "-5"},
+		"CallRawStart": {DataType: ModiferDataType.JRT, LeftSide: "fees", RightSide: "first"},
+		"JumpSequence": {Sequence: 2, DataType: ModiferDataType.JMP, LeftSide: "fees", RightSide: "0"},
+	}
+}
+
+// roundTrip format v, parse it back and format that again
+func roundTrip[T any](t *testing.T, v T, format func(T) (string, error), parse func(string) (*T, error)) (string, T) {
+	t.Helper()
+	text, err := format(v)
+	if err != nil {
+		t.Fatalf("format %+v: %v", v, err)
+	}
+	parsed, err := parse(text)
+	if err != nil {
+		t.Fatalf("parse %q: %v", text, err)
+	}
+	again, err := format(*parsed)
+	if err != nil {
+		t.Fatalf("format %+v: %v", *parsed, err)
+	}
+	if again != text {
+		t.Fatalf("formatted differently once parsed\nfirst: %s\n then: %s", text, again)
+	}
+	return text, *parsed
+}
+
+func TestDSLConditionRoundTrip(t *testing.T) {
+	for name, c := range dslConditions() {
+		t.Run(name, func(t *testing.T) {
+			text, parsed := roundTrip(t, c, FormatCondition, ParseCondition)
+			if !reflect.DeepEqual(parsed, c) {
+				t.Fatalf("%s read back as %+v, want %+v", text, parsed, c)
+			}
+		})
+	}
+}
+
+func TestDSLModiferRoundTrip(t *testing.T) {
+	for name, m := range dslModifers() {
+		t.Run(name, func(t *testing.T) {
+			text, parsed := roundTrip(t, m, FormatModifer, ParseModifer)
+			if !reflect.DeepEqual(parsed, m) {
+				t.Fatalf("%s read back as %+v, want %+v", text, parsed, m)
+			}
+		})
+	}
+}
+
+func TestDSLGroupRoundTrip(t *testing.T) {
+	adults := dslConditions()["IntMoreEqual"]
+	web := dslConditions()["String"]
+	weekend := dslConditions()["WeekdayIn"]
+	for name, g := range map[string]ConditionGroup{
+		"All":    {Mode: ConditionGroupMode.ALL, Conditions: []Condition{adults, web}},
+		"Any":    {Mode: ConditionGroupMode.ANY, Conditions: []Condition{adults}},
+		"None":   {Mode: ConditionGroupMode.NONE, Conditions: []Condition{weekend}},
+		"Empty":  {Mode: ConditionGroupMode.ALL},
+		"Groups": {Mode: ConditionGroupMode.ANY, Groups: []ConditionGroup{{Mode: ConditionGroupMode.ALL, Conditions: []Condition{web}}}},
+		"Nested": {Mode: ConditionGroupMode.ALL, Conditions: []Condition{adults}, Groups: []ConditionGroup{
+			{Mode: ConditionGroupMode.ANY, Conditions: []Condition{web, weekend}},
+			{Mode: ConditionGroupMode.NONE, Groups: []ConditionGroup{{Mode: ConditionGroupMode.ALL, Conditions: []Condition{adults}}}},
+		}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := Rule{ConditionChain: []Condition{}, ConditionTree: &g, ModiferChain: []Modifer{}}
+			text, parsed := roundTrip(t, r, FormatRule, ParseRule)
+			if !reflect.DeepEqual(parsed, r) {
+				t.Fatalf("%s read back as %+v, want %+v", text, *parsed.ConditionTree, g)
+			}
+		})
+	}
+}
+
+func TestDSLSettingsRoundTrip(t *testing.T) {
+	from := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2026, 9, 1, 12, 30, 0, 500, time.FixedZone("", 7*3600))
+	conditions, modifers := dslConditions(), dslModifers()
+	rs := []RuleSetting{
+		{
+			ID: "weekend", RuleID: "pricing", Sequence: 10, RuleType: 2, Enable: true, BreakOnFail: true,
+			ValidFrom: &from, ValidTo: &until,
+			Rule: Rule{
+				ConditionChain: []Condition{conditions["WeekdayIn"], conditions["IntMoreEqual"]},
+				ConditionTree:  &ConditionGroup{Mode: ConditionGroupMode.ANY, Conditions: []Condition{conditions["String"], conditions["StringIn"]}},
+				ModiferChain:   []Modifer{modifers["Percentage"], modifers["HalfUp"], modifers["Jump"]},
+			},
+		},
+		{
+			ID: "disabled", RuleID: "pricing", Sequence: -1,
+			Rule: Rule{ConditionChain: []Condition{conditions["Always"]}, ModiferChain: []Modifer{modifers["CallStart"]}},
+		},
+		{Enable: true, Rule: Rule{ConditionChain: []Condition{}, ModiferChain: []Modifer{}}},
+	}
+
+	text, err := FormatSettings(rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseSettings(text)
+	if err != nil {
+		t.Fatalf("parse %s: %v", text, err)
+	}
+	again, err := FormatSettings(parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != text {
+		t.Fatalf("formatted differently once parsed\nfirst:\n%s\nthen:\n%s", text, again)
+	}
+	want, _ := json.Marshal(rs)
+	got, _ := json.Marshal(parsed)
+	if string(got) != string(want) {
+		t.Fatalf("read back as\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestDSLModiferSequence(t *testing.T) {
+	rs, err := ParseSettings("setting \"a\" rule \"r\"\nthen\n  Price = Price + 10\n  Adults = Adults + 1\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Validate(rs, nil); err != nil {
+		t.Fatalf("Validate = %v, want modifers numbered by position", err)
+	}
+	if got := rs[0].Rule.ModiferChain; got[0].Sequence != 0 || got[1].Sequence != 1 {
+		t.Fatalf("sequences = %d, %d, want 0, 1", got[0].Sequence, got[1].Sequence)
+	}
+
+	r := Rule{ModiferChain: []Modifer{
+		intModifer(RuleOperand.ADD, "Price", "10"),
+		intModifer(RuleOperand.ADD, "Price", "20"),
+		intModifer(RuleOperand.ADD, "Price", "30"),
+	}}
+	r.ModiferChain[1].Sequence, r.ModiferChain[2].Sequence = 1, 5
+	text, err := FormatRule(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(text, "#0") || strings.Contains(text, "#1") || !strings.Contains(text, "#5 Price") {
+		t.Fatalf("FormatRule = %s, want #5 alone written", text)
+	}
+	back, err := ParseRule(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(back.ModiferChain, r.ModiferChain) {
+		t.Fatalf("read back as %+v, want %+v", back.ModiferChain, r.ModiferChain)
+	}
+}
+
+func TestDSLPercentage(t *testing.T) {
+	for _, tc := range []struct {
+		src   string
+		price int64
+		want  int64
+	}{
+		{"Price = Price + 10% max 500", 1000, 1100},
+		{"Price = Price + 10% max 50", 1000, 1050},
+		{"Price = Price - 20% max 1000 round half_up -1", 1234, 984},
+		{"Price = Price - 20% max 100", 1234, 1134},
+		{"Price = Price + 10%", 1000, 1100},
+		{"Price = Price + 10%", 100000, 110000},
+		{"Price = Price - 20% round half_up -1", 1234, 984},
+	} {
+		t.Run(tc.src, func(t *testing.T) {
+			m, err := ParseModifer(tc.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b := &booking{Price: tc.price}
+			if _, err := NewEngine(stubSupply{}).ApplyModifer(b, *m); err != nil {
+				t.Fatal(err)
+			}
+			if b.Price != tc.want {
+				t.Fatalf("Price = %d, want %d", b.Price, tc.want)
+			}
+		})
+	}
+}
+
+func TestDSLWeekendExample(t *testing.T) {
+	type stay struct {
+		DayOfWeek time.Time
+		Adults    int64
+		Price     int64
+	}
+	r, err := ParseRule("when DayOfWeek in [Sat,Sun] and Adults >= 2 then Price = Price + 10%")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs := RuleSetting{ID: "weekend", RuleID: "pricing", Enable: true, Rule: *r}
+	for _, tc := range []struct {
+		day    time.Time
+		adults int64
+		want   int64
+	}{
+		{time.Date(2026, 6, 6, 0, 0, 0, 0, time.UTC), 2, 1100},
+		{time.Date(2026, 6, 7, 0, 0, 0, 0, time.UTC), 3, 1100},
+		{time.Date(2026, 6, 7, 0, 0, 0, 0, time.UTC), 1, 1000},
+		{time.Date(2026, 6, 8, 0, 0, 0, 0, time.UTC), 2, 1000},
+	} {
+		b := &stay{DayOfWeek: tc.day, Adults: tc.adults, Price: 1000}
+		if _, _, err := NewEngine(stubSupply{}).ApplySetting(b, rs); err != nil {
+			t.Fatal(err)
+		}
+		if b.Price != tc.want {
+			t.Errorf("%s with %d adults: Price = %d, want %d", tc.day.Weekday(), tc.adults, b.Price, tc.want)
+		}
+	}
+}
+
+func TestDSLSyntaxError(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		parse  func(string) error
+		src    string
+		line   int
+		column int
+		msg    string
+	}{
+		{"MissingThen", parseSettings, "setting \"a\"\nwhen Adults >= 2\n  Price = 1", 3, 3, "expected then"},
+		{"MissingRight", parseSettings, "setting \"a\"\nwhen Adults >\nthen", 3, 1, "found"},
+		{"UnknownCompare", parseCondition, "Adults ~ 2", 1, 8, "unexpected"},
+		{"ListWithoutIn", parseCondition, "Adults = [1, 2]", 1, 10, "expected a value"},
+		{"NotWithoutIn", parseCondition, "Adults not 2", 1, 12, "expected in"},
+		{"EndOfInput", parseCondition, "Adults >=", 1, 10, "end of input"},
+		{"AfterMultiByte", parseCondition, `Name = "ü" Adults`, 1, 12, "end of input"},
+		{"UnterminatedString", parseCondition, "Name = \"web", 1, 8, ""},
+		{"BadTime", parseSettings, "setting \"a\" from \"tomorrow\"\nthen", 1, 18, "RFC 3339"},
+		{"GroupOrder", parseRule, "match all(any(Adults > 1), Adults > 2)\nthen", 1, 28, "come before its groups"},
+		{"BadGroupMode", parseRule, "match most(Adults > 1)\nthen", 1, 7, "all, any or none"},
+		{"MissingAssign", parseModifer, "Price 10", 1, 7, `"=" or "+="`},
+		{"PercentMaxValue", parseModifer, "Price = Price + 10% max", 1, 24, "a number"},
+		{"SecondLineColumn", parseModifer, "Price =\n\t  Price +", 2, 11, "end of input"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.parse(tc.src)
+			if !errors.Is(err, RuleSettingError.SYNTAX_INVALID) {
+				t.Fatalf("parse = %v, want SYNTAX_INVALID", err)
+			}
+			var se *SyntaxError
+			if !errors.As(err, &se) {
+				t.Fatalf("parse = %T, want a *SyntaxError", err)
+			}
+			if se.Line != tc.line || se.Column != tc.column || !strings.Contains(se.Msg, tc.msg) {
+				t.Fatalf("parse = %v, want %d:%d: ...%s...", se, tc.line, tc.column, tc.msg)
+			}
+			if want := fmt.Sprintf("%d:%d: %s", tc.line, tc.column, se.Msg); se.Error() != want {
+				t.Fatalf("Error() = %q, want %q", se.Error(), want)
+			}
+		})
+	}
+}
+
+func parseSettings(src string) error {
+	_, err := ParseSettings(src)
+	return err
+}
+
+func parseRule(src string) error {
+	_, err := ParseRule(src)
+	return err
+}
+
+func parseCondition(src string) error {
+	_, err := ParseCondition(src)
+	return err
+}
+
+func parseModifer(src string) error {
+	_, err := ParseModifer(src)
+	return err
+}
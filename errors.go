@@ -0,0 +1,101 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// JumpPoint a rule set entered by id and start sequence
+type JumpPoint struct {
+	RuleID string `json:"rule_id"`
+	Start  int    `json:"start"`
+}
+
+func (jp JumpPoint) String() string {
+	return fmt.Sprintf("%s@%d", jp.RuleID, jp.Start)
+}
+
+// JumpError a JMP or JRT chain that loops or runs too deep, Err is JUMP_CYCLE or JUMP_TOO_DEEP
+type JumpError struct {
+	Chain []JumpPoint
+	Err   error
+}
+
+func (e *JumpError) Error() string {
+	hops := make([]string, len(e.Chain))
+	for i, jp := range e.Chain {
+		hops[i] = jp.String()
+	}
+	return fmt.Sprintf("%s: %s", e.Err, strings.Join(hops, " -> "))
+}
+
+func (e *JumpError) Unwrap() error {
+	return e.Err
+}
+
+// RuleError an error raised while evaluating one RuleSetting, Condition and Modifer are -1 when it is not tied to one
+type RuleError struct {
+	SettingID string
+	Condition int
+	Modifer   int
+	Field     string
+	Err       error
+}
+
+func (e *RuleError) Error() string {
+	msg := "setting " + e.SettingID
+	if e.Condition >= 0 {
+		msg += fmt.Sprintf(" condition %d", e.Condition)
+	}
+	if e.Modifer >= 0 {
+		msg += fmt.Sprintf(" modifer %d", e.Modifer)
+	}
+	if e.Field != "" {
+		msg += " field " + e.Field
+	}
+	return fmt.Sprintf("%s: %s", msg, e.Err)
+}
+
+func (e *RuleError) Unwrap() error {
+	return e.Err
+}
+
+// ruleError wrap err with where it happened, errors already located by a jumped to setting are kept as they are
+func ruleError(rs RuleSetting, condition, modifer int, field string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var located *RuleError
+	if errors.As(err, &located) {
+		return err
+	}
+	return &RuleError{
+		SettingID: rs.ID,
+		Condition: condition,
+		Modifer:   modifer,
+		Field:     field,
+		Err:       err,
+	}
+}
+
+// recoverError turn a panic into an EVALUATION_PANIC error held in err
+func recoverError(err *error) {
+	if r := recover(); r != nil {
+		*err = fmt.Errorf("%w: %v", RuleSettingError.EVALUATION_PANIC, r)
+	}
+}
+
+// conditionField the field a condition reads, left side first
+func conditionField(c Condition) string {
+	if c.LeftType == ConditionSideType.FIELD {
+		return c.LeftSide
+	}
+	if c.RightType == ConditionSideType.FIELD {
+		return c.RightSide
+	}
+	return ""
+}
@@ -0,0 +1,81 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRuleErrorLocation(t *testing.T) {
+	rs := setting("r", 1, []Condition{intCondition("Adults", RuleConditionCompare.MORE, "1")},
+		intModifer(RuleOperand.ADD, "Price", "5"),
+		Modifer{Sequence: 1, Operand: RuleOperand.SET, DataType: ModiferDataType.STRING, LeftSide: "x", LeftType: ModiferSideType.VALUE, TargetField: "Price"},
+	)
+	b := &booking{Adults: 2, Price: 100}
+	_, _, err := NewEngine(stubSupply{}).ApplySetting(b, rs)
+	var rerr *RuleError
+	if !errors.As(err, &rerr) {
+		t.Fatalf("ApplySetting = %v, want a RuleError", err)
+	}
+	if rerr.SettingID != "r-1" || rerr.Condition != -1 || rerr.Modifer != 1 || rerr.Field != "Price" {
+		t.Errorf("located at %+v", rerr)
+	}
+	if !errors.Is(err, RuleSettingError.FIELD_TYPE_MISMATCH) {
+		t.Errorf("err = %v, want FIELD_TYPE_MISMATCH", err)
+	}
+	if want := "setting r-1 modifer 1 field Price: Field type mismatch: string target field of kind int64"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+	if b.Price != 105 {
+		t.Errorf("Price = %d, the modifer before the failing one applies", b.Price)
+	}
+
+	rs.Rule.ConditionChain = append(rs.Rule.ConditionChain, intCondition("Nope", RuleConditionCompare.EQUAL, "1"))
+	_, _, err = NewEngine(stubSupply{}).ApplySetting(b, rs)
+	if !errors.As(err, &rerr) || rerr.Condition != 1 || rerr.Modifer != -1 || rerr.Field != "Nope" {
+		t.Errorf("ApplySetting = %v, want a RuleError at condition 1", err)
+	}
+	if want := "setting r-1 condition 1 field Nope: Field not existed: Nope"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestRuleErrorJumped(t *testing.T) {
+	// the error keeps the setting it happened in, not the one that jumped
+	sp := stubSupply{"other": {setting("other", 0, nil, intModifer(RuleOperand.DIV, "Price", "0"))}}
+	rs := []RuleSetting{setting("r", 1, nil, jumpModifer(ModiferDataType.JRT, "other", 0))}
+	_, err := NewEngine(sp).ApplySettings(&booking{}, rs)
+	var rerr *RuleError
+	if !errors.As(err, &rerr) || rerr.SettingID != "other-0" || rerr.Modifer != 0 || !errors.Is(err, RuleSettingError.DIV_BY_ZERO) {
+		t.Errorf("ApplySettings = %v, want DIV_BY_ZERO in other-0", err)
+	}
+
+	sp = stubSupply{}
+	if _, err := NewEngine(sp).ApplySettings(&booking{}, rs); !errors.As(err, &rerr) || rerr.SettingID != "r-1" || !errors.Is(err, RuleSettingError.UNABLE_TO_FETCH) {
+		t.Errorf("ApplySettings = %v, want UNABLE_TO_FETCH in r-1", err)
+	}
+}
+
+func TestRecoverPanic(t *testing.T) {
+	re := NewEngine(stubSupply{})
+	rs := setting("r", 1, []Condition{intCondition("Adults", RuleConditionCompare.MORE, "1")})
+	if _, _, err := re.ApplySetting(nil, rs); !errors.Is(err, RuleSettingError.EVALUATION_PANIC) {
+		t.Errorf("ApplySetting(nil) = %v, want EVALUATION_PANIC", err)
+	}
+	if _, err := re.ApplySettings(nil, []RuleSetting{rs}); !errors.Is(err, RuleSettingError.EVALUATION_PANIC) {
+		t.Errorf("ApplySettings(nil) = %v, want EVALUATION_PANIC", err)
+	}
+
+}
+
+func TestJumpErrorString(t *testing.T) {
+	err := &JumpError{Chain: []JumpPoint{{"a", 0}, {"b", 5}, {"a", 0}}, Err: RuleSettingError.JUMP_CYCLE}
+	if want := "Jump cycle detected: a@0 -> b@5 -> a@0"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+	if !errors.Is(err, RuleSettingError.JUMP_CYCLE) {
+		t.Error("JumpError does not unwrap")
+	}
+}
@@ -0,0 +1,103 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule
+
+import (
+	"context"
+	"time"
+)
+
+// evaluation state carried through one engine call
+type evaluation struct {
+	ctx      context.Context
+	chain    []JumpPoint      // rule sets entered so far, root first
+	depth    int              // JMP and JRT hops taken
+	settings *[]*SettingTrace // trace sink, nil when not explaining
+	now      time.Time        // read once from the engine's clock, settings are live or not for the whole evaluation
+}
+
+func newEvaluation(ctx context.Context) *evaluation {
+	return &evaluation{ctx: ctx}
+}
+
+// at evaluate the validity of settings at now
+func (ev *evaluation) at(now time.Time) *evaluation {
+	ev.now = now
+	return ev
+}
+
+// enter mark the rule sets the evaluation starts from, one JumpPoint per rule set in rs starting at its
+// lowest sequence, as a fetch of rs would have started
+func (ev *evaluation) enter(rs ...RuleSetting) *evaluation {
+	ev.chain = nil
+	at := make(map[string]int)
+	for _, setting := range rs {
+		if setting.RuleID == "" {
+			continue
+		}
+		i, ok := at[setting.RuleID]
+		if !ok {
+			at[setting.RuleID] = len(ev.chain)
+			ev.chain = append(ev.chain, JumpPoint{RuleID: setting.RuleID, Start: int(setting.Sequence)})
+			continue
+		}
+		if int(setting.Sequence) < ev.chain[i].Start {
+			ev.chain[i].Start = int(setting.Sequence)
+		}
+	}
+	return ev
+}
+
+// explain record the evaluation into tr
+func (ev *evaluation) explain(tr *Trace) *evaluation {
+	ev.settings = &tr.Settings
+	return ev
+}
+
+func (ev *evaluation) visit(rs RuleSetting) *SettingTrace {
+	if ev.settings == nil {
+		return nil
+	}
+	st := &SettingTrace{
+		ID:       rs.ID,
+		RuleID:   rs.RuleID,
+		Sequence: rs.Sequence,
+	}
+	*ev.settings = append(*ev.settings, st)
+	return st
+}
+
+// jump the evaluation for the rule set a JMP or JRT modifer moves into
+func (ev *evaluation) jump(mt *ModiferTrace, id string, start int, maxDepth int) (*evaluation, error) {
+	jp := JumpPoint{RuleID: id, Start: start}
+	chain := make([]JumpPoint, len(ev.chain), len(ev.chain)+1)
+	copy(chain, ev.chain)
+	chain = append(chain, jp)
+	for _, visited := range ev.chain {
+		// starting at or before a rule set already entered runs all of it again
+		if visited.RuleID == id && start <= visited.Start {
+			return nil, &JumpError{Chain: chain, Err: RuleSettingError.JUMP_CYCLE}
+		}
+	}
+	if maxDepth > 0 && ev.depth >= maxDepth {
+		return nil, &JumpError{Chain: chain, Err: RuleSettingError.JUMP_TOO_DEEP}
+	}
+
+	next := &evaluation{
+		ctx:      ev.ctx,
+		chain:    chain,
+		depth:    ev.depth + 1,
+		settings: ev.settings,
+		now:      ev.now,
+	}
+	if mt != nil {
+		mt.Jump = &JumpTrace{
+			RuleID: id,
+			Start:  start,
+			Return: mt.Modifer.DataType == ModiferDataType.JRT,
+		}
+		next.settings = &mt.Jump.Settings
+	}
+	return next, nil
+}
@@ -0,0 +1,131 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestEnter(t *testing.T) {
+	ev := newEvaluation(context.Background()).enter(
+		setting("a", 3, nil),
+		setting("b", 7, nil),
+		setting("a", 2, nil),
+		RuleSetting{Sequence: 1},
+		setting("b", 5, nil),
+	)
+	want := []JumpPoint{{RuleID: "a", Start: 2}, {RuleID: "b", Start: 5}}
+	if !reflect.DeepEqual(ev.chain, want) {
+		t.Errorf("entered %v, want %v", ev.chain, want)
+	}
+}
+
+func TestJumpCycle(t *testing.T) {
+	cases := []struct {
+		name  string
+		sp    stubSupply
+		rs    []RuleSetting
+		chain []JumpPoint
+	}{
+		{
+			name: "back to the root",
+			sp: stubSupply{
+				"a": {setting("a", 0, nil, jumpModifer(ModiferDataType.JRT, "b", 0))},
+				"b": {setting("b", 0, nil, jumpModifer(ModiferDataType.JMP, "a", 0))},
+			},
+			chain: []JumpPoint{{"a", 0}, {"b", 0}, {"a", 0}},
+		},
+		{
+			name: "before the root's first sequence",
+			sp: stubSupply{
+				"a": {setting("a", 10, nil, jumpModifer(ModiferDataType.JMP, "b", 0))},
+				"b": {setting("b", 0, nil, jumpModifer(ModiferDataType.JMP, "a", 0))},
+			},
+			chain: []JumpPoint{{"a", 10}, {"b", 0}, {"a", 0}},
+		},
+		{
+			name: "into itself",
+			sp: stubSupply{
+				"a": {setting("a", 0, nil, jumpModifer(ModiferDataType.JMP, "a", 0))},
+			},
+			chain: []JumpPoint{{"a", 0}, {"a", 0}},
+		},
+		{
+			name: "a set entered beside the root",
+			sp: stubSupply{
+				"b": {setting("b", 0, nil, jumpModifer(ModiferDataType.JMP, "c", 0))},
+				"c": {setting("c", 0, nil, jumpModifer(ModiferDataType.JMP, "b", 0))},
+			},
+			rs: []RuleSetting{
+				setting("a", 0, []Condition{intCondition("Adults", RuleConditionCompare.EQUAL, "9")}),
+				setting("b", 1, nil, jumpModifer(ModiferDataType.JMP, "c", 0)),
+			},
+			chain: []JumpPoint{{"a", 0}, {"b", 1}, {"c", 0}, {"b", 0}},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rs := c.rs
+			if rs == nil {
+				rs = c.sp[c.chain[0].RuleID]
+			}
+			_, err := NewEngine(c.sp).ApplySettings(&booking{}, rs)
+			var je *JumpError
+			if !errors.Is(err, RuleSettingError.JUMP_CYCLE) || !errors.As(err, &je) {
+				t.Fatalf("ApplySettings = %v, want a JumpError of JUMP_CYCLE", err)
+			}
+			if !reflect.DeepEqual(je.Chain, c.chain) {
+				t.Errorf("chain %v, want %v", je.Chain, c.chain)
+			}
+		})
+	}
+}
+
+func TestJumpForward(t *testing.T) {
+	// a jump further into a rule set already entered is not a cycle
+	sp := stubSupply{"a": {
+		setting("a", 0, nil, jumpModifer(ModiferDataType.JMP, "a", 2)),
+		setting("a", 1, nil, intModifer(RuleOperand.ADD, "Price", "100")),
+		setting("a", 2, nil, intModifer(RuleOperand.ADD, "Price", "1")),
+	}}
+	b := &booking{}
+	if _, err := NewEngine(sp).ApplySettings(b, sp["a"]); err != nil {
+		t.Fatal(err)
+	}
+	if b.Price != 1 {
+		t.Errorf("Price = %d, want 1 from setting 2 alone", b.Price)
+	}
+}
+
+func TestJumpDepth(t *testing.T) {
+	sp := stubSupply{}
+	for i := 0; i < 10; i++ {
+		id := fmt.Sprint("r", i)
+		sp[id] = []RuleSetting{setting(id, 0, nil, jumpModifer(ModiferDataType.JRT, fmt.Sprint("r", i+1), 0))}
+	}
+	sp["r10"] = []RuleSetting{setting("r10", 0, nil, intModifer(RuleOperand.ADD, "Price", "1"))}
+
+	_, err := NewEngine(sp, WithMaxJumpDepth(5)).ApplySettings(&booking{}, sp["r0"])
+	var je *JumpError
+	if !errors.Is(err, RuleSettingError.JUMP_TOO_DEEP) || !errors.As(err, &je) {
+		t.Fatalf("ApplySettings = %v, want a JumpError of JUMP_TOO_DEEP", err)
+	}
+	if len(je.Chain) != 7 || je.Chain[6].RuleID != "r6" {
+		t.Errorf("chain %v, want r0 to r6", je.Chain)
+	}
+
+	for _, depth := range []int{10, 0, -1} {
+		b := &booking{}
+		if _, err := NewEngine(sp, WithMaxJumpDepth(depth)).ApplySettings(b, sp["r0"]); err != nil || b.Price != 1 {
+			t.Errorf("WithMaxJumpDepth(%d): Price %d, %v", depth, b.Price, err)
+		}
+	}
+	if _, err := NewEngine(sp).ApplySettings(&booking{}, sp["r0"]); err != nil {
+		t.Errorf("DefaultMaxJumpDepth %d: %v", DefaultMaxJumpDepth, err)
+	}
+}
@@ -0,0 +1,365 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pathStep one hop of a field path: Name, [index] or ["key"]
+type pathStep struct {
+	name  string
+	index string
+	key   bool // index is a quoted map key
+}
+
+func (ps pathStep) String() string {
+	if ps.name != "" {
+		return ps.name
+	}
+	if ps.key {
+		return strconv.Quote(ps.index)
+	}
+	return "[" + ps.index + "]"
+}
+
+// parsePath split paths like Guest.Loyalty.Tier, Rooms[0].Adults or Attributes["channel"]
+func parsePath(path string) ([]pathStep, error) {
+	var steps []pathStep
+	rest := path
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			if len(steps) == 0 {
+				return nil, fmt.Errorf("%w: %s", RuleSettingError.FIELD_PATH_INVALID, path)
+			}
+			rest = rest[1:]
+			fallthrough
+		default:
+			if len(steps) > 0 && path[len(path)-len(rest)-1] != '.' {
+				// a name straight after ]
+				return nil, fmt.Errorf("%w: %s", RuleSettingError.FIELD_PATH_INVALID, path)
+			}
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("%w: %s", RuleSettingError.FIELD_PATH_INVALID, path)
+			}
+			steps = append(steps, pathStep{name: rest[:end]})
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("%w: %s", RuleSettingError.FIELD_PATH_INVALID, path)
+			}
+			inner := rest[1:end]
+			if strings.HasPrefix(inner, `"`) {
+				// quoted keys may hold ']' so find the closing quote first
+				key, tail, err := unquotePrefix(rest[1:])
+				if err != nil || !strings.HasPrefix(tail, "]") {
+					return nil, fmt.Errorf("%w: %s", RuleSettingError.FIELD_PATH_INVALID, path)
+				}
+				steps = append(steps, pathStep{index: key, key: true})
+				rest = tail[1:]
+				continue
+			}
+			if inner == "" {
+				return nil, fmt.Errorf("%w: %s", RuleSettingError.FIELD_PATH_INVALID, path)
+			}
+			steps = append(steps, pathStep{index: inner})
+			rest = rest[end+1:]
+		}
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("%w: %s", RuleSettingError.FIELD_PATH_INVALID, path)
+	}
+	return steps, nil
+}
+
+func unquotePrefix(s string) (string, string, error) {
+	prefix, err := strconv.QuotedPrefix(s)
+	if err != nil {
+		return "", "", err
+	}
+	key, err := strconv.Unquote(prefix)
+	return key, s[len(prefix):], err
+}
+
+// deref follow pointers and interfaces, at names the path walked so far for errors
+func deref(v reflect.Value, at string) (reflect.Value, error) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return v, fmt.Errorf("%w: %s", RuleSettingError.FIELD_NIL, at)
+		}
+		v = v.Elem()
+	}
+	return v, nil
+}
+
+// walk take one step from v, at names the path walked so far
+func walk(v reflect.Value, ps pathStep, at string) (reflect.Value, error) {
+	switch {
+	case ps.name != "" && v.Kind() != reflect.Map:
+		if v.Kind() != reflect.Struct {
+			return v, fmt.Errorf("%w: %s", RuleSettingError.MODIFER_FEILD_NOT_EXISTED, at)
+		}
+		f := v.FieldByName(ps.name)
+		if !f.IsValid() {
+			return f, fmt.Errorf("%w: %s", RuleSettingError.MODIFER_FEILD_NOT_EXISTED, at)
+		}
+		return f, nil
+	case v.Kind() == reflect.Map:
+		k, err := mapKey(v.Type().Key(), ps)
+		if err != nil {
+			return v, fmt.Errorf("%w: %s", err, at)
+		}
+		e := v.MapIndex(k)
+		if !e.IsValid() {
+			return e, fmt.Errorf("%w: %s", RuleSettingError.MODIFER_FEILD_NOT_EXISTED, at)
+		}
+		return e, nil
+	case v.Kind() == reflect.Slice || v.Kind() == reflect.Array:
+		i, err := strconv.Atoi(ps.index)
+		if err != nil || ps.key {
+			return v, fmt.Errorf("%w: %s", RuleSettingError.FIELD_PATH_INVALID, at)
+		}
+		if i < 0 || i >= v.Len() {
+			return v, fmt.Errorf("%w: %s", RuleSettingError.MODIFER_FEILD_NOT_EXISTED, at)
+		}
+		return v.Index(i), nil
+	}
+	return v, fmt.Errorf("%w: %s", RuleSettingError.MODIFER_FEILD_NOT_EXISTED, at)
+}
+
+// mapKey the key ps names in a map keyed by t, plain names are keys too so documents read like structs
+func mapKey(t reflect.Type, ps pathStep) (reflect.Value, error) {
+	key := ps.index
+	if ps.name != "" {
+		key = ps.name
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return reflect.ValueOf(key).Convert(t), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(key, 10, 64)
+		if err != nil {
+			return reflect.Value{}, RuleSettingError.FIELD_PATH_INVALID
+		}
+		return reflect.ValueOf(i).Convert(t), nil
+	}
+	return reflect.Value{}, RuleSettingError.UNSUPPORTED_OPERATION
+}
+
+// fieldValue resolve path against rqr for reading, pointers on the way are followed
+func fieldValue(rqr interface{}, path string) (reflect.Value, error) {
+	steps, err := parsePath(path)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	v := reflect.ValueOf(rqr)
+	at := ""
+	for _, ps := range steps {
+		if v, err = deref(v, at); err != nil {
+			return v, err
+		}
+		at = joinPath(at, ps)
+		if v, err = walk(v, ps, at); err != nil {
+			return v, err
+		}
+	}
+	return deref(v, at)
+}
+
+// setField resolve path against rqr and hand set a settable value,
+// map elements are copied out and stored back once set returns
+func setField(rqr interface{}, path string, set func(v reflect.Value) error) error {
+	steps, err := parsePath(path)
+	if err != nil {
+		return err
+	}
+	return assign(reflect.ValueOf(rqr), steps, "", set)
+}
+
+func assign(v reflect.Value, steps []pathStep, at string, set func(v reflect.Value) error) error {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			if len(steps) > 0 || !v.CanSet() {
+				return fmt.Errorf("%w: %s", RuleSettingError.FIELD_NIL, at)
+			}
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if len(steps) == 0 {
+		if !v.CanSet() {
+			return fmt.Errorf("%w: %s", RuleSettingError.MODIFER_FEILD_NOT_EXISTED, at)
+		}
+		return set(v)
+	}
+
+	if v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return fmt.Errorf("%w: %s", RuleSettingError.FIELD_NIL, at)
+		}
+		inner := v.Elem()
+		if inner.Kind() == reflect.Map || inner.Kind() == reflect.Ptr {
+			return assign(inner, steps, at, set)
+		}
+		// values held by an interface are not addressable, edit a copy and put it back
+		tmp := reflect.New(inner.Type()).Elem()
+		tmp.Set(inner)
+		if err := assign(tmp, steps, at, set); err != nil {
+			return err
+		}
+		if !v.CanSet() {
+			return fmt.Errorf("%w: %s", RuleSettingError.MODIFER_FEILD_NOT_EXISTED, at)
+		}
+		v.Set(tmp)
+		return nil
+	}
+
+	ps := steps[0]
+	next := joinPath(at, ps)
+	if v.Kind() == reflect.Map {
+		k, err := mapKey(v.Type().Key(), ps)
+		if err != nil {
+			return fmt.Errorf("%w: %s", err, next)
+		}
+		if v.IsNil() {
+			return fmt.Errorf("%w: %s", RuleSettingError.FIELD_NIL, at)
+		}
+		tmp := reflect.New(v.Type().Elem()).Elem()
+		if e := v.MapIndex(k); e.IsValid() {
+			tmp.Set(e)
+		} else if len(steps) > 1 {
+			return fmt.Errorf("%w: %s", RuleSettingError.MODIFER_FEILD_NOT_EXISTED, next)
+		}
+		if err := assign(tmp, steps[1:], next, set); err != nil {
+			return err
+		}
+		v.SetMapIndex(k, tmp)
+		return nil
+	}
+
+	f, err := walk(v, ps, next)
+	if err != nil {
+		return err
+	}
+	return assign(f, steps[1:], next, set)
+}
+
+func joinPath(at string, ps pathStep) string {
+	switch {
+	case at == "":
+		return ps.String()
+	case ps.name != "":
+		return at + "." + ps.name
+	case ps.key:
+		return at + "[" + strconv.Quote(ps.index) + "]"
+	}
+	return at + "[" + ps.index + "]"
+}
+
+func fieldInt(rqr interface{}, path string) (int64, error) {
+	v, err := fieldValue(rqr, path)
+	if err != nil {
+		return 0, err
+	}
+	return intOf(v)
+}
+
+func fieldString(rqr interface{}, path string) (string, error) {
+	v, err := fieldValue(rqr, path)
+	if err != nil {
+		return "", err
+	}
+	return stringOf(v)
+}
+
+func fieldTime(rqr interface{}, path string) (time.Time, error) {
+	v, err := fieldValue(rqr, path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return timeOf(v)
+}
+
+func fieldStrings(rqr interface{}, path string) ([]string, error) {
+	v, err := fieldValue(rqr, path)
+	if err != nil {
+		return nil, err
+	}
+	return stringsOf(v)
+}
+
+func fieldComplex(rqr interface{}, path string) (ModiferComplex, error) {
+	v, err := fieldValue(rqr, path)
+	if err != nil {
+		return ModiferComplex{}, err
+	}
+	return complexOf(v)
+}
+
+func setFieldInt(rqr interface{}, path string, i int64) error {
+	return setField(rqr, path, func(v reflect.Value) error {
+		return assignInt(v, i)
+	})
+}
+
+func setFieldString(rqr interface{}, path string, s string) error {
+	return setField(rqr, path, func(v reflect.Value) error {
+		return assignString(v, s)
+	})
+}
+
+// fieldType resolve path against t the way fieldValue would against a value of t,
+// a nil type means the path runs through an interface and is only known at evaluation
+func fieldType(t reflect.Type, path string) (reflect.Type, error) {
+	steps, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	at := ""
+	for _, ps := range steps {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		at = joinPath(at, ps)
+		switch {
+		case t.Kind() == reflect.Interface:
+			return nil, nil
+		case t.Kind() == reflect.Map:
+			if _, err := mapKey(t.Key(), ps); err != nil {
+				return nil, fmt.Errorf("%w: %s", err, at)
+			}
+			t = t.Elem()
+		case ps.name != "" && t.Kind() == reflect.Struct:
+			f, ok := t.FieldByName(ps.name)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s", RuleSettingError.MODIFER_FEILD_NOT_EXISTED, at)
+			}
+			t = f.Type
+		case ps.name == "" && !ps.key && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array):
+			if _, err := strconv.Atoi(ps.index); err != nil {
+				return nil, fmt.Errorf("%w: %s", RuleSettingError.FIELD_PATH_INVALID, at)
+			}
+			t = t.Elem()
+		default:
+			return nil, fmt.Errorf("%w: %s", RuleSettingError.MODIFER_FEILD_NOT_EXISTED, at)
+		}
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Interface {
+		return nil, nil
+	}
+	return t, nil
+}
@@ -0,0 +1,184 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type loyalty struct {
+	Tier string
+}
+
+type guest struct {
+	Loyalty *loyalty
+	Points  int64
+}
+
+type roomRequest struct {
+	Adults int64
+}
+
+type reservation struct {
+	Guest      guest
+	Booker     *guest
+	Rooms      []roomRequest
+	Nights     [2]int64
+	Attributes map[string]string
+	Counts     map[int]int64
+	ByRoom     map[string]roomRequest
+	Extra      interface{}
+}
+
+func sampleReservation() *reservation {
+	return &reservation{
+		Guest:      guest{Loyalty: &loyalty{Tier: "gold"}, Points: 10},
+		Rooms:      []roomRequest{{Adults: 2}, {Adults: 1}},
+		Nights:     [2]int64{3, 4},
+		Attributes: map[string]string{"channel": "web", "a.b[c]": "odd"},
+		Counts:     map[int]int64{7: 1},
+		ByRoom:     map[string]roomRequest{"101": {Adults: 3}},
+		Extra:      roomRequest{Adults: 9},
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	cases := []struct {
+		path  string
+		steps []pathStep
+	}{
+		{"Price", []pathStep{{name: "Price"}}},
+		{"Guest.Loyalty.Tier", []pathStep{{name: "Guest"}, {name: "Loyalty"}, {name: "Tier"}}},
+		{"Rooms[0].Adults", []pathStep{{name: "Rooms"}, {index: "0"}, {name: "Adults"}}},
+		{`Attributes["channel"]`, []pathStep{{name: "Attributes"}, {index: "channel", key: true}}},
+		{`Attributes["a.b[c]"]`, []pathStep{{name: "Attributes"}, {index: "a.b[c]", key: true}}},
+		{`M["k"][1]`, []pathStep{{name: "M"}, {index: "k", key: true}, {index: "1"}}},
+	}
+	for _, c := range cases {
+		steps, err := parsePath(c.path)
+		if err != nil || !reflect.DeepEqual(steps, c.steps) {
+			t.Errorf("parsePath(%s) = %+v, %v, want %+v", c.path, steps, err, c.steps)
+		}
+	}
+
+	for _, path := range []string{"", ".Price", "Guest.", "Guest..Tier", "Rooms[", "Rooms[]", `Attributes["x]`, `Attributes["x"`, `Attributes["x"]y`} {
+		if _, err := parsePath(path); !errors.Is(err, RuleSettingError.FIELD_PATH_INVALID) {
+			t.Errorf("parsePath(%q) = %v, want FIELD_PATH_INVALID", path, err)
+		}
+	}
+}
+
+func TestFieldValue(t *testing.T) {
+	r := sampleReservation()
+	ints := map[string]int64{
+		"Guest.Points":         10,
+		"Rooms[1].Adults":      1,
+		"Nights[1]":            4,
+		"Counts[7]":            1,
+		`ByRoom["101"].Adults`: 3,
+		"ByRoom.101.Adults":    3,
+		"Extra.Adults":         9,
+	}
+	for path, want := range ints {
+		if got, err := fieldInt(r, path); err != nil || got != want {
+			t.Errorf("fieldInt(%s) = %d, %v, want %d", path, got, err, want)
+		}
+	}
+	strs := map[string]string{
+		"Guest.Loyalty.Tier":    "gold",
+		`Attributes["channel"]`: "web",
+		"Attributes.channel":    "web",
+		`Attributes["a.b[c]"]`:  "odd",
+	}
+	for path, want := range strs {
+		// a value as well as a pointer
+		if got, err := fieldString(*r, path); err != nil || got != want {
+			t.Errorf("fieldString(%s) = %q, %v, want %q", path, got, err, want)
+		}
+	}
+
+	errs := map[string]error{
+		"Booker.Points":         RuleSettingError.FIELD_NIL,
+		"Guest.Missing":         RuleSettingError.MODIFER_FEILD_NOT_EXISTED,
+		"Guest.Points.Value":    RuleSettingError.MODIFER_FEILD_NOT_EXISTED,
+		"Rooms[2].Adults":       RuleSettingError.MODIFER_FEILD_NOT_EXISTED,
+		"Rooms[-1].Adults":      RuleSettingError.MODIFER_FEILD_NOT_EXISTED,
+		"Rooms[x].Adults":       RuleSettingError.FIELD_PATH_INVALID,
+		`Rooms["0"].Adults`:     RuleSettingError.FIELD_PATH_INVALID,
+		`Attributes["missing"]`: RuleSettingError.MODIFER_FEILD_NOT_EXISTED,
+		"Counts[x]":             RuleSettingError.FIELD_PATH_INVALID,
+	}
+	for path, want := range errs {
+		if _, err := fieldValue(r, path); !errors.Is(err, want) {
+			t.Errorf("fieldValue(%s) = %v, want %v", path, err, want)
+		}
+	}
+	if _, err := fieldValue(r, "Booker.Loyalty.Tier"); err == nil || err.Error() != "Nil field in path: Booker" {
+		t.Errorf("the error names the nil field: %v", err)
+	}
+}
+
+func TestSetField(t *testing.T) {
+	r := sampleReservation()
+	ints := map[string]int64{
+		"Guest.Points":         4,
+		"Rooms[0].Adults":      5,
+		"Nights[0]":            6,
+		`ByRoom["101"].Adults`: 7,
+		`Counts[8]`:            8,
+		"Extra.Adults":         11,
+	}
+	for path, v := range ints {
+		if err := setFieldInt(r, path, v); err != nil {
+			t.Errorf("setFieldInt(%s): %v", path, err)
+			continue
+		}
+		if got, err := fieldInt(r, path); err != nil || got != v {
+			t.Errorf("%s = %d, %v after setting %d", path, got, err, v)
+		}
+	}
+	if err := setFieldString(r, `Attributes["channel"]`, "ota"); err != nil || r.Attributes["channel"] != "ota" {
+		t.Errorf("setFieldString of a map key: %v, %v", err, r.Attributes)
+	}
+	if err := setFieldString(r, `Attributes["new"]`, "x"); err != nil || r.Attributes["new"] != "x" {
+		t.Errorf("setFieldString of a new map key: %v, %v", err, r.Attributes)
+	}
+
+	errs := map[string]error{
+		"Booker.Points":        RuleSettingError.FIELD_NIL,
+		`ByRoom["102"].Adults`: RuleSettingError.MODIFER_FEILD_NOT_EXISTED,
+		"Rooms[9].Adults":      RuleSettingError.MODIFER_FEILD_NOT_EXISTED,
+		"Missing":              RuleSettingError.MODIFER_FEILD_NOT_EXISTED,
+		"Guest.Loyalty.Tier.X": RuleSettingError.MODIFER_FEILD_NOT_EXISTED,
+	}
+	for path, want := range errs {
+		if err := setFieldInt(r, path, 1); !errors.Is(err, want) {
+			t.Errorf("setFieldInt(%s) = %v, want %v", path, err, want)
+		}
+	}
+	if err := setFieldInt(*r, "Guest.Points", 1); !errors.Is(err, RuleSettingError.MODIFER_FEILD_NOT_EXISTED) {
+		t.Errorf("setting through a value = %v, want MODIFER_FEILD_NOT_EXISTED", err)
+	}
+}
+
+func TestNestedPathsInRules(t *testing.T) {
+	rs := RuleSetting{ID: "s", Enable: true, Rule: Rule{
+		ConditionChain: []Condition{
+			{Type: RuleConditionType.STRING, LeftSide: "Guest.Loyalty.Tier", LeftType: ConditionSideType.FIELD, RightSide: "gold", RightType: ConditionSideType.VALUE},
+			intCondition("Rooms[0].Adults", RuleConditionCompare.MORE_EQUAL, "2"),
+		},
+		ModiferChain: []Modifer{
+			{Operand: RuleOperand.ADD, DataType: ModiferDataType.INT, LeftSide: "Guest.Points", LeftType: ModiferSideType.FIELD, RightSide: `ByRoom["101"].Adults`, RightType: ModiferSideType.FIELD, TargetField: "Rooms[1].Adults"},
+		},
+	}}
+	r := sampleReservation()
+	if result, _, err := NewEngine(stubSupply{}).ApplySetting(r, rs); err != nil || !result {
+		t.Fatalf("ApplySetting = %v, %v", result, err)
+	}
+	if r.Rooms[1].Adults != 13 {
+		t.Errorf("Rooms[1].Adults = %d, want 13", r.Rooms[1].Adults)
+	}
+}
@@ -0,0 +1,293 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// FileSupply a Supply serving rule sets kept as one JSON, YAML or TOML file per RuleID under a directory,
+// a RuleID such as pricing/weekend lives in pricing/weekend.json, .yaml, .yml or .toml.
+// Each file holds a rule set as MarshalRuleSettings writes it and is validated before it is served
+type FileSupply struct {
+	dir    string
+	sample reflect.Type
+	mem    *MemorySupply
+
+	mu    sync.Mutex
+	files map[string]ruleFile // by RuleID
+}
+
+// ruleFile what was last loaded for one RuleID
+type ruleFile struct {
+	path    string
+	modTime time.Time
+	size    int64
+}
+
+var ruleFileExts = []string{".json", ".yaml", ".yml", ".toml"}
+
+// NewFileSupply load every rule set under dir, checked by Validate against sample which may be nil
+func NewFileSupply(dir string, sample reflect.Type) (*FileSupply, error) {
+	fsp := &FileSupply{
+		dir:    dir,
+		sample: sample,
+		mem:    NewMemorySupply(),
+		files:  make(map[string]ruleFile),
+	}
+	if err := fsp.Reload(); err != nil {
+		return nil, err
+	}
+	return fsp, nil
+}
+
+func (fsp *FileSupply) FetchRuleSettings(id string, start int) ([]RuleSetting, error) {
+	return fsp.mem.FetchRuleSettings(id, start)
+}
+
+func (fsp *FileSupply) FetchRuleSettingsContext(ctx context.Context, id string, start int) ([]RuleSetting, error) {
+	return fsp.mem.FetchRuleSettingsContext(ctx, id, start)
+}
+
+// SaveRuleSettings insert rs or replace the settings with the same ID, rewriting the file of every rule set touched.
+// Files are replaced atomically and keep their format, new rule sets are written as JSON
+func (fsp *FileSupply) SaveRuleSettings(rs []RuleSetting) error {
+	fsp.mu.Lock()
+	defer fsp.mu.Unlock()
+
+	byRule := make(map[string][]RuleSetting)
+	var order []string
+	for _, setting := range rs {
+		if setting.ID == "" {
+			setting.ID = uuid.NewV4().String()
+		}
+		if ruleID, ok := fsp.mem.ruleOf(setting.ID); ok && ruleID != setting.RuleID {
+			// moving it would mean rewriting two files that cannot be replaced together
+			return fmt.Errorf("%w: setting %s belongs to rule set %s", RuleSettingError.VALUE_INVALID, setting.ID, ruleID)
+		}
+		if _, ok := byRule[setting.RuleID]; !ok {
+			order = append(order, setting.RuleID)
+		}
+		byRule[setting.RuleID] = append(byRule[setting.RuleID], setting)
+	}
+
+	for _, ruleID := range order {
+		if err := checkRuleID(ruleID); err != nil {
+			return err
+		}
+		settings, err := fsp.mem.FetchRuleSettings(ruleID, math.MinInt)
+		if err != nil && !errors.Is(err, RuleSettingError.RULE_NOT_FOUND) {
+			return err
+		}
+		settings = upsertSettings(settings, byRule[ruleID])
+		if err := Validate(settings, fsp.sample); err != nil {
+			return err
+		}
+
+		path := filepath.Join(fsp.dir, filepath.FromSlash(ruleID)+ruleFileExts[0])
+		if f, ok := fsp.files[ruleID]; ok {
+			path = f.path
+		}
+		if err := writeRuleFile(path, settings); err != nil {
+			return err
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		fsp.files[ruleID] = ruleFile{path: path, modTime: info.ModTime(), size: info.Size()}
+		fsp.mem.replace(ruleID, settings)
+	}
+	return nil
+}
+
+func (fsp *FileSupply) SaveRuleSettingsContext(ctx context.Context, rs []RuleSetting) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return fsp.SaveRuleSettings(rs)
+}
+
+// Reload pick up files added, changed or removed since the last load. A file that fails to load
+// keeps its previous rule set served and its error is returned along with the others
+func (fsp *FileSupply) Reload() error {
+	fsp.mu.Lock()
+	defer fsp.mu.Unlock()
+
+	found := make(map[string]string)
+	var errs []error
+	err := filepath.WalkDir(fsp.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), ".") && path != fsp.dir {
+			// temporary files of an atomic write and hidden directories
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		ext := filepath.Ext(path)
+		if d.IsDir() || !slices.Contains(ruleFileExts, ext) {
+			return nil
+		}
+		rel, err := filepath.Rel(fsp.dir, path)
+		if err != nil {
+			return err
+		}
+		ruleID := filepath.ToSlash(strings.TrimSuffix(rel, ext))
+		if other, ok := found[ruleID]; ok {
+			errs = append(errs, fmt.Errorf("%w: rule set %s is in both %s and %s", RuleSettingError.VALUE_INVALID, ruleID, other, path))
+			return nil
+		}
+		found[ruleID] = path
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for ruleID, path := range found {
+		info, err := os.Stat(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if f, ok := fsp.files[ruleID]; ok && f.path == path && f.modTime.Equal(info.ModTime()) && f.size == info.Size() {
+			continue
+		}
+		settings, err := fsp.readRuleFile(ruleID, path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			continue
+		}
+		fsp.files[ruleID] = ruleFile{path: path, modTime: info.ModTime(), size: info.Size()}
+		fsp.mem.replace(ruleID, settings)
+	}
+	for ruleID := range fsp.files {
+		if _, ok := found[ruleID]; !ok {
+			delete(fsp.files, ruleID)
+			fsp.mem.replace(ruleID, nil)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Watch Reload every interval until ctx is done, handing each failed reload to onError which may be nil
+func (fsp *FileSupply) Watch(ctx context.Context, interval time.Duration, onError func(err error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := fsp.Reload(); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+func (fsp *FileSupply) readRuleFile(ruleID, path string) ([]RuleSetting, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	format, _ := RuleFormatOf(path)
+	rs, err := UnmarshalRuleSettings(b, format)
+	if err != nil {
+		return nil, err
+	}
+	for i := range rs {
+		if rs[i].RuleID == "" {
+			rs[i].RuleID = ruleID
+		}
+		if rs[i].RuleID != ruleID {
+			return nil, fmt.Errorf("%w: setting %s belongs to rule set %s", RuleSettingError.VALUE_INVALID, rs[i].ID, rs[i].RuleID)
+		}
+		if rs[i].ID == "" {
+			return nil, fmt.Errorf("%w: setting %d has no id", RuleSettingError.VALUE_INVALID, i)
+		}
+	}
+	sort.SliceStable(rs, func(i, j int) bool {
+		return rs[i].Sequence < rs[j].Sequence
+	})
+	if err := Validate(rs, fsp.sample); err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+// writeRuleFile replace path with rs through a temporary file in the same directory
+func writeRuleFile(path string, rs []RuleSetting) error {
+	format, _ := RuleFormatOf(path)
+	b, err := MarshalRuleSettings(rs, format)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// upsertSettings settings with every setting of rs put in place of the one with its ID, in Sequence order
+func upsertSettings(settings, rs []RuleSetting) []RuleSetting {
+	index := make(map[string]int, len(settings))
+	for i, setting := range settings {
+		index[setting.ID] = i
+	}
+	for _, setting := range rs {
+		if i, ok := index[setting.ID]; ok {
+			settings[i] = setting
+			continue
+		}
+		index[setting.ID] = len(settings)
+		settings = append(settings, setting)
+	}
+	sort.SliceStable(settings, func(i, j int) bool {
+		return settings[i].Sequence < settings[j].Sequence
+	})
+	return settings
+}
+
+// checkRuleID a RuleID must stay inside the supply's directory
+func checkRuleID(ruleID string) error {
+	clean := filepath.ToSlash(filepath.Clean(filepath.FromSlash(ruleID)))
+	if ruleID == "" || clean != ruleID || strings.HasPrefix(clean, "../") || clean == ".." || filepath.IsAbs(ruleID) {
+		return fmt.Errorf("%w: rule set %q", RuleSettingError.VALUE_INVALID, ruleID)
+	}
+	return nil
+}
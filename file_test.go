@@ -0,0 +1,264 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	rule "github.com/007lock/go-turner"
+	"github.com/007lock/go-turner/supplytest"
+)
+
+// writeRules write rs to path in the format of its extension
+func writeRules(t *testing.T, path string, rs ...rule.RuleSetting) {
+	t.Helper()
+	format, ok := rule.RuleFormatOf(path)
+	if !ok {
+		t.Fatalf("no format for %s", path)
+	}
+	b, err := rule.MarshalRuleSettings(rs, format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func expectSettings(t *testing.T, sp rule.Supply, ruleID string, ids ...string) {
+	t.Helper()
+	rs, err := sp.FetchRuleSettings(ruleID, 0)
+	if err != nil {
+		t.Fatalf("FetchRuleSettings(%q): %v", ruleID, err)
+	}
+	got := make([]string, len(rs))
+	for i, setting := range rs {
+		got[i] = setting.ID
+	}
+	if strings.Join(got, ",") != strings.Join(ids, ",") {
+		t.Fatalf("rule set %s: %v, want %v", ruleID, got, ids)
+	}
+}
+
+func TestFileSupplyConformance(t *testing.T) {
+	supplytest.Run(t, func(t *testing.T) rule.Supply {
+		fsp, err := rule.NewFileSupply(t.TempDir(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fsp
+	})
+}
+
+func TestFileSupplyLoadsEveryFormat(t *testing.T) {
+	dir := t.TempDir()
+	writeRules(t, filepath.Join(dir, "json.json"), supplytest.Setting("a", "", 2, "100"), supplytest.Setting("b", "", 1, "100"))
+	writeRules(t, filepath.Join(dir, "yaml.yaml"), supplytest.Setting("c", "", 1, "100"))
+	writeRules(t, filepath.Join(dir, "yml.yml"), supplytest.Setting("d", "", 1, "100"))
+	writeRules(t, filepath.Join(dir, "pricing", "weekend.toml"), supplytest.Setting("e", "", 1, "100"))
+	writeRules(t, filepath.Join(dir, ".hidden", "skipped.json"), supplytest.Setting("f", "", 1, "100"))
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a rule set"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fsp, err := rule.NewFileSupply(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectSettings(t, fsp, "json", "b", "a")
+	expectSettings(t, fsp, "yaml", "c")
+	expectSettings(t, fsp, "yml", "d")
+	expectSettings(t, fsp, "pricing/weekend", "e")
+	if _, err := fsp.FetchRuleSettings(".hidden/skipped", 0); !errors.Is(err, rule.RuleSettingError.RULE_NOT_FOUND) {
+		t.Fatalf("FetchRuleSettings = %v, want hidden directories skipped", err)
+	}
+	rs, _ := fsp.FetchRuleSettings("pricing/weekend", 0)
+	if rs[0].RuleID != "pricing/weekend" {
+		t.Fatalf("RuleID = %q, want the path pricing/weekend", rs[0].RuleID)
+	}
+}
+
+func TestFileSupplyRejectsFiles(t *testing.T) {
+	other := supplytest.Setting("a", "", 1, "100")
+	other.RuleID = "other"
+	invalid := supplytest.Setting("a", "", 1, "100")
+	invalid.Rule.ModiferChain[0].Operand = 99
+
+	for _, tc := range []struct {
+		name  string
+		write func(t *testing.T, dir string)
+	}{
+		{"TwoFormats", func(t *testing.T, dir string) {
+			writeRules(t, filepath.Join(dir, "r.json"), supplytest.Setting("a", "", 1, "100"))
+			writeRules(t, filepath.Join(dir, "r.yaml"), supplytest.Setting("b", "", 1, "100"))
+		}},
+		{"OtherRuleSet", func(t *testing.T, dir string) {
+			writeRules(t, filepath.Join(dir, "r.json"), other)
+		}},
+		{"NoID", func(t *testing.T, dir string) {
+			writeRules(t, filepath.Join(dir, "r.json"), supplytest.Setting("", "", 1, "100"))
+		}},
+		{"Invalid", func(t *testing.T, dir string) {
+			writeRules(t, filepath.Join(dir, "r.json"), invalid)
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			tc.write(t, dir)
+			if _, err := rule.NewFileSupply(dir, nil); err == nil {
+				t.Fatal("NewFileSupply succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestFileSupplySaveKeepsFormat(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "r.yaml")
+	writeRules(t, yamlPath, supplytest.Setting("a", "", 1, "100"))
+	fsp, err := rule.NewFileSupply(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	added := supplytest.Setting("b", "", 2, "100")
+	added.RuleID = "r"
+	created := supplytest.Setting("c", "", 1, "100")
+	created.RuleID = "pricing/new"
+	if err := fsp.SaveRuleSettings([]rule.RuleSetting{added, created}); err != nil {
+		t.Fatal(err)
+	}
+	expectSettings(t, fsp, "r", "a", "b")
+	expectSettings(t, fsp, "pricing/new", "c")
+
+	b, err := os.ReadFile(yamlPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs, err := rule.UnmarshalRuleSettings(b, rule.RuleFormat.YAML)
+	if err != nil || len(rs) != 2 {
+		t.Fatalf("r.yaml read as %+v, %v, want YAML with a and b", rs, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "pricing", "new.json")); err != nil {
+		t.Fatalf("the new rule set is not written as JSON: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			t.Errorf("temporary file %s left behind", entry.Name())
+		}
+	}
+
+	again, err := rule.NewFileSupply(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectSettings(t, again, "r", "a", "b")
+	expectSettings(t, again, "pricing/new", "c")
+}
+
+func TestFileSupplySaveRejects(t *testing.T) {
+	fsp, err := rule.NewFileSupply(t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := supplytest.Setting("a", "", 1, "100")
+	a.RuleID = "r"
+	if err := fsp.SaveRuleSettings([]rule.RuleSetting{a}); err != nil {
+		t.Fatal(err)
+	}
+
+	moved := a
+	moved.RuleID = "q"
+	escaping := supplytest.Setting("b", "", 1, "100")
+	escaping.RuleID = "../outside"
+	invalid := supplytest.Setting("c", "", 1, "100")
+	invalid.RuleID = "r"
+	invalid.Rule.ModiferChain[0].Operand = 99
+	for name, setting := range map[string]rule.RuleSetting{"Moved": moved, "Escaping": escaping, "Invalid": invalid} {
+		t.Run(name, func(t *testing.T) {
+			if err := fsp.SaveRuleSettings([]rule.RuleSetting{setting}); err == nil {
+				t.Fatal("SaveRuleSettings succeeded, want an error")
+			}
+			expectSettings(t, fsp, "r", "a")
+		})
+	}
+}
+
+func TestFileSupplyReload(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "r.json")
+	gone := filepath.Join(dir, "gone.json")
+	writeRules(t, path, supplytest.Setting("a", "", 1, "100"))
+	writeRules(t, gone, supplytest.Setting("g", "", 1, "100"))
+	fsp, err := rule.NewFileSupply(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeRules(t, path, supplytest.Setting("a", "", 1, "100"), supplytest.Setting("b", "", 2, "100"))
+	writeRules(t, filepath.Join(dir, "added.toml"), supplytest.Setting("c", "", 1, "100"))
+	if err := os.Remove(gone); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsp.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	expectSettings(t, fsp, "r", "a", "b")
+	expectSettings(t, fsp, "added", "c")
+	if _, err := fsp.FetchRuleSettings("gone", 0); !errors.Is(err, rule.RuleSettingError.RULE_NOT_FOUND) {
+		t.Fatalf("FetchRuleSettings = %v, want the removed file dropped", err)
+	}
+
+	if err := os.WriteFile(path, []byte(`[{"id":`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	err = fsp.Reload()
+	if err == nil || !strings.Contains(err.Error(), path) {
+		t.Fatalf("Reload = %v, want an error naming %s", err, path)
+	}
+	expectSettings(t, fsp, "r", "a", "b")
+}
+
+func TestFileSupplyWatch(t *testing.T) {
+	dir := t.TempDir()
+	fsp, err := rule.NewFileSupply(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- fsp.Watch(ctx, 5*time.Millisecond, nil)
+	}()
+
+	writeRules(t, filepath.Join(dir, "r.json"), supplytest.Setting("a", "", 1, "100"))
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, err := fsp.FetchRuleSettings("r", 0); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the new file was not picked up")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Watch = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,374 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+// GormSupply a Supply over the DB_TABLE_RULE table through gorm
+type GormSupply struct {
+	db *gorm.DB
+}
+
+func NewGormSupply(db *gorm.DB) *GormSupply {
+	return &GormSupply{db: db}
+}
+
+// Migrate create or update the DB_TABLE_RULE and DB_TABLE_INFO tables and their indexes
+func (gs *GormSupply) Migrate() error {
+	if err := gs.db.Table(DB_TABLE_RULE).AutoMigrate(&RuleSettingDB{}).Error; err != nil {
+		return err
+	}
+	return gs.db.Table(DB_TABLE_INFO).AutoMigrate(&RuleInfoDB{}).Error
+}
+
+// SaveRuleSettings insert rs or replace the rows with the same ID in one transaction, settings without an ID get one
+func (gs *GormSupply) SaveRuleSettings(rs []RuleSetting) error {
+	tx := gs.db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	for _, rule := range rs {
+		ruleDB, err := rule.MakeDBObject()
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		err = tx.Table(DB_TABLE_RULE).Save(ruleDB).Error
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit().Error
+}
+
+// FetchRuleSettings the enabled settings of rule set id with Sequence >= start, in order
+func (gs *GormSupply) FetchRuleSettings(id string, start int) ([]RuleSetting, error) {
+	return gs.fetchRuleSettings(gs.db.Table(DB_TABLE_RULE).Where("rule_id = ? AND sequence >= ? AND enable = ?", id, start, true), id)
+}
+
+// FetchAllRuleSettings the settings of rule set id with Sequence >= start, in order, disabled ones included
+func (gs *GormSupply) FetchAllRuleSettings(id string, start int) ([]RuleSetting, error) {
+	return gs.fetchRuleSettings(gs.db.Table(DB_TABLE_RULE).Where("rule_id = ? AND sequence >= ?", id, start), id)
+}
+
+func (gs *GormSupply) fetchRuleSettings(q *gorm.DB, id string) ([]RuleSetting, error) {
+	var rsdb []RuleSettingDB
+	err := q.Order("sequence").Find(&rsdb).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(rsdb) == 0 {
+		var count int
+		err := gs.db.Table(DB_TABLE_RULE).Where("rule_id = ?", id).Count(&count).Error
+		if err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			return nil, fmt.Errorf("%w: %s", RuleSettingError.RULE_NOT_FOUND, id)
+		}
+	}
+
+	rs := make([]RuleSetting, len(rsdb))
+	for i, ruledb := range rsdb {
+		rule, err := ruledb.MakeObject()
+		if err != nil {
+			return nil, err
+		}
+		rs[i] = *rule
+	}
+	return rs, nil
+}
+
+// SaveRuleInfo insert info or replace the row with the same ID, keeping its CreatedAt
+func (gs *GormSupply) SaveRuleInfo(info RuleInfo) error {
+	if err := checkInfo(info); err != nil {
+		return err
+	}
+	tx := gs.db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	var stored RuleInfoDB
+	err := tx.Table(DB_TABLE_INFO).Where("id = ?", info.ID).First(&stored).Error
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		tx.Rollback()
+		return err
+	}
+	stampInfo(&info, stored.CreatedAt, time.Now())
+	infoDB, err := info.MakeDBObject()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	err = tx.Table(DB_TABLE_INFO).Save(infoDB).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// FetchRuleInfo the RuleInfo of rule set id
+func (gs *GormSupply) FetchRuleInfo(id string) (*RuleInfo, error) {
+	var infodb RuleInfoDB
+	err := gs.db.Table(DB_TABLE_INFO).Where("id = ?", id).First(&infodb).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, fmt.Errorf("%w: %s", RuleSettingError.RULE_NOT_FOUND, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return infodb.MakeObject()
+}
+
+// ListRuleInfos every RuleInfo in ID order, paged
+func (gs *GormSupply) ListRuleInfos(offset, limit int) ([]RuleInfo, error) {
+	return gs.SearchRuleInfos(InfoQuery{Offset: offset, Limit: limit})
+}
+
+// SearchRuleInfos the RuleInfo matching q in ID order
+func (gs *GormSupply) SearchRuleInfos(q InfoQuery) ([]RuleInfo, error) {
+	db := gs.db.Table(DB_TABLE_INFO)
+	if where, args := q.where(func(int) string { return "?" }); where != "" {
+		db = db.Where(where, args...)
+	}
+	if q.Limit > 0 {
+		db = db.Offset(q.Offset).Limit(q.Limit)
+	}
+	var infosdb []RuleInfoDB
+	if err := db.Order("id").Find(&infosdb).Error; err != nil {
+		return nil, err
+	}
+	if q.Limit <= 0 {
+		// OFFSET without LIMIT is not portable, those rows are skipped here
+		infosdb = infosdb[min(max(q.Offset, 0), len(infosdb)):]
+	}
+
+	infos := make([]RuleInfo, len(infosdb))
+	for i, infodb := range infosdb {
+		info, err := infodb.MakeObject()
+		if err != nil {
+			return nil, err
+		}
+		infos[i] = *info
+	}
+	return infos, nil
+}
+
+// GormVersionedSupply a VersionedSupply over the DB_TABLE_VERSION and DB_TABLE_PUBLICATION tables through gorm
+type GormVersionedSupply struct {
+	db *gorm.DB
+}
+
+func NewGormVersionedSupply(db *gorm.DB) *GormVersionedSupply {
+	return &GormVersionedSupply{db: db}
+}
+
+// Migrate create or update the DB_TABLE_VERSION and DB_TABLE_PUBLICATION tables and their indexes
+func (gv *GormVersionedSupply) Migrate() error {
+	if err := gv.db.Table(DB_TABLE_VERSION).AutoMigrate(&RuleVersionDB{}).Error; err != nil {
+		return err
+	}
+	return gv.db.Table(DB_TABLE_PUBLICATION).AutoMigrate(&RulePublicationDB{}).Error
+}
+
+// SaveRuleSettings make a new draft version of every rule set touched in one transaction, holding its latest
+// version with rs put in place of the settings with the same ID. Settings without an ID get one
+func (gv *GormVersionedSupply) SaveRuleSettings(rs []RuleSetting) error {
+	byRule := make(map[string][]RuleSetting)
+	var order []string
+	for _, setting := range rs {
+		if setting.ID == "" {
+			setting.ID = uuid.NewV4().String()
+		}
+		if _, ok := byRule[setting.RuleID]; !ok {
+			order = append(order, setting.RuleID)
+		}
+		byRule[setting.RuleID] = append(byRule[setting.RuleID], setting)
+	}
+
+	tx := gv.db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	for _, ruleID := range order {
+		var latest RuleVersionDB
+		err := tx.Table(DB_TABLE_VERSION).Where("rule_id = ?", ruleID).Order("version DESC").First(&latest).Error
+		if err != nil && !gorm.IsRecordNotFoundError(err) {
+			tx.Rollback()
+			return err
+		}
+		var settings []RuleSetting
+		if latest.Settings != "" {
+			if err := json.Unmarshal([]byte(latest.Settings), &settings); err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+		ss, err := json.Marshal(upsertSettings(settings, byRule[ruleID]))
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		versionDB := &RuleVersionDB{RuleID: ruleID, Version: latest.Version + 1, Settings: string(ss)}
+		err = tx.Table(DB_TABLE_VERSION).Create(versionDB).Error
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit().Error
+}
+
+// FetchRuleSettings the settings of the published version of rule set id with Sequence >= start, in order
+func (gv *GormVersionedSupply) FetchRuleSettings(id string, start int) ([]RuleSetting, error) {
+	version, err := gv.PublishedVersion(id)
+	if err != nil {
+		return nil, err
+	}
+	return gv.FetchVersion(id, version, start)
+}
+
+// FetchVersion the settings of version of rule set id with Sequence >= start, in order
+func (gv *GormVersionedSupply) FetchVersion(id string, version int, start int) ([]RuleSetting, error) {
+	versiondb, err := gv.version(gv.db, id, version)
+	if err != nil {
+		return nil, err
+	}
+	var settings []RuleSetting
+	if err := json.Unmarshal([]byte(versiondb.Settings), &settings); err != nil {
+		return nil, err
+	}
+	rs := make([]RuleSetting, 0, len(settings))
+	for _, setting := range settings {
+		if setting.Sequence >= int64(start) {
+			rs = append(rs, setting)
+		}
+	}
+	return rs, nil
+}
+
+// ListVersions every version of rule set id, oldest first
+func (gv *GormVersionedSupply) ListVersions(id string) ([]RuleVersion, error) {
+	var versionsdb []RuleVersionDB
+	err := gv.db.Table(DB_TABLE_VERSION).
+		Select("rule_id, version, created_at").
+		Where("rule_id = ?", id).
+		Order("version").
+		Find(&versionsdb).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(versionsdb) == 0 {
+		return nil, fmt.Errorf("%w: %s", RuleSettingError.RULE_NOT_FOUND, id)
+	}
+	published, err := gv.PublishedVersion(id)
+	if err != nil && !errors.Is(err, RuleSettingError.RULE_NOT_FOUND) {
+		return nil, err
+	}
+
+	versions := make([]RuleVersion, len(versionsdb))
+	for i, versiondb := range versionsdb {
+		versions[i] = RuleVersion{
+			RuleID:    versiondb.RuleID,
+			Version:   versiondb.Version,
+			Published: versiondb.Version == published,
+			CreatedAt: versiondb.CreatedAt,
+		}
+	}
+	return versions, nil
+}
+
+// PublishedVersion the version of rule set id FetchRuleSettings serves
+func (gv *GormVersionedSupply) PublishedVersion(id string) (int, error) {
+	var publication RulePublicationDB
+	err := gv.db.Table(DB_TABLE_PUBLICATION).Where("rule_id = ?", id).Order("id DESC").First(&publication).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return 0, fmt.Errorf("%w: %s has no published version", RuleSettingError.RULE_NOT_FOUND, id)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return publication.Version, nil
+}
+
+// Publish serve version of rule set id from now on
+func (gv *GormVersionedSupply) Publish(id string, version int) error {
+	tx := gv.db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	if _, err := gv.version(tx, id, version); err != nil {
+		tx.Rollback()
+		return err
+	}
+	var current RulePublicationDB
+	err := tx.Table(DB_TABLE_PUBLICATION).Where("rule_id = ?", id).Order("id DESC").First(&current).Error
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		tx.Rollback()
+		return err
+	}
+	if err == nil && current.Version == version {
+		return tx.Commit().Error
+	}
+	err = tx.Table(DB_TABLE_PUBLICATION).Create(&RulePublicationDB{RuleID: id, Version: version, PublishedAt: time.Now()}).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// Rollback serve again the version of rule set id published before the current one, and return it
+func (gv *GormVersionedSupply) Rollback(id string) (int, error) {
+	tx := gv.db.Begin()
+	if tx.Error != nil {
+		return 0, tx.Error
+	}
+	var publications []RulePublicationDB
+	err := tx.Table(DB_TABLE_PUBLICATION).Where("rule_id = ?", id).Order("id DESC").Limit(2).Find(&publications).Error
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if len(publications) < 2 {
+		tx.Rollback()
+		return 0, fmt.Errorf("%w: %s has no earlier published version", RuleSettingError.VERSION_NOT_FOUND, id)
+	}
+	err = tx.Table(DB_TABLE_PUBLICATION).Where("id = ?", publications[0].ID).Delete(&RulePublicationDB{}).Error
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	return publications[1].Version, tx.Commit().Error
+}
+
+// version the row of version of rule set id, read through db which may be a transaction
+func (gv *GormVersionedSupply) version(db *gorm.DB, id string, version int) (*RuleVersionDB, error) {
+	var versiondb RuleVersionDB
+	err := db.Table(DB_TABLE_VERSION).Where("rule_id = ? AND version = ?", id, version).First(&versiondb).Error
+	if gorm.IsRecordNotFoundError(err) {
+		var count int
+		if err := db.Table(DB_TABLE_VERSION).Where("rule_id = ?", id).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			return nil, fmt.Errorf("%w: %s", RuleSettingError.RULE_NOT_FOUND, id)
+		}
+		return nil, fmt.Errorf("%w: %s version %d", RuleSettingError.VERSION_NOT_FOUND, id, version)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &versiondb, nil
+}
@@ -0,0 +1,166 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule_test
+
+import (
+	"errors"
+	"testing"
+
+	rule "github.com/007lock/go-turner"
+	"github.com/007lock/go-turner/supplytest"
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+)
+
+// openGorm an empty in-memory SQLite database, one connection so every statement sees the same database
+func openGorm(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.DB().SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func newGormSupply(t *testing.T) *rule.GormSupply {
+	t.Helper()
+	gs := rule.NewGormSupply(openGorm(t))
+	if err := gs.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	return gs
+}
+
+func TestGormSupplyConformance(t *testing.T) {
+	supplytest.Run(t, func(t *testing.T) rule.Supply {
+		return newGormSupply(t)
+	})
+}
+
+func TestGormSupplyDisabled(t *testing.T) {
+	gs := newGormSupply(t)
+	err := gs.SaveRuleSettings([]rule.RuleSetting{
+		{ID: "a", RuleID: "r", Sequence: 1, Enable: true},
+		{ID: "b", RuleID: "r", Sequence: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs, err := gs.FetchRuleSettings("r", 0); err != nil || len(rs) != 1 || rs[0].ID != "a" {
+		t.Fatalf("FetchRuleSettings = %+v, %v, want a alone", rs, err)
+	}
+	rs, err := gs.FetchAllRuleSettings("r", 0)
+	if err != nil || len(rs) != 2 || !rs[0].Enable || rs[1].Enable {
+		t.Fatalf("FetchAllRuleSettings = %+v, %v, want a enabled and b disabled", rs, err)
+	}
+}
+
+func TestGormSupplyIncludeDisabled(t *testing.T) {
+	type booking struct{ Price int64 }
+	disabled := supplytest.Setting("b", "r", 2, "10")
+	disabled.Enable = false
+	gs := newGormSupply(t)
+	if err := gs.SaveRuleSettings([]rule.RuleSetting{supplytest.Setting("a", "r", 1, "1"), disabled}); err != nil {
+		t.Fatal(err)
+	}
+	entry := []rule.RuleSetting{{ID: "entry", RuleID: "entry", Sequence: 1, Enable: true, Rule: rule.Rule{
+		ModiferChain: []rule.Modifer{{DataType: rule.ModiferDataType.JRT, LeftSide: "r", RightSide: "0"}},
+	}}}
+
+	for _, tc := range []struct {
+		name  string
+		opts  []rule.Option
+		price int64
+	}{
+		{"Skipped", nil, 101},
+		{"Included", []rule.Option{rule.WithIncludeDisabled(true)}, 111},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			b := &booking{Price: 100}
+			if _, err := rule.NewEngine(gs, tc.opts...).ApplySettings(b, entry); err != nil {
+				t.Fatal(err)
+			}
+			if b.Price != tc.price {
+				t.Fatalf("Price = %d, want %d", b.Price, tc.price)
+			}
+		})
+	}
+}
+
+func TestGormSupplyMigrateTwice(t *testing.T) {
+	gs := newGormSupply(t)
+	if err := gs.SaveRuleSettings([]rule.RuleSetting{{ID: "a", RuleID: "r", Enable: true}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := gs.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	if rs, err := gs.FetchRuleSettings("r", 0); err != nil || len(rs) != 1 {
+		t.Fatalf("FetchRuleSettings = %+v, %v, want a kept across migrations", rs, err)
+	}
+}
+
+func TestGormSupplyRuleInfo(t *testing.T) {
+	gs := newGormSupply(t)
+	if err := gs.SaveRuleInfo(rule.RuleInfo{ID: "weekend", Status: 9}); !errors.Is(err, rule.RuleSettingError.VALUE_INVALID) {
+		t.Fatalf("SaveRuleInfo of an unknown status = %v, want VALUE_INVALID", err)
+	}
+	for _, info := range []rule.RuleInfo{
+		{ID: "weekend", Name: "Weekend rates", Owner: "revenue", Tags: []string{"rate"}},
+		{ID: "loyalty", Name: "Loyalty discount", Owner: "crm", Tags: []string{"rate", "member"}},
+		{ID: "promo_100%", Name: "Promotion", Owner: "crm"},
+	} {
+		if err := gs.SaveRuleInfo(info); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	info, err := gs.FetchRuleInfo("weekend")
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := info.CreatedAt
+	info.Description = "Saturday and Sunday"
+	if err := gs.SaveRuleInfo(*info); err != nil {
+		t.Fatal(err)
+	}
+	info, err = gs.FetchRuleInfo("weekend")
+	if err != nil || info.Description != "Saturday and Sunday" || !info.CreatedAt.Equal(created) {
+		t.Fatalf("FetchRuleInfo = %+v, %v, want the description replaced and CreatedAt kept", info, err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		q    rule.InfoQuery
+		ids  []string
+	}{
+		{"Owner", rule.InfoQuery{Owner: "crm"}, []string{"loyalty", "promo_100%"}},
+		{"Tag", rule.InfoQuery{Tags: []string{"member"}}, []string{"loyalty"}},
+		{"Text", rule.InfoQuery{Text: "RATES"}, []string{"weekend"}},
+		{"LikeEscaped", rule.InfoQuery{Text: "100%"}, []string{"promo_100%"}},
+		{"Offset", rule.InfoQuery{Offset: 1}, []string{"promo_100%", "weekend"}},
+		{"Page", rule.InfoQuery{Offset: 1, Limit: 1}, []string{"promo_100%"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			infos, err := gs.SearchRuleInfos(tc.q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(infos) != len(tc.ids) {
+				t.Fatalf("%+v, want %v", infos, tc.ids)
+			}
+			for i := range infos {
+				if infos[i].ID != tc.ids[i] {
+					t.Fatalf("%+v, want %v", infos, tc.ids)
+				}
+			}
+		})
+	}
+
+	if _, err := gs.FetchRuleInfo("missing"); !errors.Is(err, rule.RuleSettingError.RULE_NOT_FOUND) {
+		t.Fatalf("FetchRuleInfo = %v, want RULE_NOT_FOUND", err)
+	}
+}
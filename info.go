@@ -0,0 +1,119 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// checkInfo a RuleInfo must name its rule set and have a known status
+func checkInfo(info RuleInfo) error {
+	if info.ID == "" {
+		return fmt.Errorf("%w: rule info has no id", RuleSettingError.VALUE_INVALID)
+	}
+	switch info.Status {
+	case RuleInfoStatus.DRAFT, RuleInfoStatus.ACTIVE, RuleInfoStatus.ARCHIVED:
+		return nil
+	}
+	return fmt.Errorf("%w: rule info %s has status %d", RuleSettingError.VALUE_INVALID, info.ID, info.Status)
+}
+
+// stampInfo set UpdatedAt to now, CreatedAt is kept as created when the info was stored before
+func stampInfo(info *RuleInfo, created time.Time, now time.Time) {
+	info.UpdatedAt = now
+	if !created.IsZero() {
+		info.CreatedAt = created
+	} else if info.CreatedAt.IsZero() {
+		info.CreatedAt = now
+	}
+}
+
+// match whether info is one of the results of q, paging aside
+func (q InfoQuery) match(info RuleInfo) bool {
+	if q.Text != "" {
+		text := strings.ToLower(q.Text)
+		if !strings.Contains(strings.ToLower(info.ID), text) &&
+			!strings.Contains(strings.ToLower(info.Name), text) &&
+			!strings.Contains(strings.ToLower(info.Description), text) {
+			return false
+		}
+	}
+	if q.Owner != "" && info.Owner != q.Owner {
+		return false
+	}
+	for _, tag := range q.Tags {
+		if !slices.Contains(info.Tags, tag) {
+			return false
+		}
+	}
+	if q.RuleType != nil && info.RuleType != *q.RuleType {
+		return false
+	}
+	if q.Status != nil && info.Status != *q.Status {
+		return false
+	}
+	return true
+}
+
+// filter the infos matching q in ID order, paged
+func (q InfoQuery) filter(infos []RuleInfo) []RuleInfo {
+	out := []RuleInfo{}
+	for _, info := range infos {
+		if q.match(info) {
+			out = append(out, info)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].ID < out[j].ID
+	})
+	if q.Offset > 0 {
+		if q.Offset >= len(out) {
+			return []RuleInfo{}
+		}
+		out = out[q.Offset:]
+	}
+	if q.Limit > 0 && q.Limit < len(out) {
+		out = out[:q.Limit]
+	}
+	return out
+}
+
+// where the SQL condition of q over DB_TABLE_INFO and its arguments, placeholder gives the n-th one from 1.
+// Tags are matched against the JSON array they are stored as
+func (q InfoQuery) where(placeholder func(n int) string) (string, []interface{}) {
+	var clauses []string
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return placeholder(len(args))
+	}
+	if q.Text != "" {
+		text := "%" + escapeLike(strings.ToLower(q.Text)) + "%"
+		clauses = append(clauses, fmt.Sprintf("(LOWER(id) LIKE %s ESCAPE '!' OR LOWER(name) LIKE %s ESCAPE '!' OR LOWER(description) LIKE %s ESCAPE '!')",
+			arg(text), arg(text), arg(text)))
+	}
+	if q.Owner != "" {
+		clauses = append(clauses, "owner = "+arg(q.Owner))
+	}
+	for _, tag := range q.Tags {
+		tt, _ := json.Marshal(tag)
+		clauses = append(clauses, "tags LIKE "+arg("%"+escapeLike(string(tt))+"%")+" ESCAPE '!'")
+	}
+	if q.RuleType != nil {
+		clauses = append(clauses, "rule_type = "+arg(*q.RuleType))
+	}
+	if q.Status != nil {
+		clauses = append(clauses, "status = "+arg(*q.Status))
+	}
+	return strings.Join(clauses, " AND "), args
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
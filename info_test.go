@@ -0,0 +1,183 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sampleInfos() []RuleInfo {
+	return []RuleInfo{
+		{ID: "weekend", Name: "Weekend rates", Owner: "revenue", Tags: []string{"rate"}, RuleType: 1, Status: RuleInfoStatus.ACTIVE},
+		{ID: "loyalty", Name: "Loyalty discount", Description: "Points for MEMBERS", Owner: "crm", Tags: []string{"rate", "member"}, RuleType: 2, Status: RuleInfoStatus.ACTIVE},
+		{ID: "promo_100%", Name: "Promotion", Owner: "crm", Status: RuleInfoStatus.DRAFT},
+		{ID: "legacy", Name: "Old rates", Owner: "revenue", Tags: []string{"rate"}, RuleType: 1, Status: RuleInfoStatus.ARCHIVED},
+	}
+}
+
+func TestCheckInfo(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		info RuleInfo
+		ok   bool
+	}{
+		{"Draft", RuleInfo{ID: "r", Status: RuleInfoStatus.DRAFT}, true},
+		{"Active", RuleInfo{ID: "r", Status: RuleInfoStatus.ACTIVE}, true},
+		{"Archived", RuleInfo{ID: "r", Status: RuleInfoStatus.ARCHIVED}, true},
+		{"NoID", RuleInfo{Status: RuleInfoStatus.ACTIVE}, false},
+		{"UnknownStatus", RuleInfo{ID: "r", Status: 7}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkInfo(tc.info)
+			if tc.ok && err != nil {
+				t.Fatalf("checkInfo = %v, want nil", err)
+			}
+			if !tc.ok && !errors.Is(err, RuleSettingError.VALUE_INVALID) {
+				t.Fatalf("checkInfo = %v, want VALUE_INVALID", err)
+			}
+		})
+	}
+}
+
+func TestStampInfo(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	stored := now.Add(-48 * time.Hour)
+	given := now.Add(-time.Hour)
+
+	for _, tc := range []struct {
+		name    string
+		given   time.Time
+		stored  time.Time
+		created time.Time
+	}{
+		{"New", time.Time{}, time.Time{}, now},
+		{"NewWithCreatedAt", given, time.Time{}, given},
+		{"Stored", given, stored, stored},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			info := RuleInfo{ID: "r", CreatedAt: tc.given}
+			stampInfo(&info, tc.stored, now)
+			if !info.CreatedAt.Equal(tc.created) || !info.UpdatedAt.Equal(now) {
+				t.Fatalf("created %v updated %v, want %v and %v", info.CreatedAt, info.UpdatedAt, tc.created, now)
+			}
+		})
+	}
+}
+
+func TestInfoQueryFilter(t *testing.T) {
+	ruleType := 1
+	active := RuleInfoStatus.ACTIVE
+	for _, tc := range []struct {
+		name string
+		q    InfoQuery
+		ids  []string
+	}{
+		{"All", InfoQuery{}, []string{"legacy", "loyalty", "promo_100%", "weekend"}},
+		{"TextInID", InfoQuery{Text: "PROMO"}, []string{"promo_100%"}},
+		{"TextInName", InfoQuery{Text: "rates"}, []string{"legacy", "weekend"}},
+		{"TextInDescription", InfoQuery{Text: "members"}, []string{"loyalty"}},
+		{"Owner", InfoQuery{Owner: "crm"}, []string{"loyalty", "promo_100%"}},
+		{"EveryTag", InfoQuery{Tags: []string{"rate", "member"}}, []string{"loyalty"}},
+		{"RuleType", InfoQuery{RuleType: &ruleType}, []string{"legacy", "weekend"}},
+		{"Status", InfoQuery{Status: &active}, []string{"loyalty", "weekend"}},
+		{"Combined", InfoQuery{Owner: "revenue", Status: &active}, []string{"weekend"}},
+		{"Offset", InfoQuery{Offset: 3}, []string{"weekend"}},
+		{"Limit", InfoQuery{Limit: 2}, []string{"legacy", "loyalty"}},
+		{"Page", InfoQuery{Offset: 1, Limit: 2}, []string{"loyalty", "promo_100%"}},
+		{"PastEnd", InfoQuery{Offset: 4}, []string{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			infos := tc.q.filter(sampleInfos())
+			ids := make([]string, len(infos))
+			for i, info := range infos {
+				ids[i] = info.ID
+			}
+			if !reflect.DeepEqual(ids, tc.ids) {
+				t.Fatalf("%v, want %v", ids, tc.ids)
+			}
+		})
+	}
+}
+
+func TestInfoQueryWhere(t *testing.T) {
+	ruleType := 1
+	status := RuleInfoStatus.DRAFT
+	where, args := InfoQuery{
+		Text:     "50%_off",
+		Owner:    "crm",
+		Tags:     []string{`say "hi"`},
+		RuleType: &ruleType,
+		Status:   &status,
+	}.where(PostgresDialect{}.Placeholder)
+
+	for _, clause := range []string{
+		"LOWER(id) LIKE $1 ESCAPE '!'",
+		"LOWER(description) LIKE $3 ESCAPE '!'",
+		"owner = $4",
+		"tags LIKE $5 ESCAPE '!'",
+		"rule_type = $6",
+		"status = $7",
+	} {
+		if !strings.Contains(where, clause) {
+			t.Errorf("where = %s, want %q in it", where, clause)
+		}
+	}
+	want := []interface{}{"%50!%!_off%", "%50!%!_off%", "%50!%!_off%", "crm", `%"say \"hi\""%`, 1, 0}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("args = %v, want %v", args, want)
+	}
+
+	if where, args := (InfoQuery{Offset: 2, Limit: 1}).where(SQLiteDialect{}.Placeholder); where != "" || len(args) != 0 {
+		t.Errorf("paging alone = %q %v, want no condition", where, args)
+	}
+}
+
+func TestRuleInfoDBRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		tags []string
+		db   string
+	}{
+		{"NoTags", nil, "[]"},
+		{"Tags", []string{"rate", `a "quoted" tag`}, `["rate","a \"quoted\" tag"]`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			info := RuleInfo{
+				ID:        "r",
+				Name:      "Rates",
+				Owner:     "revenue",
+				Tags:      tc.tags,
+				RuleType:  2,
+				Status:    RuleInfoStatus.ARCHIVED,
+				CreatedAt: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+				UpdatedAt: time.Date(2024, 6, 2, 0, 0, 0, 0, time.UTC),
+			}
+			infodb, err := info.MakeDBObject()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if infodb.Tags != tc.db {
+				t.Fatalf("tags stored as %s, want %s", infodb.Tags, tc.db)
+			}
+			back, err := infodb.MakeObject()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tc.tags) == 0 && len(back.Tags) == 0 {
+				back.Tags = tc.tags
+			}
+			if !reflect.DeepEqual(*back, info) {
+				t.Fatalf("read back as %+v, want %+v", *back, info)
+			}
+		})
+	}
+
+	if _, err := (&RuleInfoDB{ID: "r", Tags: "not json"}).MakeObject(); err == nil {
+		t.Fatal("MakeObject of tags that are not a JSON array succeeded, want an error")
+	}
+}
@@ -0,0 +1,58 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// document the engine's view of rqr: structs and maps such as map[string]interface{} are used as given,
+// json.RawMessage is decoded into a map, and a *json.RawMessage also gets the modified document written back
+type document struct {
+	rqr interface{}
+	raw *json.RawMessage
+}
+
+func openDocument(rqr interface{}) (*document, error) {
+	switch doc := rqr.(type) {
+	case json.RawMessage:
+		m, err := decodeDocument(doc)
+		if err != nil {
+			return nil, err
+		}
+		return &document{rqr: m}, nil
+	case *json.RawMessage:
+		m, err := decodeDocument(*doc)
+		if err != nil {
+			return nil, err
+		}
+		return &document{rqr: m, raw: doc}, nil
+	}
+	return &document{rqr: rqr}, nil
+}
+
+// decodeDocument numbers stay json.Number so large ints survive untouched
+func decodeDocument(raw json.RawMessage) (map[string]interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var m map[string]interface{}
+	if err := dec.Decode(&m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// close write the document back to the *json.RawMessage it came from
+func (d *document) close() error {
+	if d.raw == nil {
+		return nil
+	}
+	b, err := json.Marshal(d.rqr)
+	if err != nil {
+		return err
+	}
+	*d.raw = b
+	return nil
+}
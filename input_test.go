@@ -0,0 +1,98 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func documentSettings() []RuleSetting {
+	return []RuleSetting{setting("doc", 1, []Condition{
+		intCondition("Adults", RuleConditionCompare.MORE_EQUAL, "2"),
+		{Type: RuleConditionType.STRING, LeftSide: `Attributes["channel"]`, LeftType: ConditionSideType.FIELD, RightSide: "web", RightType: ConditionSideType.VALUE},
+		{Type: RuleConditionType.DATE, LeftSide: "CheckIn", LeftType: ConditionSideType.FIELD, Compare: RuleConditionCompare.MORE, RightSide: "1000", RightType: ConditionSideType.VALUE},
+		intCondition("Guest.Nights", RuleConditionCompare.EQUAL, "3"),
+	},
+		intModifer(RuleOperand.ADD, "Price", "10"),
+		Modifer{Operand: RuleOperand.SET, DataType: ModiferDataType.STRING, LeftSide: "promo", LeftType: ModiferSideType.VALUE, TargetField: "Tag"},
+		Modifer{Operand: RuleOperand.MLT, DataType: ModiferDataType.DECIMAL, LeftSide: "Rate", LeftType: ModiferSideType.FIELD, RightSide: "1.5", RightType: ModiferSideType.VALUE, TargetField: "Guest.Rate"},
+	)}
+}
+
+func TestApplySettingsMap(t *testing.T) {
+	m := map[string]interface{}{
+		"Adults":     3,
+		"Price":      int64(1),
+		"Rate":       "2.1",
+		"Attributes": map[string]string{"channel": "web"},
+		"CheckIn":    500000,
+		"Guest":      map[string]interface{}{"Nights": 3.0},
+	}
+	result, err := NewEngine(stubSupply{}).ApplySettings(m, documentSettings())
+	if err != nil || !result {
+		t.Fatalf("ApplySettings = %v, %v", result, err)
+	}
+	if m["Price"] != int64(11) || m["Tag"] != "promo" {
+		t.Errorf("Price %v, Tag %v, want 11, promo", m["Price"], m["Tag"])
+	}
+	if rate := m["Guest"].(map[string]interface{})["Rate"]; rate != json.Number("3.15") {
+		t.Errorf("Guest.Rate = %#v, want json.Number 3.15", rate)
+	}
+}
+
+func TestApplySettingsRawJSON(t *testing.T) {
+	raw := json.RawMessage(`{"Adults": 2, "Price": 9007199254740993, "Rate": 10.2, "Attributes": {"channel": "web"}, "CheckIn": "2024-05-01T00:00:00Z", "Guest": {"Nights": 3}}`)
+	re := NewEngine(stubSupply{})
+
+	// by value the document is evaluated but not written back
+	kept := append(json.RawMessage(nil), raw...)
+	if result, err := re.ApplySettings(kept, documentSettings()); err != nil || !result {
+		t.Fatalf("ApplySettings = %v, %v", result, err)
+	}
+	if string(kept) != string(raw) {
+		t.Errorf("a json.RawMessage was changed to %s", kept)
+	}
+
+	result, err := re.ApplySettings(&raw, documentSettings())
+	if err != nil || !result {
+		t.Fatalf("ApplySettings = %v, %v", result, err)
+	}
+	var doc struct {
+		Price int64
+		Tag   string
+		Guest struct {
+			Nights int
+			Rate   json.Number
+		}
+	}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		t.Fatal(err)
+	}
+	// past 2^53, the price would not survive a float64
+	if doc.Price != 9007199254741003 || doc.Tag != "promo" || doc.Guest.Nights != 3 || doc.Guest.Rate != "15.3" {
+		t.Errorf("written back as %s", raw)
+	}
+}
+
+func TestApplySettingsDocumentErrors(t *testing.T) {
+	re := NewEngine(stubSupply{})
+	raw := json.RawMessage(`{"Adults": `)
+	if _, err := re.ApplySettings(&raw, documentSettings()); err == nil {
+		t.Error("ApplySettings of broken JSON succeeded")
+	}
+	m := map[string]interface{}{"Adults": "two"}
+	if _, err := re.CheckRuleCondition(m, intCondition("Adults", RuleConditionCompare.EQUAL, "2")); !errors.Is(err, RuleSettingError.VALUE_INVALID) {
+		t.Errorf("CheckRuleCondition of a word = %v, want VALUE_INVALID", err)
+	}
+	m = map[string]interface{}{"Adults": 2.5}
+	if _, err := re.CheckRuleCondition(m, intCondition("Adults", RuleConditionCompare.EQUAL, "2")); !errors.Is(err, RuleSettingError.VALUE_INVALID) {
+		t.Errorf("CheckRuleCondition of a fraction = %v, want VALUE_INVALID", err)
+	}
+	m = map[string]interface{}{"Adults": []int{2}}
+	if _, err := re.CheckRuleCondition(m, intCondition("Adults", RuleConditionCompare.EQUAL, "2")); !errors.Is(err, RuleSettingError.FIELD_TYPE_MISMATCH) {
+		t.Errorf("CheckRuleCondition of a list = %v, want FIELD_TYPE_MISMATCH", err)
+	}
+}
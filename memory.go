@@ -0,0 +1,254 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// MemorySupply a Supply kept in memory and keyed by RuleID, safe for concurrent use.
+// Settings are copied on the way in and out so callers never share them with the store
+type MemorySupply struct {
+	mu    sync.RWMutex
+	rules map[string][]RuleSetting
+	ids   map[string]string // setting ID to RuleID
+	infos map[string]RuleInfo
+}
+
+func NewMemorySupply() *MemorySupply {
+	return &MemorySupply{
+		rules: make(map[string][]RuleSetting),
+		ids:   make(map[string]string),
+		infos: make(map[string]RuleInfo),
+	}
+}
+
+// SaveRuleSettings insert rs or replace the settings with the same ID, settings without an ID get one
+func (ms *MemorySupply) SaveRuleSettings(rs []RuleSetting) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	touched := make(map[string]bool)
+	for _, setting := range rs {
+		setting = setting.clone()
+		if setting.ID == "" {
+			setting.ID = uuid.NewV4().String()
+		}
+		if ruleID, ok := ms.ids[setting.ID]; ok {
+			ms.remove(ruleID, setting.ID)
+		}
+		ms.rules[setting.RuleID] = append(ms.rules[setting.RuleID], setting)
+		ms.ids[setting.ID] = setting.RuleID
+		touched[setting.RuleID] = true
+	}
+	for ruleID := range touched {
+		settings := ms.rules[ruleID]
+		sort.SliceStable(settings, func(i, j int) bool {
+			return settings[i].Sequence < settings[j].Sequence
+		})
+	}
+	return nil
+}
+
+func (ms *MemorySupply) remove(ruleID, id string) {
+	settings := ms.rules[ruleID]
+	for i := range settings {
+		if settings[i].ID == id {
+			ms.rules[ruleID] = append(settings[:i:i], settings[i+1:]...)
+			break
+		}
+	}
+	if len(ms.rules[ruleID]) == 0 {
+		delete(ms.rules, ruleID)
+	}
+	delete(ms.ids, id)
+}
+
+// ruleOf the RuleID holding setting id
+func (ms *MemorySupply) ruleOf(id string) (string, bool) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	ruleID, ok := ms.ids[id]
+	return ruleID, ok
+}
+
+// replace swap the whole of rule set ruleID for rs, an empty rs drops it
+func (ms *MemorySupply) replace(ruleID string, rs []RuleSetting) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	for _, setting := range ms.rules[ruleID] {
+		delete(ms.ids, setting.ID)
+	}
+	delete(ms.rules, ruleID)
+	for _, setting := range rs {
+		if other, ok := ms.ids[setting.ID]; ok {
+			ms.remove(other, setting.ID)
+		}
+		ms.rules[ruleID] = append(ms.rules[ruleID], setting.clone())
+		ms.ids[setting.ID] = ruleID
+	}
+	settings := ms.rules[ruleID]
+	sort.SliceStable(settings, func(i, j int) bool {
+		return settings[i].Sequence < settings[j].Sequence
+	})
+}
+
+// FetchRuleSettings the settings of rule set id with Sequence >= start, in order
+func (ms *MemorySupply) FetchRuleSettings(id string, start int) ([]RuleSetting, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	settings, ok := ms.rules[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", RuleSettingError.RULE_NOT_FOUND, id)
+	}
+	rs := make([]RuleSetting, 0, len(settings))
+	for _, setting := range settings {
+		if setting.Sequence >= int64(start) {
+			rs = append(rs, setting.clone())
+		}
+	}
+	return rs, nil
+}
+
+// SaveRuleSettingsContext SaveRuleSettings bound to ctx
+func (ms *MemorySupply) SaveRuleSettingsContext(ctx context.Context, rs []RuleSetting) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return ms.SaveRuleSettings(rs)
+}
+
+// FetchRuleSettingsContext FetchRuleSettings bound to ctx
+func (ms *MemorySupply) FetchRuleSettingsContext(ctx context.Context, id string, start int) ([]RuleSetting, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ms.FetchRuleSettings(id, start)
+}
+
+// SaveRuleInfo insert info or replace the one with the same ID, keeping its CreatedAt
+func (ms *MemorySupply) SaveRuleInfo(info RuleInfo) error {
+	if err := checkInfo(info); err != nil {
+		return err
+	}
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	info = info.clone()
+	stampInfo(&info, ms.infos[info.ID].CreatedAt, time.Now())
+	ms.infos[info.ID] = info
+	return nil
+}
+
+// FetchRuleInfo the RuleInfo of rule set id
+func (ms *MemorySupply) FetchRuleInfo(id string) (*RuleInfo, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	info, ok := ms.infos[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", RuleSettingError.RULE_NOT_FOUND, id)
+	}
+	info = info.clone()
+	return &info, nil
+}
+
+// ListRuleInfos every RuleInfo in ID order, paged
+func (ms *MemorySupply) ListRuleInfos(offset, limit int) ([]RuleInfo, error) {
+	return ms.SearchRuleInfos(InfoQuery{Offset: offset, Limit: limit})
+}
+
+// SearchRuleInfos the RuleInfo matching q in ID order
+func (ms *MemorySupply) SearchRuleInfos(q InfoQuery) ([]RuleInfo, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	infos := make([]RuleInfo, 0, len(ms.infos))
+	for _, info := range ms.infos {
+		infos = append(infos, info)
+	}
+	infos = q.filter(infos)
+	for i := range infos {
+		infos[i] = infos[i].clone()
+	}
+	return infos, nil
+}
+
+// Load save the JSON array of RuleSetting read from r
+func (ms *MemorySupply) Load(r io.Reader) error {
+	var rs []RuleSetting
+	if err := json.NewDecoder(r).Decode(&rs); err != nil {
+		return err
+	}
+	return ms.SaveRuleSettings(rs)
+}
+
+// LoadFiles Load every file in paths, such as test fixtures
+func (ms *MemorySupply) LoadFiles(paths ...string) error {
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		err = ms.Load(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// clone a copy of rs that shares no slices or pointers with it
+func (rs RuleSetting) clone() RuleSetting {
+	if rs.ValidFrom != nil {
+		t := *rs.ValidFrom
+		rs.ValidFrom = &t
+	}
+	if rs.ValidTo != nil {
+		t := *rs.ValidTo
+		rs.ValidTo = &t
+	}
+	rs.Rule.ConditionChain = append([]Condition(nil), rs.Rule.ConditionChain...)
+	if rs.Rule.ConditionTree != nil {
+		tree := rs.Rule.ConditionTree.clone()
+		rs.Rule.ConditionTree = &tree
+	}
+	rs.Rule.ModiferChain = append([]Modifer(nil), rs.Rule.ModiferChain...)
+	for i, rm := range rs.Rule.ModiferChain {
+		if rm.Rounding != nil {
+			r := *rm.Rounding
+			rs.Rule.ModiferChain[i].Rounding = &r
+		}
+	}
+	return rs
+}
+
+func (g ConditionGroup) clone() ConditionGroup {
+	g.Conditions = append([]Condition(nil), g.Conditions...)
+	groups := g.Groups
+	g.Groups = nil
+	for _, sub := range groups {
+		g.Groups = append(g.Groups, sub.clone())
+	}
+	return g
+}
+
+func (info RuleInfo) clone() RuleInfo {
+	if info.Tags != nil {
+		info.Tags = append([]string{}, info.Tags...)
+	}
+	return info
+}
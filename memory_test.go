@@ -0,0 +1,190 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	rule "github.com/007lock/go-turner"
+	"github.com/007lock/go-turner/supplytest"
+)
+
+func TestMemorySupplyConformance(t *testing.T) {
+	supplytest.Run(t, func(t *testing.T) rule.Supply {
+		return rule.NewMemorySupply()
+	})
+}
+
+func TestMemorySupplyMovesSettingBetweenRuleSets(t *testing.T) {
+	ms := rule.NewMemorySupply()
+	if err := ms.SaveRuleSettings([]rule.RuleSetting{
+		{ID: "a", RuleID: "r", Sequence: 1},
+		{ID: "b", RuleID: "q", Sequence: 1},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ms.SaveRuleSettings([]rule.RuleSetting{{ID: "b", RuleID: "r", Sequence: 2}}); err != nil {
+		t.Fatal(err)
+	}
+	rs, err := ms.FetchRuleSettings("r", 0)
+	if err != nil || len(rs) != 2 || rs[0].ID != "a" || rs[1].ID != "b" {
+		t.Fatalf("FetchRuleSettings = %+v, %v, want a and b in r", rs, err)
+	}
+	if _, err := ms.FetchRuleSettings("q", 0); !errors.Is(err, rule.RuleSettingError.RULE_NOT_FOUND) {
+		t.Fatalf("FetchRuleSettings of the emptied q = %v, want RULE_NOT_FOUND", err)
+	}
+}
+
+func TestMemorySupplyLoad(t *testing.T) {
+	ms := rule.NewMemorySupply()
+	err := ms.Load(strings.NewReader(`[
+		{"id":"a","rule_id":"r","sequence":5},
+		{"id":"b","rule_id":"r","sequence":1},
+		{"id":"c","rule_id":"q","sequence":1}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs, err := ms.FetchRuleSettings("r", 0)
+	if err != nil || len(rs) != 2 || rs[0].ID != "b" || rs[1].ID != "a" {
+		t.Fatalf("FetchRuleSettings = %+v, %v, want b then a", rs, err)
+	}
+	if err := ms.Load(strings.NewReader(`{"id":"a"}`)); err == nil {
+		t.Fatal("Load of an object instead of an array succeeded, want an error")
+	}
+}
+
+func TestMemorySupplyLoadFiles(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.json")
+	second := filepath.Join(dir, "second.json")
+	broken := filepath.Join(dir, "broken.json")
+	for path, content := range map[string]string{
+		first:  `[{"id":"a","rule_id":"r","sequence":1}]`,
+		second: `[{"id":"a","rule_id":"r","sequence":3},{"id":"b","rule_id":"r","sequence":2}]`,
+		broken: `[{"id":`,
+	} {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ms := rule.NewMemorySupply()
+	if err := ms.LoadFiles(first, second); err != nil {
+		t.Fatal(err)
+	}
+	rs, err := ms.FetchRuleSettings("r", 0)
+	if err != nil || len(rs) != 2 || rs[0].ID != "b" || rs[1].ID != "a" || rs[1].Sequence != 3 {
+		t.Fatalf("FetchRuleSettings = %+v, %v, want a replaced by the later file", rs, err)
+	}
+
+	err = ms.LoadFiles(broken)
+	if err == nil || !strings.Contains(err.Error(), broken) {
+		t.Fatalf("LoadFiles = %v, want an error naming %s", err, broken)
+	}
+	if err := ms.LoadFiles(filepath.Join(dir, "missing.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("LoadFiles of a missing file = %v, want os.ErrNotExist", err)
+	}
+}
+
+func TestMemorySupplySavedCopiesIsolated(t *testing.T) {
+	ms := rule.NewMemorySupply()
+	rs := []rule.RuleSetting{{
+		ID:     "a",
+		RuleID: "r",
+		Rule: rule.Rule{
+			ConditionChain: []rule.Condition{{LeftSide: "Adults"}},
+			ModiferChain:   []rule.Modifer{{LeftSide: "Price", Rounding: &rule.Rounding{Places: 2}}},
+		},
+	}}
+	if err := ms.SaveRuleSettings(rs); err != nil {
+		t.Fatal(err)
+	}
+	rs[0].Rule.ConditionChain[0].LeftSide = "Children"
+	rs[0].Rule.ModiferChain[0].Rounding.Places = 0
+
+	saved, err := ms.FetchRuleSettings("r", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved[0].Rule.ConditionChain[0].LeftSide != "Adults" || saved[0].Rule.ModiferChain[0].Rounding.Places != 2 {
+		t.Fatalf("changing saved settings changed the supply: %+v", saved[0].Rule)
+	}
+}
+
+func TestMemorySupplyRuleInfo(t *testing.T) {
+	ms := rule.NewMemorySupply()
+	if err := ms.SaveRuleInfo(rule.RuleInfo{Name: "no id"}); !errors.Is(err, rule.RuleSettingError.VALUE_INVALID) {
+		t.Fatalf("SaveRuleInfo without an id = %v, want VALUE_INVALID", err)
+	}
+	for _, info := range []rule.RuleInfo{
+		{ID: "weekend", Name: "Weekend rates", Owner: "revenue", Tags: []string{"rate"}},
+		{ID: "loyalty", Name: "Loyalty discount", Owner: "crm", Tags: []string{"rate", "member"}},
+	} {
+		if err := ms.SaveRuleInfo(info); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	info, err := ms.FetchRuleInfo("weekend")
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := info.CreatedAt
+	info.Tags[0] = "changed"
+	info.Name = "Weekend"
+	if err := ms.SaveRuleInfo(*info); err != nil {
+		t.Fatal(err)
+	}
+	info, err = ms.FetchRuleInfo("weekend")
+	if err != nil || info.Name != "Weekend" || !info.CreatedAt.Equal(created) {
+		t.Fatalf("FetchRuleInfo = %+v, %v, want the name replaced and CreatedAt kept", info, err)
+	}
+
+	infos, err := ms.SearchRuleInfos(rule.InfoQuery{Tags: []string{"member"}})
+	if err != nil || len(infos) != 1 || infos[0].ID != "loyalty" {
+		t.Fatalf("SearchRuleInfos = %+v, %v, want loyalty", infos, err)
+	}
+	infos, err = ms.ListRuleInfos(1, 1)
+	if err != nil || len(infos) != 1 || infos[0].ID != "weekend" {
+		t.Fatalf("ListRuleInfos = %+v, %v, want weekend on the second page", infos, err)
+	}
+	if _, err := ms.FetchRuleInfo("missing"); !errors.Is(err, rule.RuleSettingError.RULE_NOT_FOUND) {
+		t.Fatalf("FetchRuleInfo = %v, want RULE_NOT_FOUND", err)
+	}
+}
+
+func TestMemorySupplyConcurrent(t *testing.T) {
+	ms := rule.NewMemorySupply()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				id := fmt.Sprintf("s-%d", j%10)
+				if err := ms.SaveRuleSettings([]rule.RuleSetting{{ID: id, RuleID: "r", Sequence: int64(i)}}); err != nil {
+					t.Error(err)
+					return
+				}
+				if _, err := ms.FetchRuleSettings("r", 0); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	rs, err := ms.FetchRuleSettings("r", 0)
+	if err != nil || len(rs) != 10 {
+		t.Fatalf("FetchRuleSettings = %d settings, %v, want the 10 distinct ones", len(rs), err)
+	}
+}
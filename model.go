@@ -5,17 +5,19 @@ package rule
 
 import (
 	"encoding/json"
+	"time"
 
+	"github.com/CloudHMS/hms.loyalty.core/pkg/util"
 	uuid "github.com/satori/go.uuid"
 )
 
 type Condition struct {
-	Type      int    `json:"type"`
-	LeftSide  string `json:"left_side"`
-	LeftType  int    `json:"left_type"`
-	Compare   int    `json:"compare"`
-	RightSide string `json:"right_side"`
-	RightType int    `json:"right_type"`
+	Type      ConditionTypeCode `json:"type"`
+	LeftSide  string            `json:"left_side"`
+	LeftType  SideCode          `json:"left_type"`
+	Compare   CompareCode       `json:"compare"`
+	RightSide string            `json:"right_side"`
+	RightType SideCode          `json:"right_type"`
 }
 
 type JSONmap struct {
@@ -25,20 +27,65 @@ type JSONmap struct {
 }
 
 type Modifer struct {
-	Sequence    int    `json:"sequence"`
-	Operand     int    `json:"operand"`
-	DataType    int    `json:"data_type"`
-	LeftSide    string `json:"left_side"`
-	LeftType    int    `json:"left_type"`
-	RightSide   string `json:"right_side"`
-	RightType   int    `json:"right_type"`
-	TargetField string `json:"target_field"`
+	Sequence    int          `json:"sequence"`
+	Operand     OperandCode  `json:"operand"`
+	DataType    DataTypeCode `json:"data_type"`
+	LeftSide    string       `json:"left_side"`
+	LeftType    SideCode     `json:"left_type"`
+	RightSide   string       `json:"right_side"`
+	RightType   SideCode     `json:"right_type"`
+	TargetField string       `json:"target_field"`
+	Rounding    *Rounding    `json:"rounding,omitempty"`
 }
 
+// Rounding applied to DIV and percentage results, Places -3 rounds to the nearest 1000
+type Rounding struct {
+	Mode   RoundingModeCode `json:"mode"`
+	Places int32            `json:"places"`
+}
+
+// ModiferComplex a COMPLEX side. + and - add or take the smaller of Flat and Percent percent of the left side,
+// all of the percentage when Uncapped, which a JSON object without "flat" reads as
 type ModiferComplex struct {
 	Flat      int64     `json:"flat"`
 	Percent   int64     `json:"percentage"`
 	Selection []JSONmap `json:"select"`
+	Uncapped  bool      `json:"-"`
+}
+
+func (rm ModiferComplex) MarshalJSON() ([]byte, error) {
+	type plain ModiferComplex
+	if !rm.Uncapped {
+		return json.Marshal(plain(rm))
+	}
+	return json.Marshal(struct {
+		Percent   int64     `json:"percentage"`
+		Selection []JSONmap `json:"select"`
+	}{rm.Percent, rm.Selection})
+}
+
+func (rm *ModiferComplex) UnmarshalJSON(b []byte) error {
+	type plain ModiferComplex
+	v := struct {
+		*plain
+		Flat *int64 `json:"flat"`
+	}{plain: (*plain)(rm)}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	rm.Uncapped = v.Flat == nil
+	if v.Flat != nil {
+		rm.Flat = *v.Flat
+	}
+	return nil
+}
+
+// capped the amount + and - add or take for pct percent of the left side
+func (rm *ModiferComplex) capped(pct int64) int64 {
+	if rm.Uncapped {
+		return pct
+	}
+	return util.MinInt64(rm.Flat, pct)
 }
 
 func (rm *ModiferComplex) Select(key string) *JSONmap {
@@ -50,29 +97,119 @@ func (rm *ModiferComplex) Select(key string) *JSONmap {
 	return nil
 }
 
+// ConditionGroup nestable conditions combined by Mode, see ConditionGroupMode
+type ConditionGroup struct {
+	Mode       GroupModeCode    `json:"mode"`
+	Conditions []Condition      `json:"conditions,omitempty"`
+	Groups     []ConditionGroup `json:"groups,omitempty"`
+}
+
+// Rule ConditionChain must all pass, ConditionTree when set must pass as well
 type Rule struct {
-	ConditionChain []Condition `json:"condition_chain"`
-	ModiferChain   []Modifer   `json:"rate_modifer"`
+	ConditionChain []Condition     `json:"condition_chain"`
+	ConditionTree  *ConditionGroup `json:"condition_tree,omitempty"`
+	ModiferChain   []Modifer       `json:"rate_modifer"`
 }
 
 type RuleSetting struct {
-	ID          string `json:"id" gorm:"primary_key"`
-	Enable      bool   `json:"enable"`
-	BreakOnFail bool   `json:"break_on_fail"`
-	Sequence    int64  `json:"sequence"`
-	RuleID      string `json:"rule_id"`
-	RuleType    int    `json:"rule_type"` // for filtering
-	Rule        Rule   `json:"rule"`
+	ID          string     `json:"id" gorm:"primary_key"`
+	Enable      bool       `json:"enable"`
+	BreakOnFail bool       `json:"break_on_fail"`
+	Sequence    int64      `json:"sequence"`
+	RuleID      string     `json:"rule_id"`
+	RuleType    int        `json:"rule_type"` // for filtering
+	ValidFrom   *time.Time `json:"valid_from,omitempty"`
+	ValidTo     *time.Time `json:"valid_to,omitempty"`
+	Rule        Rule       `json:"rule"`
+}
+
+// Active whether the setting is live at t, ValidFrom is inclusive, ValidTo exclusive and either may be left open
+func (rs *RuleSetting) Active(t time.Time) bool {
+	return rs.activity(t) == SettingSkip.NONE
+}
+
+// activity why the setting is not live at t, SettingSkip.NONE when it is
+func (rs *RuleSetting) activity(t time.Time) int {
+	if rs.ValidFrom != nil && t.Before(*rs.ValidFrom) {
+		return SettingSkip.NOT_YET_VALID
+	}
+	if rs.ValidTo != nil && !t.Before(*rs.ValidTo) {
+		return SettingSkip.EXPIRED
+	}
+	return SettingSkip.NONE
 }
 
 type RuleSettingDB struct {
-	ID          string `json:"id" gorm:"primary_key"`
-	Enable      bool   `json:"enable"`
-	BreakOnFail bool   `json:"break_on_fail"`
-	Sequence    int64  `json:"sequence"`
-	RuleID      string `json:"rule_id"`
-	RuleType    int    `json:"rule_type"`
-	Rule        string `json:"rule"`
+	ID          string     `json:"id" gorm:"primary_key"`
+	Enable      bool       `json:"enable"`
+	BreakOnFail bool       `json:"break_on_fail"`
+	Sequence    int64      `json:"sequence" gorm:"index:idx_rule_settings_rule_sequence"`
+	RuleID      string     `json:"rule_id" gorm:"index:idx_rule_settings_rule_sequence"`
+	RuleType    int        `json:"rule_type"`
+	ValidFrom   *time.Time `json:"valid_from"`
+	ValidTo     *time.Time `json:"valid_to"`
+	Rule        string     `json:"rule" gorm:"type:text"`
+}
+
+// RuleInfo what a rule set is for and who looks after it, ID is its RuleID
+type RuleInfo struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Owner       string    `json:"owner"`
+	Tags        []string  `json:"tags"`
+	RuleType    int       `json:"rule_type"`
+	Status      int       `json:"status"` // see RuleInfoStatus
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// RuleInfoDB a row of DB_TABLE_INFO describing one rule set, ID is its RuleID
+type RuleInfoDB struct {
+	ID          string    `json:"id" gorm:"primary_key"`
+	Name        string    `json:"name"`
+	Description string    `json:"description" gorm:"type:text"`
+	Owner       string    `json:"owner" gorm:"index"`
+	Tags        string    `json:"tags" gorm:"type:text"` // JSON array
+	RuleType    int       `json:"rule_type"`
+	Status      int       `json:"status"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// RuleVersion one immutable snapshot of a rule set, made by every save to a VersionedSupply
+type RuleVersion struct {
+	RuleID    string    `json:"rule_id"`
+	Version   int       `json:"version"` // from 1
+	Published bool      `json:"published"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RuleVersionDB a row of DB_TABLE_VERSION, Settings is the JSON array of the version's RuleSetting
+type RuleVersionDB struct {
+	RuleID    string    `json:"rule_id" gorm:"primary_key"`
+	Version   int       `json:"version" gorm:"primary_key;auto_increment:false"`
+	Settings  string    `json:"settings" gorm:"type:text"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RulePublicationDB a row of DB_TABLE_PUBLICATION, the latest row of a RuleID is its published version
+type RulePublicationDB struct {
+	ID          uint      `json:"id" gorm:"primary_key"`
+	RuleID      string    `json:"rule_id" gorm:"index"`
+	Version     int       `json:"version"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// InfoQuery which RuleInfo to search for, zero fields match everything
+type InfoQuery struct {
+	Text     string   `json:"text"` // case-insensitive, in ID, Name or Description
+	Owner    string   `json:"owner"`
+	Tags     []string `json:"tags"` // every one of them
+	RuleType *int     `json:"rule_type,omitempty"`
+	Status   *int     `json:"status,omitempty"`
+	Offset   int      `json:"offset"`
+	Limit    int      `json:"limit"` // zero or less for no limit
 }
 
 func (rs *RuleSetting) MakeDBObject() (*RuleSettingDB, error) {
@@ -90,6 +227,8 @@ func (rs *RuleSetting) MakeDBObject() (*RuleSettingDB, error) {
 		Sequence:    rs.Sequence,
 		RuleID:      rs.RuleID,
 		RuleType:    rs.RuleType,
+		ValidFrom:   rs.ValidFrom,
+		ValidTo:     rs.ValidTo,
 		Rule:        string(rr),
 	}
 
@@ -109,8 +248,57 @@ func (rs *RuleSettingDB) MakeObject() (*RuleSetting, error) {
 		Sequence:    rs.Sequence,
 		RuleID:      rs.RuleID,
 		RuleType:    rs.RuleType,
+		ValidFrom:   rs.ValidFrom,
+		ValidTo:     rs.ValidTo,
 		Rule:        *rr,
 	}
 
 	return rsdb, nil
 }
+
+func (ri *RuleInfo) MakeDBObject() (*RuleInfoDB, error) {
+	tags := ri.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	tt, err := json.Marshal(tags)
+	if err != nil {
+		return nil, err
+	}
+	ridb := &RuleInfoDB{
+		ID:          ri.ID,
+		Name:        ri.Name,
+		Description: ri.Description,
+		Owner:       ri.Owner,
+		Tags:        string(tt),
+		RuleType:    ri.RuleType,
+		Status:      ri.Status,
+		CreatedAt:   ri.CreatedAt,
+		UpdatedAt:   ri.UpdatedAt,
+	}
+
+	return ridb, nil
+}
+
+func (ri *RuleInfoDB) MakeObject() (*RuleInfo, error) {
+	var tags []string
+	if ri.Tags != "" {
+		err := json.Unmarshal([]byte(ri.Tags), &tags)
+		if err != nil {
+			return nil, err
+		}
+	}
+	info := &RuleInfo{
+		ID:          ri.ID,
+		Name:        ri.Name,
+		Description: ri.Description,
+		Owner:       ri.Owner,
+		Tags:        tags,
+		RuleType:    ri.RuleType,
+		Status:      ri.Status,
+		CreatedAt:   ri.CreatedAt,
+		UpdatedAt:   ri.UpdatedAt,
+	}
+
+	return info, nil
+}
@@ -0,0 +1,64 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule
+
+import "time"
+
+// Option configure the engine returned by NewEngine
+type Option func(re *ruleEngine)
+
+// WithMaxJumpDepth limit the JMP and JRT hops of one evaluation, zero or less removes the limit
+func WithMaxJumpDepth(depth int) Option {
+	return func(re *ruleEngine) {
+		re.maxJumpDepth = depth
+	}
+}
+
+// WithRounding the Rounding used by modifers that do not carry their own
+func WithRounding(r Rounding) Option {
+	return func(re *ruleEngine) {
+		re.defaultRounding = &r
+	}
+}
+
+// WithConditionStrategy how a setting's ConditionChain is checked, one of ConditionStrategy.
+// Either way the first false or failing condition in chain order decides the result
+func WithConditionStrategy(strategy int) Option {
+	return func(re *ruleEngine) {
+		re.conditionStrategy = strategy
+	}
+}
+
+// WithParallelism bound the goroutines checking one chain under ConditionStrategy.PARALLEL,
+// zero or less uses GOMAXPROCS
+func WithParallelism(limit int) Option {
+	return func(re *ruleEngine) {
+		re.parallelism = limit
+	}
+}
+
+// WithClock the time settings are checked against for their ValidFrom and ValidTo, time.Now by default
+func WithClock(clock func() time.Time) Option {
+	return func(re *ruleEngine) {
+		re.clock = clock
+	}
+}
+
+// WithIncludeDisabled evaluate settings whose Enable is false as well, for previews and what-if runs
+func WithIncludeDisabled(include bool) Option {
+	return func(re *ruleEngine) {
+		re.includeDisabled = include
+	}
+}
+
+// WithVersion fetch version of rule set ruleID instead of its published one when a JMP or JRT moves into it,
+// the Supply given to NewEngine must be a VersionedSupply
+func WithVersion(ruleID string, version int) Option {
+	return func(re *ruleEngine) {
+		if re.versions == nil {
+			re.versions = make(map[string]int)
+		}
+		re.versions[ruleID] = version
+	}
+}
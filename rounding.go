@@ -0,0 +1,96 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// rounding the Rounding a modifer asked for, falling back to the engine default
+func (re *ruleEngine) rounding(rm Modifer) *Rounding {
+	if rm.Rounding != nil {
+		return rm.Rounding
+	}
+	return re.defaultRounding
+}
+
+// minIntPlaces the most negative Places an int64 result can be rounded to, 10^19 is out of its range
+const minIntPlaces = -18
+
+// divide num by den and round the exact quotient, a nil Rounding truncates like integer division
+func (r *Rounding) divide(num, den int64) (int64, error) {
+	if r == nil {
+		return num / den, nil
+	}
+	if r.Places < minIntPlaces {
+		return 0, fmt.Errorf("%w: rounding places %d", RuleSettingError.VALUE_INVALID, r.Places)
+	}
+	if den < 0 {
+		num, den = -num, -den
+	}
+	step := int64(1)
+	for p := r.Places; p < 0; p++ {
+		step *= 10
+	}
+
+	// num/den is q*step + rem + frac, rem and frac carrying the sign of num and |frac| < 1
+	q, rem, frac := num/den/step, num/den%step, num%den
+	if rem == 0 && frac == 0 {
+		return q * step, nil
+	}
+	sign := int64(1)
+	if num < 0 {
+		sign = -1
+	}
+	switch r.Mode {
+	case RoundingMode.HALF_UP, RoundingMode.HALF_EVEN:
+		// against half a step, 2*|rem+frac| against step
+		twice, whole := 2*abs64(rem), step
+		if step == 1 {
+			twice, whole = 2*abs64(frac), den
+		} else if frac != 0 {
+			twice++ // frac lifts it past 2*|rem| yet short of the next even number, whole is even
+		}
+		if twice > whole || twice == whole && (r.Mode == RoundingMode.HALF_UP || q%2 != 0) {
+			q += sign
+		}
+	case RoundingMode.FLOOR:
+		if sign < 0 {
+			q--
+		}
+	case RoundingMode.CEIL:
+		if sign > 0 {
+			q++
+		}
+	}
+	return q * step, nil
+}
+
+// decimal round d, a nil Rounding leaves it as it is. A quotient reaches here already rounded to
+// decimal.DivisionPrecision places, rounding to more places than that adds nothing
+func (r *Rounding) decimal(d decimal.Decimal) decimal.Decimal {
+	if r == nil {
+		return d
+	}
+	switch r.Mode {
+	case RoundingMode.HALF_UP:
+		return d.Round(r.Places)
+	case RoundingMode.HALF_EVEN:
+		return d.RoundBank(r.Places)
+	case RoundingMode.FLOOR:
+		return d.RoundFloor(r.Places)
+	case RoundingMode.CEIL:
+		return d.RoundCeil(r.Places)
+	}
+	return d.RoundDown(r.Places)
+}
+
+func abs64(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
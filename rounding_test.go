@@ -0,0 +1,183 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestRoundingDivide(t *testing.T) {
+	var (
+		truncate = &Rounding{Mode: RoundingMode.TRUNCATE}
+		halfUp   = &Rounding{Mode: RoundingMode.HALF_UP}
+		halfEven = &Rounding{Mode: RoundingMode.HALF_EVEN}
+		floor    = &Rounding{Mode: RoundingMode.FLOOR}
+		ceil     = &Rounding{Mode: RoundingMode.CEIL}
+	)
+	cases := []struct {
+		r        *Rounding
+		num, den int64
+		want     int64
+	}{
+		{nil, 7, 2, 3},
+		{nil, -7, 2, -3},
+		{truncate, -7, 2, -3},
+		{halfUp, 7, 2, 4},
+		{halfUp, -7, 2, -4},
+		{halfUp, 7, -2, -4},
+		{halfUp, -7, -2, 4},
+		{halfUp, 5, 3, 2},
+		{halfUp, 4, 3, 1},
+		{halfEven, 5, 2, 2},
+		{halfEven, 7, 2, 4},
+		{halfEven, -5, 2, -2},
+		{halfEven, -7, 2, -4},
+		{floor, 7, 2, 3},
+		{floor, -7, 2, -4},
+		{floor, 7, -2, -4},
+		{ceil, 7, 2, 4},
+		{ceil, -7, 2, -3},
+		{ceil, 6, 2, 3},
+		{halfUp, 0, 3, 0},
+		{halfUp, math.MaxInt64, 1, math.MaxInt64},
+		{floor, math.MinInt64 + 1, 1, math.MinInt64 + 1},
+		// positive places are whole units for int64
+		{&Rounding{Mode: RoundingMode.HALF_UP, Places: 2}, 7, 2, 4},
+	}
+	for _, c := range cases {
+		if got, err := c.r.divide(c.num, c.den); err != nil || got != c.want {
+			t.Errorf("%+v: %d / %d = %d, %v, want %d", c.r, c.num, c.den, got, err, c.want)
+		}
+	}
+}
+
+func TestRoundingDivideNegativePlaces(t *testing.T) {
+	rounding := func(mode RoundingModeCode, places int) *Rounding {
+		return &Rounding{Mode: mode, Places: int32(places)}
+	}
+	cases := []struct {
+		r        *Rounding
+		num, den int64
+		want     int64
+	}{
+		{rounding(RoundingMode.HALF_UP, -3), 1234567, 1, 1235000},
+		{rounding(RoundingMode.HALF_UP, -3), 1234499, 1, 1234000},
+		{rounding(RoundingMode.HALF_UP, -3), 1234500, 1, 1235000},
+		{rounding(RoundingMode.HALF_UP, -3), -1234500, 1, -1235000},
+		{rounding(RoundingMode.HALF_EVEN, -3), 1234500, 1, 1234000},
+		{rounding(RoundingMode.HALF_EVEN, -3), 1235500, 1, 1236000},
+		{rounding(RoundingMode.HALF_EVEN, -3), -1234500, 1, -1234000},
+		// the quotient's fraction breaks the tie
+		{rounding(RoundingMode.HALF_EVEN, -1), 2251, 50, 50},  // 45.02
+		{rounding(RoundingMode.HALF_EVEN, -1), 2249, 50, 40},  // 44.98
+		{rounding(RoundingMode.HALF_UP, -1), -2249, 50, -40},  // -44.98
+		{rounding(RoundingMode.HALF_UP, -2), 14999, 300, 0},   // 49.99
+		{rounding(RoundingMode.HALF_UP, -2), 15001, 300, 100}, // 50.003
+		{rounding(RoundingMode.CEIL, -3), 1234001, 1, 1235000},
+		{rounding(RoundingMode.CEIL, -3), 1000, 3, 1000}, // 333.3
+		{rounding(RoundingMode.CEIL, -3), -1999, 1, -1000},
+		{rounding(RoundingMode.FLOOR, -3), -1001, 1, -2000},
+		{rounding(RoundingMode.FLOOR, -3), -1, 3, -1000}, // -0.3
+		{rounding(RoundingMode.TRUNCATE, -3), -1234999, 1, -1234000},
+		{rounding(RoundingMode.TRUNCATE, -3), 999, 1, 0},
+		// percentages, a large denominator no longer overflows against the step
+		{rounding(RoundingMode.HALF_UP, -16), 15 * 1e17, 100, 2e16},
+		{rounding(RoundingMode.HALF_UP, -18), math.MaxInt64, 10, 1e18},
+		{rounding(RoundingMode.TRUNCATE, -18), math.MaxInt64, 1, 9e18},
+	}
+	for _, c := range cases {
+		if got, err := c.r.divide(c.num, c.den); err != nil || got != c.want {
+			t.Errorf("%+v: %d / %d = %d, %v, want %d", c.r, c.num, c.den, got, err, c.want)
+		}
+	}
+}
+
+func TestRoundingPlacesOutOfRange(t *testing.T) {
+	m := intModifer(RuleOperand.DIV, "Price", "3")
+	m.Rounding = &Rounding{Mode: RoundingMode.HALF_UP, Places: -19}
+	b := &booking{Price: 100}
+	if _, err := NewEngine(stubSupply{}).ApplyModifer(b, m); !errors.Is(err, RuleSettingError.VALUE_INVALID) {
+		t.Errorf("ApplyModifer = %v, want VALUE_INVALID", err)
+	}
+	if b.Price != 100 {
+		t.Errorf("Price = %d, want it left as it was", b.Price)
+	}
+	if _, err := (&Rounding{Places: -19}).divide(1, 1); !errors.Is(err, RuleSettingError.VALUE_INVALID) {
+		t.Errorf("divide = %v, want VALUE_INVALID", err)
+	}
+
+	var errs ValidationErrors
+	if !errors.As(Validate([]RuleSetting{setting("r", 1, nil, m)}, reflect.TypeOf(booking{})), &errs) ||
+		len(errs) != 1 || errs[0].Location != "rule.rate_modifer[0].rounding.places" || !errors.Is(errs[0].Err, RuleSettingError.VALUE_INVALID) {
+		t.Errorf("Validate = %v, want places out of range", errs)
+	}
+}
+
+func TestRoundingDecimal(t *testing.T) {
+	cases := []struct {
+		r    *Rounding
+		d    string
+		want string
+	}{
+		{nil, "-1234.5678", "-1234.5678"},
+		{&Rounding{Mode: RoundingMode.TRUNCATE, Places: 2}, "-1234.5678", "-1234.56"},
+		{&Rounding{Mode: RoundingMode.HALF_UP, Places: 2}, "1234.565", "1234.57"},
+		{&Rounding{Mode: RoundingMode.HALF_UP, Places: 2}, "-1234.565", "-1234.57"},
+		{&Rounding{Mode: RoundingMode.HALF_EVEN, Places: 2}, "1234.565", "1234.56"},
+		{&Rounding{Mode: RoundingMode.HALF_EVEN, Places: 0}, "-1234.5", "-1234"},
+		{&Rounding{Mode: RoundingMode.FLOOR, Places: 1}, "-0.01", "-0.1"},
+		{&Rounding{Mode: RoundingMode.CEIL, Places: 1}, "0.01", "0.1"},
+		{&Rounding{Mode: RoundingMode.TRUNCATE, Places: -2}, "-1234.5", "-1200"},
+		{&Rounding{Mode: RoundingMode.HALF_UP, Places: -3}, "1500", "2000"},
+		{&Rounding{Mode: RoundingMode.HALF_EVEN, Places: -3}, "2500", "2000"},
+		{&Rounding{Mode: RoundingMode.FLOOR, Places: -3}, "-1", "-1000"},
+		{&Rounding{Mode: RoundingMode.CEIL, Places: -3}, "1", "1000"},
+		{&Rounding{Mode: RoundingMode.HALF_UP, Places: -20}, "1e21", "1000000000000000000000"},
+	}
+	for _, c := range cases {
+		got := c.r.decimal(decimal.RequireFromString(c.d))
+		if !got.Equal(decimal.RequireFromString(c.want)) {
+			t.Errorf("%+v: %s rounded to %s, want %s", c.r, c.d, got, c.want)
+		}
+	}
+}
+
+func TestRoundingModifers(t *testing.T) {
+	halfUp := Rounding{Mode: RoundingMode.HALF_UP}
+	div := intModifer(RuleOperand.DIV, "Price", "2")
+	pct := intModifer(RuleOperand.SUB, "Price", `{"flat": 1000, "percentage": 15}`)
+	pct.RightType = ModiferSideType.COMPLEX
+	thousands := &Rounding{Mode: RoundingMode.CEIL, Places: -3}
+
+	cases := []struct {
+		name  string
+		opts  []Option
+		m     Modifer
+		price int64
+		want  int64
+	}{
+		{"DIV truncates by default", nil, div, 7, 3},
+		{"DIV with WithRounding", []Option{WithRounding(halfUp)}, div, 7, 4},
+		{"percentage truncates by default", nil, pct, 1010, 859}, // 151.5 off
+		{"percentage with WithRounding", []Option{WithRounding(halfUp)}, pct, 1010, 858},
+		{"the modifer's own rounding first", []Option{WithRounding(halfUp)}, withRounding(pct, thousands), 1010, 10},
+		{"DIV to thousands", nil, withRounding(intModifer(RuleOperand.DIV, "Price", "3"), thousands), 100000, 34000},
+	}
+	for _, c := range cases {
+		b := &booking{Price: c.price}
+		if _, err := NewEngine(stubSupply{}, c.opts...).ApplyModifer(b, c.m); err != nil || b.Price != c.want {
+			t.Errorf("%s: Price %d, %v, want %d", c.name, b.Price, err, c.want)
+		}
+	}
+}
+
+func withRounding(m Modifer, r *Rounding) Modifer {
+	m.Rounding = r
+	return m
+}
@@ -4,9 +4,11 @@
 package rule
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"runtime"
 	"sort"
 	"strconv"
 	"strings"
@@ -14,81 +16,144 @@ import (
 	"time"
 
 	"github.com/CloudHMS/hms.loyalty.core/pkg/util"
-
-	"github.com/jinzhu/gorm"
-	uuid "github.com/satori/go.uuid"
 )
 
 type ruleEngine struct {
-	sp Supply
+	sp                Supply
+	maxJumpDepth      int
+	defaultRounding   *Rounding
+	conditionStrategy int
+	parallelism       int
+	versions          map[string]int // pinned by WithVersion
+	clock             func() time.Time
+	includeDisabled   bool
 }
 
-func NewEngine(sp Supply) Engine {
-	return &ruleEngine{sp}
+func NewEngine(sp Supply, opts ...Option) Engine {
+	re := &ruleEngine{
+		sp:           sp,
+		maxJumpDepth: DefaultMaxJumpDepth,
+		clock:        time.Now,
+	}
+	for _, opt := range opts {
+		opt(re)
+	}
+	return re
 }
 
 // ApplySetting Check conditions and apply settings from for single rule
 func (re *ruleEngine) ApplySetting(rqr interface{}, rs RuleSetting) (bool, bool, error) {
-	var wg sync.WaitGroup
-	wg.Add(len(rs.Rule.ConditionChain))
-	var resultSum int
-	resultSum = 0
-	var grtn error
-	for _, condition := range rs.Rule.ConditionChain {
-		go func(condition Condition) {
-			temp := reflect.Indirect(reflect.ValueOf(rqr)).Interface()
-			result, err := re.CheckRuleCondition(temp, condition)
-			if err != nil && grtn == nil {
-				grtn = err
-			}
+	return re.ApplySettingContext(context.Background(), rqr, rs)
+}
 
-			if !result {
-				resultSum--
-			}
-			wg.Done()
-		}(condition)
+// ApplySettingContext ApplySetting bound to ctx
+func (re *ruleEngine) ApplySettingContext(ctx context.Context, rqr interface{}, rs RuleSetting) (bool, bool, error) {
+	doc, err := openDocument(rqr)
+	if err != nil {
+		return false, false, err
+	}
+	result, br, err := re.applySetting(newEvaluation(ctx).at(re.clock()).enter(rs), doc.rqr, rs)
+	if cerr := doc.close(); err == nil {
+		err = cerr
+	}
+	return result, br, err
+}
+
+// ExplainSetting ApplySetting and record how the result was reached
+func (re *ruleEngine) ExplainSetting(rqr interface{}, rs RuleSetting) (bool, bool, *Trace, error) {
+	return re.ExplainSettingContext(context.Background(), rqr, rs)
+}
+
+// ExplainSettingContext ExplainSetting bound to ctx
+func (re *ruleEngine) ExplainSettingContext(ctx context.Context, rqr interface{}, rs RuleSetting) (bool, bool, *Trace, error) {
+	doc, err := openDocument(rqr)
+	if err != nil {
+		return false, false, nil, err
+	}
+	tr := &Trace{}
+	result, br, err := re.applySetting(newEvaluation(ctx).at(re.clock()).enter(rs).explain(tr), doc.rqr, rs)
+	if cerr := doc.close(); err == nil {
+		err = cerr
 	}
+	tr.Result = result
+	tr.Error = errorString(err)
+	return result, br, tr, err
+}
+
+func (re *ruleEngine) applySetting(ev *evaluation, rqr interface{}, rs RuleSetting) (bool, bool, error) {
+	st := ev.visit(rs)
+	if skip := re.skip(ev, rs); skip != SettingSkip.NONE {
+		// as if it were not in the rule set
+		st.skip(skip)
+		return false, false, nil
+	}
+	result, br, err := re.evaluateSetting(ev, st, rqr, rs)
+	err = ruleError(rs, -1, -1, "", err)
+	st.done(result, br, err)
+	return result, br, err
+}
+
+// skip why rs is passed over in ev, SettingSkip.NONE when it is evaluated
+func (re *ruleEngine) skip(ev *evaluation, rs RuleSetting) int {
+	if !rs.Enable && !re.includeDisabled {
+		return SettingSkip.DISABLED
+	}
+	return rs.activity(ev.now)
+}
 
-	wg.Wait()
-	if grtn != nil {
-		return false, false, grtn
+func (re *ruleEngine) evaluateSetting(ev *evaluation, st *SettingTrace, rqr interface{}, rs RuleSetting) (_ bool, _ bool, err error) {
+	defer recoverError(&err)
+
+	temp := reflect.Indirect(reflect.ValueOf(rqr)).Interface()
+	matched, i, err := re.checkConditionChain(ev.ctx, temp, rs.Rule.ConditionChain, st)
+	if err != nil && i >= 0 {
+		return false, false, ruleError(rs, i, -1, conditionField(rs.Rule.ConditionChain[i]), err)
+	}
+	if err != nil {
+		return false, false, err
+	}
+	if matched && rs.Rule.ConditionTree != nil {
+		matched, err = re.checkConditionGroup(temp, *rs.Rule.ConditionTree, st.tree(rs.Rule.ConditionTree))
+		if err != nil {
+			return false, false, err
+		}
 	}
-	if resultSum < 0 {
+	if !matched {
 		if rs.BreakOnFail {
 			return false, true, nil
 		}
 		return false, false, nil
 	}
+	if st != nil {
+		st.Matched = true
+	}
 
 	// Apply modifers
 	sort.Slice(rs.Rule.ModiferChain, func(i, j int) bool {
 		return rs.Rule.ModiferChain[i].Sequence < rs.Rule.ModiferChain[j].Sequence
 	})
 
-	for _, modifer := range rs.Rule.ModiferChain {
+	for i, modifer := range rs.Rule.ModiferChain {
+		if err := ev.ctx.Err(); err != nil {
+			return false, false, err
+		}
+		mt := st.modifer(i, modifer)
 		if modifer.DataType == ModiferDataType.JMP {
 			// Jump then leave
-			id := modifer.LeftSide
-			start, err := strconv.ParseInt(modifer.RightSide, 10, 64)
-			if err != nil {
-				return false, false, RuleSettingError.MODIFER_SIDE_INVALID
-			}
-
-			rsn, err := re.sp.FetchRuleSettings(id, int(start))
-			if err != nil {
-				return false, false, RuleSettingError.UNABLE_TO_FETCH
-			}
-			rs, er := re.ApplySettings(rqr, rsn)
-			return rs, true, er
+			result, err := re.applyModiferJump(ev, mt, rqr, modifer)
+			err = ruleError(rs, -1, i, "", err)
+			mt.done(rqr, result, err)
+			return result, true, err
 		}
-		result, err := re.ApplyModifer(rqr, modifer)
+		mt.before(rqr)
+		result, err := re.applyModifer(ev, mt, rqr, modifer)
+		err = ruleError(rs, -1, i, modifer.TargetField, err)
+		mt.done(rqr, result, err)
 
 		if err != nil {
-			// TODO: log error
 			return false, false, err
 		}
 		if !result {
-			// TODO: log error
 			return false, false, nil
 		}
 	}
@@ -97,6 +162,44 @@ func (re *ruleEngine) ApplySetting(rqr interface{}, rs RuleSetting) (bool, bool,
 
 // ApplySettings Check conditions and apply settings from rule set
 func (re *ruleEngine) ApplySettings(rqr interface{}, rs []RuleSetting) (bool, error) {
+	return re.ApplySettingsContext(context.Background(), rqr, rs)
+}
+
+// ApplySettingsContext ApplySettings bound to ctx, checked between settings, modifers and before every fetch
+func (re *ruleEngine) ApplySettingsContext(ctx context.Context, rqr interface{}, rs []RuleSetting) (bool, error) {
+	doc, err := openDocument(rqr)
+	if err != nil {
+		return false, err
+	}
+	result, err := re.applySettings(newEvaluation(ctx).at(re.clock()).enter(rs...), doc.rqr, rs)
+	if cerr := doc.close(); err == nil {
+		err = cerr
+	}
+	return result, err
+}
+
+// ExplainSettings ApplySettings and record every setting, condition, modifer and jump visited
+func (re *ruleEngine) ExplainSettings(rqr interface{}, rs []RuleSetting) (bool, *Trace, error) {
+	return re.ExplainSettingsContext(context.Background(), rqr, rs)
+}
+
+// ExplainSettingsContext ExplainSettings bound to ctx
+func (re *ruleEngine) ExplainSettingsContext(ctx context.Context, rqr interface{}, rs []RuleSetting) (bool, *Trace, error) {
+	doc, err := openDocument(rqr)
+	if err != nil {
+		return false, nil, err
+	}
+	tr := &Trace{}
+	result, err := re.applySettings(newEvaluation(ctx).at(re.clock()).enter(rs...).explain(tr), doc.rqr, rs)
+	if cerr := doc.close(); err == nil {
+		err = cerr
+	}
+	tr.Result = result
+	tr.Error = errorString(err)
+	return result, tr, err
+}
+
+func (re *ruleEngine) applySettings(ev *evaluation, rqr interface{}, rs []RuleSetting) (bool, error) {
 	// check settings sequence
 	cs := re.checkSettingSequence(rs)
 	if !cs {
@@ -104,12 +207,12 @@ func (re *ruleEngine) ApplySettings(rqr interface{}, rs []RuleSetting) (bool, er
 	}
 
 	for _, setting := range rs {
-		result, br, err := re.ApplySetting(rqr, setting)
-		if err != nil {
+		if err := ev.ctx.Err(); err != nil {
 			return false, err
 		}
-		if !result {
-			// TODO: log
+		result, br, err := re.applySetting(ev, rqr, setting)
+		if err != nil {
+			return false, err
 		}
 		if br {
 			return result, nil
@@ -120,19 +223,46 @@ func (re *ruleEngine) ApplySettings(rqr interface{}, rs []RuleSetting) (bool, er
 
 // ApplyModifer apply the Modifer directly to the rqr data
 func (re *ruleEngine) ApplyModifer(rqr interface{}, rm Modifer) (bool, error) {
+	return re.ApplyModiferContext(context.Background(), rqr, rm)
+}
+
+// ApplyModiferContext ApplyModifer bound to ctx
+func (re *ruleEngine) ApplyModiferContext(ctx context.Context, rqr interface{}, rm Modifer) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	doc, err := openDocument(rqr)
+	if err != nil {
+		return false, err
+	}
+	result, err := re.applyModifer(newEvaluation(ctx).at(re.clock()), nil, doc.rqr, rm)
+	if cerr := doc.close(); err == nil {
+		err = cerr
+	}
+	return result, err
+}
+
+func (re *ruleEngine) applyModifer(ev *evaluation, mt *ModiferTrace, rqr interface{}, rm Modifer) (_ bool, err error) {
+	defer recoverError(&err)
+
 	switch rm.DataType {
 	case ModiferDataType.STRING:
 		if err := re.applyModiferString(rqr, rm); err != nil {
-			return false, re.applyModiferString(rqr, rm)
+			return false, err
 		}
 		return true, nil
 	case ModiferDataType.INT:
 		if err := re.applyModiferInt(rqr, rm); err != nil {
-			return false, re.applyModiferInt(rqr, rm)
+			return false, err
+		}
+		return true, nil
+	case ModiferDataType.DECIMAL:
+		if err := re.applyModiferDecimal(rqr, rm); err != nil {
+			return false, err
 		}
 		return true, nil
 	case ModiferDataType.JRT:
-		return re.applyModiferJumpReturn(rqr, rm)
+		return re.applyModiferJump(ev, mt, rqr, rm)
 	}
 
 	return false, RuleSettingError.UNSUPPORTED_OPERATION
@@ -146,25 +276,33 @@ func (re *ruleEngine) applyModiferString(rqr interface{}, rm Modifer) error {
 		case ModiferSideType.VALUE:
 			vl = rm.LeftSide
 		case ModiferSideType.FIELD:
-			temp := reflect.Indirect(reflect.ValueOf(rqr)).FieldByName(rm.LeftSide)
-			vl = temp.String()
+			temp, err := fieldString(rqr, rm.LeftSide)
+			if err != nil {
+				return err
+			}
+			vl = temp
 		default:
 			return RuleSettingError.MODIFER_SIDE_INVALID
 		}
-		reflect.Indirect(reflect.ValueOf(rqr)).FieldByName(rm.TargetField).SetString(vl)
-		return nil
+		return setFieldString(rqr, rm.TargetField, vl)
 
 	case RuleOperand.SEL:
-		selectWhat := reflect.Indirect(reflect.ValueOf(rqr)).FieldByName(rm.LeftSide).String()
+		selectWhat, err := fieldString(rqr, rm.LeftSide)
+		if err != nil {
+			return err
+		}
 		var vr ModiferComplex
 		switch rm.RightType {
 		case ModiferSideType.VALUE:
 			return RuleSettingError.MODIFER_SIDE_INVALID
 		case ModiferSideType.FIELD:
-			temp := reflect.Indirect(reflect.ValueOf(rqr)).FieldByName(rm.RightSide).Interface().(ModiferComplex)
+			temp, err := fieldComplex(rqr, rm.RightSide)
+			if err != nil {
+				return err
+			}
 			vr = temp
 		case ModiferSideType.COMPLEX:
-			var temp *ModiferComplex
+			temp := new(ModiferComplex)
 			err := json.Unmarshal([]byte(rm.RightSide), temp)
 			if err != nil {
 				return err
@@ -176,10 +314,9 @@ func (re *ruleEngine) applyModiferString(rqr interface{}, rm Modifer) error {
 
 		if sel := vr.Select(selectWhat); sel != nil {
 			modifer := sel.Value
-			reflect.Indirect(reflect.ValueOf(rqr)).FieldByName(rm.TargetField).SetString(modifer)
-			return nil
+			return setFieldString(rqr, rm.TargetField, modifer)
 		}
-		return fmt.Errorf("Field not existed %s", selectWhat)
+		return fmt.Errorf("%w: %s", RuleSettingError.MODIFER_FEILD_NOT_EXISTED, selectWhat)
 	}
 	return RuleSettingError.UNSUPPORTED_OPERATION
 }
@@ -192,31 +329,36 @@ func (re *ruleEngine) applyModiferInt(rqr interface{}, rm Modifer) error {
 		case ModiferSideType.VALUE:
 			temp, err := strconv.ParseInt(rm.LeftSide, 10, 64)
 			if err != nil {
-				return fmt.Errorf("Invalid left modifer value side %s", rm.LeftSide)
+				return fmt.Errorf("%w: left value %s", RuleSettingError.MODIFER_SIDE_INVALID, rm.LeftSide)
 			}
 			vl = temp
 		case ModiferSideType.FIELD:
-			temp := reflect.Indirect(reflect.ValueOf(rqr)).FieldByName(rm.LeftSide)
-			vl = temp.Int()
+			temp, err := fieldInt(rqr, rm.LeftSide)
+			if err != nil {
+				return err
+			}
+			vl = temp
 		default:
-			return fmt.Errorf("Invalid left modifer side %s", rm.LeftSide)
+			return fmt.Errorf("%w: left %s", RuleSettingError.MODIFER_SIDE_INVALID, rm.LeftSide)
 		}
-		reflect.Indirect(reflect.ValueOf(rqr)).FieldByName(rm.TargetField).SetInt(vl)
-		return nil
+		return setFieldInt(rqr, rm.TargetField, vl)
 	case RuleOperand.ADD:
 		var vl int64
 		switch rm.LeftType {
 		case ModiferSideType.VALUE:
 			temp, err := strconv.ParseInt(rm.LeftSide, 10, 64)
 			if err != nil {
-				return fmt.Errorf("Invalid left modifer value side %s", rm.LeftSide)
+				return fmt.Errorf("%w: left value %s", RuleSettingError.MODIFER_SIDE_INVALID, rm.LeftSide)
 			}
 			vl = temp
 		case ModiferSideType.FIELD:
-			temp := reflect.Indirect(reflect.ValueOf(rqr)).FieldByName(rm.LeftSide)
-			vl = temp.Int()
+			temp, err := fieldInt(rqr, rm.LeftSide)
+			if err != nil {
+				return err
+			}
+			vl = temp
 		default:
-			return fmt.Errorf("Invalid left modifer side %s", rm.LeftSide)
+			return fmt.Errorf("%w: left %s", RuleSettingError.MODIFER_SIDE_INVALID, rm.LeftSide)
 		}
 
 		var vr int64
@@ -224,39 +366,48 @@ func (re *ruleEngine) applyModiferInt(rqr interface{}, rm Modifer) error {
 		case ModiferSideType.VALUE:
 			temp, err := strconv.ParseInt(rm.RightSide, 10, 64)
 			if err != nil {
-				return fmt.Errorf("Invalid right modifer value side %s", rm.RightSide)
+				return fmt.Errorf("%w: right value %s", RuleSettingError.MODIFER_SIDE_INVALID, rm.RightSide)
 			}
 			vr = temp
 		case ModiferSideType.FIELD:
-			temp := reflect.Indirect(reflect.ValueOf(rqr)).FieldByName(rm.RightSide)
-			vr = temp.Int()
+			temp, err := fieldInt(rqr, rm.RightSide)
+			if err != nil {
+				return err
+			}
+			vr = temp
 		case ModiferSideType.COMPLEX:
-			var temp *ModiferComplex
+			temp := new(ModiferComplex)
 			err := json.Unmarshal([]byte(rm.RightSide), temp)
 			if err != nil {
 				return err
 			}
-			vr = util.MinInt64(temp.Flat, (temp.Percent*vl)/100)
+			pct, err := re.rounding(rm).divide(temp.Percent*vl, 100)
+			if err != nil {
+				return err
+			}
+			vr = temp.capped(pct)
 		default:
-			return fmt.Errorf("Invalid right modifer side %s", rm.RightSide)
+			return fmt.Errorf("%w: right %s", RuleSettingError.MODIFER_SIDE_INVALID, rm.RightSide)
 		}
 
-		reflect.Indirect(reflect.ValueOf(rqr)).FieldByName(rm.TargetField).SetInt(vl + vr)
-		return nil
+		return setFieldInt(rqr, rm.TargetField, vl+vr)
 	case RuleOperand.SUB:
 		var vl int64
 		switch rm.LeftType {
 		case ModiferSideType.VALUE:
 			temp, err := strconv.ParseInt(rm.LeftSide, 10, 64)
 			if err != nil {
-				return fmt.Errorf("Invalid left modifer value side %s", rm.LeftSide)
+				return fmt.Errorf("%w: left value %s", RuleSettingError.MODIFER_SIDE_INVALID, rm.LeftSide)
 			}
 			vl = temp
 		case ModiferSideType.FIELD:
-			temp := reflect.Indirect(reflect.ValueOf(rqr)).FieldByName(rm.LeftSide)
-			vl = temp.Int()
+			temp, err := fieldInt(rqr, rm.LeftSide)
+			if err != nil {
+				return err
+			}
+			vl = temp
 		default:
-			return fmt.Errorf("Invalid left modifer side %s", rm.LeftSide)
+			return fmt.Errorf("%w: left %s", RuleSettingError.MODIFER_SIDE_INVALID, rm.LeftSide)
 		}
 
 		var vr int64
@@ -264,39 +415,48 @@ func (re *ruleEngine) applyModiferInt(rqr interface{}, rm Modifer) error {
 		case ModiferSideType.VALUE:
 			temp, err := strconv.ParseInt(rm.RightSide, 10, 64)
 			if err != nil {
-				return fmt.Errorf("Invalid right modifer value side %s", rm.RightSide)
+				return fmt.Errorf("%w: right value %s", RuleSettingError.MODIFER_SIDE_INVALID, rm.RightSide)
 			}
 			vr = temp
 		case ModiferSideType.FIELD:
-			temp := reflect.Indirect(reflect.ValueOf(rqr)).FieldByName(rm.RightSide)
-			vr = temp.Int()
+			temp, err := fieldInt(rqr, rm.RightSide)
+			if err != nil {
+				return err
+			}
+			vr = temp
 		case ModiferSideType.COMPLEX:
-			var temp *ModiferComplex
+			temp := new(ModiferComplex)
 			err := json.Unmarshal([]byte(rm.RightSide), temp)
 			if err != nil {
 				return err
 			}
-			vr = util.MinInt64(temp.Flat, (temp.Percent*vl)/100)
+			pct, err := re.rounding(rm).divide(temp.Percent*vl, 100)
+			if err != nil {
+				return err
+			}
+			vr = temp.capped(pct)
 		default:
-			return fmt.Errorf("Invalid right modifer side %s", rm.RightSide)
+			return fmt.Errorf("%w: right %s", RuleSettingError.MODIFER_SIDE_INVALID, rm.RightSide)
 		}
 
-		reflect.Indirect(reflect.ValueOf(rqr)).FieldByName(rm.TargetField).SetInt(vl - vr)
-		return nil
+		return setFieldInt(rqr, rm.TargetField, vl-vr)
 	case RuleOperand.MLT:
 		var vl int64
 		switch rm.LeftType {
 		case ModiferSideType.VALUE:
 			temp, err := strconv.ParseInt(rm.LeftSide, 10, 64)
 			if err != nil {
-				return fmt.Errorf("Invalid left modifer value side %s", rm.LeftSide)
+				return fmt.Errorf("%w: left value %s", RuleSettingError.MODIFER_SIDE_INVALID, rm.LeftSide)
 			}
 			vl = temp
 		case ModiferSideType.FIELD:
-			temp := reflect.Indirect(reflect.ValueOf(rqr)).FieldByName(rm.LeftSide)
-			vl = temp.Int()
+			temp, err := fieldInt(rqr, rm.LeftSide)
+			if err != nil {
+				return err
+			}
+			vl = temp
 		default:
-			return fmt.Errorf("Invalid left modifer side %s", rm.LeftSide)
+			return fmt.Errorf("%w: left %s", RuleSettingError.MODIFER_SIDE_INVALID, rm.LeftSide)
 		}
 
 		var vr int64
@@ -304,25 +464,30 @@ func (re *ruleEngine) applyModiferInt(rqr interface{}, rm Modifer) error {
 		case ModiferSideType.VALUE:
 			temp, err := strconv.ParseInt(rm.RightSide, 10, 64)
 			if err != nil {
-				return fmt.Errorf("Invalid right modifer value side %s", rm.RightSide)
+				return fmt.Errorf("%w: right value %s", RuleSettingError.MODIFER_SIDE_INVALID, rm.RightSide)
 			}
 			vr = temp
 		case ModiferSideType.FIELD:
-			temp := reflect.Indirect(reflect.ValueOf(rqr)).FieldByName(rm.RightSide)
-			vr = temp.Int()
+			temp, err := fieldInt(rqr, rm.RightSide)
+			if err != nil {
+				return err
+			}
+			vr = temp
 		case ModiferSideType.COMPLEX:
-			var temp *ModiferComplex
+			temp := new(ModiferComplex)
 			err := json.Unmarshal([]byte(rm.RightSide), temp)
 			if err != nil {
 				return err
 			}
-			vr = (temp.Percent * vl) / 100
+			vr, err = re.rounding(rm).divide(temp.Percent*vl, 100)
+			if err != nil {
+				return err
+			}
 		default:
-			return fmt.Errorf("Invalid right modifer side %s", rm.RightSide)
+			return fmt.Errorf("%w: right %s", RuleSettingError.MODIFER_SIDE_INVALID, rm.RightSide)
 		}
 
-		reflect.Indirect(reflect.ValueOf(rqr)).FieldByName(rm.TargetField).SetInt(vl * vr)
-		return nil
+		return setFieldInt(rqr, rm.TargetField, vl*vr)
 	case RuleOperand.DIV:
 		var vl int64
 		switch rm.LeftType {
@@ -333,8 +498,11 @@ func (re *ruleEngine) applyModiferInt(rqr interface{}, rm Modifer) error {
 			}
 			vl = temp
 		case ModiferSideType.FIELD:
-			temp := reflect.Indirect(reflect.ValueOf(rqr)).FieldByName(rm.LeftSide)
-			vl = temp.Int()
+			temp, err := fieldInt(rqr, rm.LeftSide)
+			if err != nil {
+				return err
+			}
+			vl = temp
 		default:
 			return RuleSettingError.MODIFER_SIDE_INVALID
 		}
@@ -348,15 +516,21 @@ func (re *ruleEngine) applyModiferInt(rqr interface{}, rm Modifer) error {
 			}
 			vr = temp
 		case ModiferSideType.FIELD:
-			temp := reflect.Indirect(reflect.ValueOf(rqr)).FieldByName(rm.RightSide)
-			vr = temp.Int()
+			temp, err := fieldInt(rqr, rm.RightSide)
+			if err != nil {
+				return err
+			}
+			vr = temp
 		case ModiferSideType.COMPLEX:
-			var temp *ModiferComplex
+			temp := new(ModiferComplex)
 			err := json.Unmarshal([]byte(rm.RightSide), temp)
 			if err != nil {
 				return err
 			}
-			vr = (temp.Percent * vl) / 100
+			vr, err = re.rounding(rm).divide(temp.Percent*vl, 100)
+			if err != nil {
+				return err
+			}
 		default:
 			return RuleSettingError.MODIFER_SIDE_INVALID
 		}
@@ -364,19 +538,28 @@ func (re *ruleEngine) applyModiferInt(rqr interface{}, rm Modifer) error {
 			return RuleSettingError.DIV_BY_ZERO
 		}
 
-		reflect.Indirect(reflect.ValueOf(rqr)).FieldByName(rm.TargetField).SetInt(vl / vr)
-		return nil
+		q, err := re.rounding(rm).divide(vl, vr)
+		if err != nil {
+			return err
+		}
+		return setFieldInt(rqr, rm.TargetField, q)
 	case RuleOperand.SEL:
-		selectWhat := reflect.Indirect(reflect.ValueOf(rqr)).FieldByName(rm.LeftSide).String()
+		selectWhat, err := fieldString(rqr, rm.LeftSide)
+		if err != nil {
+			return err
+		}
 		var vr ModiferComplex
 		switch rm.RightType {
 		case ModiferSideType.VALUE:
 			return RuleSettingError.MODIFER_SIDE_INVALID
 		case ModiferSideType.FIELD:
-			temp := reflect.Indirect(reflect.ValueOf(rqr)).FieldByName(rm.RightSide).Interface().(ModiferComplex)
+			temp, err := fieldComplex(rqr, rm.RightSide)
+			if err != nil {
+				return err
+			}
 			vr = temp
 		case ModiferSideType.COMPLEX:
-			var temp *ModiferComplex
+			temp := new(ModiferComplex)
 			err := json.Unmarshal([]byte(rm.RightSide), temp)
 			if err != nil {
 				return err
@@ -388,22 +571,30 @@ func (re *ruleEngine) applyModiferInt(rqr interface{}, rm Modifer) error {
 
 		if sel := vr.Select(selectWhat); sel != nil {
 			modifer, _ := strconv.ParseInt(sel.Value, 10, 64)
-			reflect.Indirect(reflect.ValueOf(rqr)).FieldByName(rm.TargetField).SetInt(modifer)
-			return nil
+			return setFieldInt(rqr, rm.TargetField, modifer)
 		}
-		return fmt.Errorf("Field not existed %s", selectWhat)
+		return fmt.Errorf("%w: %s", RuleSettingError.MODIFER_FEILD_NOT_EXISTED, selectWhat)
 	case RuleOperand.SUM:
-		selectWhat := reflect.Indirect(reflect.ValueOf(rqr)).FieldByName(rm.LeftSide).Interface().([]string)
-		modifer := reflect.Indirect(reflect.ValueOf(rqr)).FieldByName(rm.TargetField).Int()
+		selectWhat, err := fieldStrings(rqr, rm.LeftSide)
+		if err != nil {
+			return err
+		}
+		modifer, err := fieldInt(rqr, rm.TargetField)
+		if err != nil {
+			return err
+		}
 		var vr ModiferComplex
 		switch rm.RightType {
 		case ModiferSideType.VALUE:
 			return RuleSettingError.MODIFER_SIDE_INVALID
 		case ModiferSideType.FIELD:
-			temp := reflect.Indirect(reflect.ValueOf(rqr)).FieldByName(rm.RightSide).Interface().(ModiferComplex)
+			temp, err := fieldComplex(rqr, rm.RightSide)
+			if err != nil {
+				return err
+			}
 			vr = temp
 		case ModiferSideType.COMPLEX:
-			var temp *ModiferComplex
+			temp := new(ModiferComplex)
 			err := json.Unmarshal([]byte(rm.RightSide), temp)
 			if err != nil {
 				return err
@@ -419,54 +610,92 @@ func (re *ruleEngine) applyModiferInt(rqr interface{}, rm Modifer) error {
 				modifer = modifer + value
 			}
 		}
-		reflect.Indirect(reflect.ValueOf(rqr)).FieldByName(rm.TargetField).SetInt(modifer)
-		return nil
+		return setFieldInt(rqr, rm.TargetField, modifer)
 	}
 
 	return RuleSettingError.UNSUPPORTED_OPERATION
 }
 
-func (re *ruleEngine) applyModiferJumpReturn(rqr interface{}, rm Modifer) (bool, error) {
+// applyModiferJump fetch the rule set named by a JMP or JRT modifer and apply it
+func (re *ruleEngine) applyModiferJump(ev *evaluation, mt *ModiferTrace, rqr interface{}, rm Modifer) (bool, error) {
 	id := rm.LeftSide
 	start, err := strconv.ParseInt(rm.RightSide, 10, 64)
 	if err != nil {
 		return false, RuleSettingError.MODIFER_SIDE_INVALID
 	}
 
-	rsn, err := re.sp.FetchRuleSettings(id, int(start))
+	next, err := ev.jump(mt, id, int(start), re.maxJumpDepth)
 	if err != nil {
+		return false, err
+	}
+
+	rsn, err := re.fetchRuleSettings(ev.ctx, id, int(start))
+	if err != nil {
+		if ev.ctx.Err() != nil {
+			return false, ev.ctx.Err()
+		}
 		return false, RuleSettingError.UNABLE_TO_FETCH
 	}
-	return re.ApplySettings(rqr, rsn)
+	return re.applySettings(next, rqr, rsn)
 }
 
-func (re *ruleEngine) compareDayOfWeek(rqr interface{}, c Condition) (bool, error) {
-	var vl reflect.Value
+func (re *ruleEngine) fetchRuleSettings(ctx context.Context, id string, start int) ([]RuleSetting, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if version, ok := re.versions[id]; ok {
+		sp, ok := re.sp.(VersionedSupply)
+		if !ok {
+			return nil, fmt.Errorf("%w: version %d of %s pinned on a supply without versions", RuleSettingError.UNSUPPORTED_OPERATION, version, id)
+		}
+		return sp.FetchVersion(id, version, start)
+	}
+	if sp, ok := re.sp.(DisabledSupply); ok && re.includeDisabled {
+		return sp.FetchAllRuleSettings(id, start)
+	}
+	if sp, ok := re.sp.(ContextSupply); ok {
+		return sp.FetchRuleSettingsContext(ctx, id, start)
+	}
+	return re.sp.FetchRuleSettings(id, start)
+}
+
+func (re *ruleEngine) compareDayOfWeek(rqr interface{}, c Condition, ct *ConditionTrace) (bool, error) {
+	var vl time.Time
 	switch c.LeftType {
 	case ConditionSideType.FIELD:
-		vl = reflect.ValueOf(rqr).FieldByName(c.LeftSide)
+		temp, err := fieldTime(rqr, c.LeftSide)
+		if err != nil {
+			return false, err
+		}
+		vl = temp
 	case ConditionSideType.VALUE:
 		temp, err := strconv.ParseInt(c.LeftSide, 10, 64)
 		if err != nil {
 			return false, err
 		}
-		vl = reflect.ValueOf(time.Unix(temp, 0))
+		vl = time.Unix(temp, 0)
 	default:
 		return false, RuleSettingError.CONDITION_SIDE_INVALID
 	}
-	dl := vl.Interface().(time.Time).Weekday()
+	dl := vl.Weekday()
+	ct.left(dl.String())
 
 	// handle In array
 	if c.Compare == RuleConditionCompare.IN || c.Compare == RuleConditionCompare.NOT_IN {
 		var vr []string
 		switch c.RightType {
 		case ConditionSideType.FIELD:
-			vr = strings.Split(reflect.ValueOf(rqr).FieldByName(c.RightSide).String(), ",")
+			temp, err := fieldString(rqr, c.RightSide)
+			if err != nil {
+				return false, err
+			}
+			vr = strings.Split(temp, ",")
 		case ConditionSideType.VALUE:
 			vr = strings.Split(c.RightSide, ",")
 		default:
 			return false, RuleSettingError.CONDITION_SIDE_INVALID
 		}
+		ct.right(vr)
 
 		for _, v := range vr {
 			i, err := strconv.ParseInt(v, 10, 64)
@@ -498,7 +727,11 @@ func (re *ruleEngine) compareDayOfWeek(rqr interface{}, c Condition) (bool, erro
 	var vr int64
 	switch c.RightType {
 	case ConditionSideType.FIELD:
-		vr = reflect.ValueOf(rqr).FieldByName(c.RightSide).Interface().(time.Time).Unix()
+		temp, err := fieldTime(rqr, c.RightSide)
+		if err != nil {
+			return false, err
+		}
+		vr = temp.Unix()
 	case ConditionSideType.VALUE:
 		temp, err := strconv.ParseInt(c.RightSide, 10, 64)
 		if err != nil {
@@ -510,6 +743,7 @@ func (re *ruleEngine) compareDayOfWeek(rqr interface{}, c Condition) (bool, erro
 	}
 
 	dr := time.Unix(vr, 0).Weekday()
+	ct.right(dr.String())
 	switch c.Compare {
 	case RuleConditionCompare.EQUAL:
 		return dl == dr, nil
@@ -520,33 +754,43 @@ func (re *ruleEngine) compareDayOfWeek(rqr interface{}, c Condition) (bool, erro
 
 }
 
-func (re *ruleEngine) compareDate(rqr interface{}, c Condition) (bool, error) {
-	var vl reflect.Value
+func (re *ruleEngine) compareDate(rqr interface{}, c Condition, ct *ConditionTrace) (bool, error) {
+	var vl time.Time
 	switch c.LeftType {
 	case ConditionSideType.FIELD:
-		vl = reflect.ValueOf(rqr).FieldByName(c.LeftSide)
+		temp, err := fieldTime(rqr, c.LeftSide)
+		if err != nil {
+			return false, err
+		}
+		vl = temp
 	case ConditionSideType.VALUE:
 		temp, err := strconv.ParseInt(c.LeftSide, 10, 64)
 		if err != nil {
 			return false, err
 		}
-		vl = reflect.ValueOf(time.Unix(temp, 0))
+		vl = time.Unix(temp, 0)
 	default:
 		return false, RuleSettingError.CONDITION_SIDE_INVALID
 	}
-	dl := util.StripTimeDMY(vl.Interface().(time.Time))
+	dl := util.StripTimeDMY(vl)
+	ct.left(dl)
 
 	// handle In case
 	if c.Compare == RuleConditionCompare.IN || c.Compare == RuleConditionCompare.NOT_IN {
 		var vr []string
 		switch c.RightType {
 		case ConditionSideType.FIELD:
-			vr = strings.Split(reflect.ValueOf(rqr).FieldByName(c.RightSide).String(), ",")
+			temp, err := fieldString(rqr, c.RightSide)
+			if err != nil {
+				return false, err
+			}
+			vr = strings.Split(temp, ",")
 		case ConditionSideType.VALUE:
 			vr = strings.Split(c.RightSide, ",")
 		default:
 			return false, RuleSettingError.CONDITION_SIDE_INVALID
 		}
+		ct.right(vr)
 
 		for _, v := range vr {
 			i, err := strconv.ParseInt(v, 10, 64)
@@ -577,7 +821,11 @@ func (re *ruleEngine) compareDate(rqr interface{}, c Condition) (bool, error) {
 	var vr int64
 	switch c.RightType {
 	case ConditionSideType.FIELD:
-		vr = reflect.ValueOf(rqr).FieldByName(c.RightSide).Interface().(time.Time).Unix()
+		temp, err := fieldTime(rqr, c.RightSide)
+		if err != nil {
+			return false, err
+		}
+		vr = temp.Unix()
 	case ConditionSideType.VALUE:
 		temp, err := strconv.ParseInt(c.RightSide, 10, 64)
 		if err != nil {
@@ -589,6 +837,7 @@ func (re *ruleEngine) compareDate(rqr interface{}, c Condition) (bool, error) {
 	}
 
 	dr := util.StripTimeDMY(time.Unix(vr, 0))
+	ct.right(dr)
 	switch c.Compare {
 	case RuleConditionCompare.EQUAL:
 		return dl.Sub(dr) == 0, nil
@@ -606,11 +855,15 @@ func (re *ruleEngine) compareDate(rqr interface{}, c Condition) (bool, error) {
 	return false, RuleSettingError.UNSUPPORTED_OPERATION
 }
 
-func (re *ruleEngine) compareGenericString(rqr interface{}, c Condition) (bool, error) {
+func (re *ruleEngine) compareGenericString(rqr interface{}, c Condition, ct *ConditionTrace) (bool, error) {
 	var vl string
 	switch c.LeftType {
 	case ConditionSideType.FIELD:
-		vl = reflect.ValueOf(rqr).FieldByName(c.LeftSide).String()
+		temp, err := fieldString(rqr, c.LeftSide)
+		if err != nil {
+			return false, err
+		}
+		vl = temp
 	case ConditionSideType.VALUE:
 		vl = c.LeftSide
 	default:
@@ -620,12 +873,18 @@ func (re *ruleEngine) compareGenericString(rqr interface{}, c Condition) (bool,
 	var vr string
 	switch c.RightType {
 	case ConditionSideType.FIELD:
-		vr = reflect.ValueOf(rqr).FieldByName(c.RightSide).String()
+		temp, err := fieldString(rqr, c.RightSide)
+		if err != nil {
+			return false, err
+		}
+		vr = temp
 	case ConditionSideType.VALUE:
 		vr = c.RightSide
 	default:
 		return false, RuleSettingError.CONDITION_SIDE_INVALID
 	}
+	ct.left(vl)
+	ct.right(vr)
 
 	switch c.Compare {
 	case RuleConditionCompare.EQUAL:
@@ -637,11 +896,15 @@ func (re *ruleEngine) compareGenericString(rqr interface{}, c Condition) (bool,
 	return false, RuleSettingError.UNSUPPORTED_OPERATION
 }
 
-func (re *ruleEngine) compareGenericInt(rqr interface{}, c Condition) (bool, error) {
+func (re *ruleEngine) compareGenericInt(rqr interface{}, c Condition, ct *ConditionTrace) (bool, error) {
 	var vl int64
 	switch c.LeftType {
 	case ConditionSideType.FIELD:
-		vl = reflect.ValueOf(rqr).FieldByName(c.LeftSide).Int()
+		temp, err := fieldInt(rqr, c.LeftSide)
+		if err != nil {
+			return false, err
+		}
+		vl = temp
 	case ConditionSideType.VALUE:
 		temp, err := strconv.ParseInt(c.LeftSide, 10, 64)
 		if err != nil {
@@ -651,6 +914,7 @@ func (re *ruleEngine) compareGenericInt(rqr interface{}, c Condition) (bool, err
 	default:
 		return false, RuleSettingError.CONDITION_SIDE_INVALID
 	}
+	ct.left(vl)
 
 	// handle In array
 	if c.Compare == RuleConditionCompare.IN || c.Compare == RuleConditionCompare.NOT_IN {
@@ -658,12 +922,17 @@ func (re *ruleEngine) compareGenericInt(rqr interface{}, c Condition) (bool, err
 		var vr []string
 		switch c.RightType {
 		case ConditionSideType.FIELD:
-			vr = strings.Split(reflect.ValueOf(rqr).FieldByName(c.RightSide).String(), ",")
+			temp, err := fieldString(rqr, c.RightSide)
+			if err != nil {
+				return false, err
+			}
+			vr = strings.Split(temp, ",")
 		case ConditionSideType.VALUE:
 			vr = strings.Split(c.RightSide, ",")
 		default:
 			return false, RuleSettingError.CONDITION_SIDE_INVALID
 		}
+		ct.right(vr)
 
 		for _, v := range vr {
 			i, err := strconv.ParseInt(v, 10, 64)
@@ -694,7 +963,11 @@ func (re *ruleEngine) compareGenericInt(rqr interface{}, c Condition) (bool, err
 	var vr int64
 	switch c.RightType {
 	case ConditionSideType.FIELD:
-		vr = reflect.ValueOf(rqr).FieldByName(c.RightSide).Int()
+		temp, err := fieldInt(rqr, c.RightSide)
+		if err != nil {
+			return false, err
+		}
+		vr = temp
 	case ConditionSideType.VALUE:
 		temp, err := strconv.ParseInt(c.RightSide, 10, 64)
 		if err != nil {
@@ -704,6 +977,7 @@ func (re *ruleEngine) compareGenericInt(rqr interface{}, c Condition) (bool, err
 	default:
 		return false, RuleSettingError.CONDITION_SIDE_INVALID
 	}
+	ct.right(vr)
 
 	switch c.Compare {
 	case RuleConditionCompare.EQUAL:
@@ -722,6 +996,68 @@ func (re *ruleEngine) compareGenericInt(rqr interface{}, c Condition) (bool, err
 	return false, RuleSettingError.UNSUPPORTED_OPERATION
 }
 
+// checkConditionChain the first false or failing condition in chain order decides, i is its index.
+// i is -1 when every condition passes or ctx is done before the chain is checked
+func (re *ruleEngine) checkConditionChain(ctx context.Context, rqr interface{}, cs []Condition, st *SettingTrace) (bool, int, error) {
+	cts := st.conditions(cs)
+	switch re.conditionStrategy {
+	case ConditionStrategy.SEQUENTIAL:
+		for i, condition := range cs {
+			if err := ctx.Err(); err != nil {
+				st.checked(i)
+				return false, -1, err
+			}
+			result, err := re.checkRuleCondition(rqr, condition, cts[i])
+			cts[i].done(result, err)
+			if err != nil || !result {
+				st.checked(i + 1)
+				return false, i, err
+			}
+		}
+		return true, -1, nil
+	case ConditionStrategy.PARALLEL:
+		limit := re.parallelism
+		if limit <= 0 {
+			limit = runtime.GOMAXPROCS(0)
+		}
+		results := make([]bool, len(cs))
+		errs := make([]error, len(cs))
+		sem := make(chan struct{}, limit)
+		var wg sync.WaitGroup
+		started := 0
+		for i, condition := range cs {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+			}
+			if ctx.Err() != nil {
+				break
+			}
+			started++
+			wg.Add(1)
+			go func(i int, condition Condition) {
+				defer wg.Done()
+				results[i], errs[i] = re.checkRuleCondition(rqr, condition, cts[i])
+				cts[i].done(results[i], errs[i])
+				<-sem
+			}(i, condition)
+		}
+		wg.Wait()
+		if started < len(cs) {
+			st.checked(started)
+			return false, -1, ctx.Err()
+		}
+
+		for i := range cs {
+			if errs[i] != nil || !results[i] {
+				return false, i, errs[i]
+			}
+		}
+		return true, -1, nil
+	}
+	return false, -1, RuleSettingError.UNSUPPORTED_OPERATION
+}
+
 func (re *ruleEngine) checkSettingSequence(settings []RuleSetting) bool {
 	for idx := 0; idx < len(settings)-1; idx++ {
 		if settings[idx].Sequence > settings[idx+1].Sequence {
@@ -733,52 +1069,79 @@ func (re *ruleEngine) checkSettingSequence(settings []RuleSetting) bool {
 
 // CheckRuleCondition Check if the result fit the condition
 func (re *ruleEngine) CheckRuleCondition(rqr interface{}, c Condition) (bool, error) {
+	doc, err := openDocument(rqr)
+	if err != nil {
+		return false, err
+	}
+	return re.checkRuleCondition(doc.rqr, c, nil)
+}
+
+func (re *ruleEngine) checkRuleCondition(rqr interface{}, c Condition, ct *ConditionTrace) (_ bool, err error) {
+	defer recoverError(&err)
+
 	switch c.Type {
 	case RuleConditionType.DAY_OF_WEEK:
-		return re.compareDayOfWeek(rqr, c)
+		return re.compareDayOfWeek(rqr, c, ct)
 	case RuleConditionType.DATE:
-		return re.compareDate(rqr, c)
+		return re.compareDate(rqr, c, ct)
 	case RuleConditionType.STRING:
-		return re.compareGenericString(rqr, c)
+		return re.compareGenericString(rqr, c, ct)
 	case RuleConditionType.INT:
-		return re.compareGenericInt(rqr, c)
+		return re.compareGenericInt(rqr, c, ct)
+	case RuleConditionType.DECIMAL:
+		return re.compareDecimal(rqr, c, ct)
 	case RuleConditionType.MUST:
 		return true, nil
 	}
 	return false, RuleSettingError.UNSUPPORTED_OPERATION
 }
 
-// CreateRuleSettings Insert Rules into db
-func (re *ruleEngine) CreateRuleSettings(tx *gorm.DB, rs []RuleSetting) error {
-	for _, rule := range rs {
-		ruleDB, err := rule.MakeDBObject()
-		if err != nil {
-			return err
-		}
-		ruleDB.ID = uuid.NewV4().String()
-		err = tx.Table(DB_TABLE_RULE).Create(ruleDB).Error
-		if err != nil {
-			return err
-		}
+// CheckConditionGroup Check if the result fit the condition group and its nested groups
+func (re *ruleEngine) CheckConditionGroup(rqr interface{}, g ConditionGroup) (bool, error) {
+	doc, err := openDocument(rqr)
+	if err != nil {
+		return false, err
 	}
-	return nil
+	return re.checkConditionGroup(reflect.Indirect(reflect.ValueOf(doc.rqr)).Interface(), g, nil)
 }
 
-// FetchRuleSettings fetch Rule set with id
-func (re *ruleEngine) FetchRuleSettings(tx *gorm.DB, id string) ([]RuleSetting, error) {
-	var rsdb []RuleSettingDB
-	err := tx.Table(DB_TABLE_RULE).Where("rule_id = ?", id).Scan(&rsdb).Error
-	if err != nil {
-		return nil, err
+func (re *ruleEngine) checkConditionGroup(rqr interface{}, g ConditionGroup, gt *GroupTrace) (bool, error) {
+	result, err := re.evaluateConditionGroup(rqr, g, gt)
+	gt.done(result, err)
+	return result, err
+}
+
+// evaluateConditionGroup members are checked in order, stopping as soon as the group is decided
+func (re *ruleEngine) evaluateConditionGroup(rqr interface{}, g ConditionGroup, gt *GroupTrace) (bool, error) {
+	var decisive bool
+	switch g.Mode {
+	case ConditionGroupMode.ALL:
+		decisive = false
+	case ConditionGroupMode.ANY, ConditionGroupMode.NONE:
+		decisive = true
+	default:
+		return false, RuleSettingError.UNSUPPORTED_OPERATION
 	}
 
-	rs := make([]RuleSetting, len(rsdb))
-	for i, ruledb := range rsdb {
-		rule, err := ruledb.MakeObject()
+	for i, condition := range g.Conditions {
+		ct := gt.condition(i, condition)
+		result, err := re.checkRuleCondition(rqr, condition, ct)
+		ct.done(result, err)
 		if err != nil {
-			return nil, err
+			return false, err
+		}
+		if result == decisive {
+			return g.Mode == ConditionGroupMode.ANY, nil
+		}
+	}
+	for _, group := range g.Groups {
+		result, err := re.checkConditionGroup(rqr, group, gt.group(group))
+		if err != nil {
+			return false, err
+		}
+		if result == decisive {
+			return g.Mode == ConditionGroupMode.ANY, nil
 		}
-		rs[i] = *rule
 	}
-	return rs, nil
+	return g.Mode != ConditionGroupMode.ANY, nil
 }
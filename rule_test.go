@@ -0,0 +1,455 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+// stubSupply rule sets by RuleID, fetched from start as a Supply must
+type stubSupply map[string][]RuleSetting
+
+func (sp stubSupply) SaveRuleSettings(rs []RuleSetting) error {
+	for _, setting := range rs {
+		sp[setting.RuleID] = append(sp[setting.RuleID], setting)
+	}
+	return nil
+}
+
+func (sp stubSupply) FetchRuleSettings(id string, start int) ([]RuleSetting, error) {
+	settings, ok := sp[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", RuleSettingError.RULE_NOT_FOUND, id)
+	}
+	var rs []RuleSetting
+	for _, setting := range settings {
+		if setting.Sequence >= int64(start) {
+			rs = append(rs, setting)
+		}
+	}
+	return rs, nil
+}
+
+type booking struct {
+	Adults int64
+	Price  int64
+	Name   string
+	Tag    string
+}
+
+func setting(ruleID string, seq int64, conditions []Condition, modifers ...Modifer) RuleSetting {
+	return RuleSetting{
+		ID:       fmt.Sprintf("%s-%d", ruleID, seq),
+		Enable:   true,
+		Sequence: seq,
+		RuleID:   ruleID,
+		Rule:     Rule{ConditionChain: conditions, ModiferChain: modifers},
+	}
+}
+
+func intCondition(field string, compare CompareCode, value string) Condition {
+	return Condition{
+		Type:      RuleConditionType.INT,
+		LeftSide:  field,
+		LeftType:  ConditionSideType.FIELD,
+		Compare:   compare,
+		RightSide: value,
+		RightType: ConditionSideType.VALUE,
+	}
+}
+
+// intModifer field = field operand value
+func intModifer(operand OperandCode, field string, value string) Modifer {
+	return Modifer{
+		Operand:     operand,
+		DataType:    ModiferDataType.INT,
+		LeftSide:    field,
+		LeftType:    ModiferSideType.FIELD,
+		RightSide:   value,
+		RightType:   ModiferSideType.VALUE,
+		TargetField: field,
+	}
+}
+
+func jumpModifer(dataType DataTypeCode, ruleID string, start int) Modifer {
+	return Modifer{DataType: dataType, LeftSide: ruleID, RightSide: fmt.Sprint(start)}
+}
+
+func TestApplySettings(t *testing.T) {
+	re := NewEngine(stubSupply{})
+	rs := []RuleSetting{
+		setting("r", 1, []Condition{intCondition("Adults", RuleConditionCompare.MORE_EQUAL, "2")},
+			intModifer(RuleOperand.ADD, "Price", "10")),
+		setting("r", 2, []Condition{intCondition("Adults", RuleConditionCompare.MORE, "2")},
+			intModifer(RuleOperand.ADD, "Price", "100")),
+		setting("r", 3, nil, intModifer(RuleOperand.MLT, "Price", "2")),
+	}
+
+	b := &booking{Adults: 2, Price: 100}
+	result, err := re.ApplySettings(b, rs)
+	if err != nil || !result {
+		t.Fatalf("ApplySettings = %v, %v", result, err)
+	}
+	if b.Price != 220 {
+		t.Errorf("Price = %d, want 220", b.Price)
+	}
+
+	rs[1].BreakOnFail = true
+	b = &booking{Adults: 2, Price: 100}
+	result, err = re.ApplySettings(b, rs)
+	if err != nil || result {
+		t.Fatalf("ApplySettings with a failing BreakOnFail = %v, %v", result, err)
+	}
+	if b.Price != 110 {
+		t.Errorf("Price = %d, want 110, the settings after the break left out", b.Price)
+	}
+
+	rs[0], rs[1] = rs[1], rs[0]
+	if _, err := re.ApplySettings(&booking{}, rs); err != RuleSettingError.SETTING_NOT_IN_ORDER {
+		t.Errorf("ApplySettings out of order = %v, want SETTING_NOT_IN_ORDER", err)
+	}
+}
+
+func TestCheckConditionGroup(t *testing.T) {
+	one := intCondition("Adults", RuleConditionCompare.EQUAL, "1")
+	two := intCondition("Adults", RuleConditionCompare.EQUAL, "2")
+	missing := intCondition("Missing", RuleConditionCompare.EQUAL, "1")
+	cases := []struct {
+		name string
+		g    ConditionGroup
+		want bool
+		err  error
+	}{
+		{"ANY of one true", ConditionGroup{Mode: ConditionGroupMode.ANY, Conditions: []Condition{one, two}}, true, nil},
+		{"ANY of none true", ConditionGroup{Mode: ConditionGroupMode.ANY, Conditions: []Condition{one}}, false, nil},
+		{"ALL of one false", ConditionGroup{Mode: ConditionGroupMode.ALL, Conditions: []Condition{one, two}}, false, nil},
+		{"ALL true", ConditionGroup{Mode: ConditionGroupMode.ALL, Conditions: []Condition{two, two}}, true, nil},
+		{"NONE true", ConditionGroup{Mode: ConditionGroupMode.NONE, Conditions: []Condition{one}}, true, nil},
+		{"NONE of one true", ConditionGroup{Mode: ConditionGroupMode.NONE, Conditions: []Condition{one, two}}, false, nil},
+		{"empty ANY", ConditionGroup{Mode: ConditionGroupMode.ANY}, false, nil},
+		{"empty ALL", ConditionGroup{Mode: ConditionGroupMode.ALL}, true, nil},
+		{"empty NONE", ConditionGroup{Mode: ConditionGroupMode.NONE}, true, nil},
+		{"nested (1 OR 2) AND NOT 1", ConditionGroup{Mode: ConditionGroupMode.ALL, Groups: []ConditionGroup{
+			{Mode: ConditionGroupMode.ANY, Conditions: []Condition{one, two}},
+			{Mode: ConditionGroupMode.NONE, Conditions: []Condition{one}},
+		}}, true, nil},
+		{"NONE of a true group", ConditionGroup{Mode: ConditionGroupMode.NONE, Groups: []ConditionGroup{
+			{Mode: ConditionGroupMode.ANY, Conditions: []Condition{two}},
+		}}, false, nil},
+		{"decided before an error", ConditionGroup{Mode: ConditionGroupMode.ANY, Conditions: []Condition{two, missing}}, true, nil},
+		{"an error before deciding", ConditionGroup{Mode: ConditionGroupMode.ANY, Conditions: []Condition{one, missing}}, false, RuleSettingError.MODIFER_FEILD_NOT_EXISTED},
+		{"unknown mode", ConditionGroup{Mode: 9, Conditions: []Condition{two}}, false, RuleSettingError.UNSUPPORTED_OPERATION},
+	}
+	re := NewEngine(stubSupply{})
+	for _, c := range cases {
+		got, err := re.CheckConditionGroup(&booking{Adults: 2}, c.g)
+		if got != c.want || !errors.Is(err, c.err) || (err == nil) != (c.err == nil) {
+			t.Errorf("%s: CheckConditionGroup = %v, %v, want %v, %v", c.name, got, err, c.want, c.err)
+		}
+	}
+}
+
+func TestConditionTree(t *testing.T) {
+	rs := setting("r", 1, []Condition{intCondition("Adults", RuleConditionCompare.MORE, "0")},
+		intModifer(RuleOperand.ADD, "Price", "10"))
+	rs.Rule.ConditionTree = &ConditionGroup{Mode: ConditionGroupMode.ANY, Conditions: []Condition{
+		intCondition("Adults", RuleConditionCompare.EQUAL, "1"),
+		intCondition("Price", RuleConditionCompare.MORE_EQUAL, "100"),
+	}}
+	re := NewEngine(stubSupply{})
+
+	for _, c := range []struct {
+		b     booking
+		price int64
+	}{
+		{booking{Adults: 1}, 10},
+		{booking{Adults: 2, Price: 100}, 110},
+		{booking{Adults: 2, Price: 50}, 50},
+		{booking{Adults: 0, Price: 100}, 100}, // the chain fails first
+	} {
+		b := c.b
+		result, _, tr, err := re.ExplainSetting(&b, rs)
+		if err != nil || result != (b.Price != c.b.Price) || b.Price != c.price {
+			t.Errorf("%+v: Price %d, %v, %v, want %d", c.b, b.Price, result, err, c.price)
+		}
+		if c.b.Adults > 0 && (tr.Settings[0].Tree == nil || tr.Settings[0].Tree.Result != result) {
+			t.Errorf("%+v: tree traced as %+v", c.b, tr.Settings[0].Tree)
+		}
+	}
+
+	var r Rule
+	if err := json.Unmarshal([]byte(`{"condition_chain":[],"rate_modifer":[]}`), &r); err != nil || r.ConditionTree != nil {
+		t.Errorf("a rule without condition_tree reads as %+v, %v", r.ConditionTree, err)
+	}
+}
+
+func strategies() map[string][]Option {
+	return map[string][]Option{
+		"SEQUENTIAL":  {WithConditionStrategy(ConditionStrategy.SEQUENTIAL)},
+		"PARALLEL":    {WithConditionStrategy(ConditionStrategy.PARALLEL)},
+		"PARALLEL 1":  {WithConditionStrategy(ConditionStrategy.PARALLEL), WithParallelism(1)},
+		"PARALLEL 2":  {WithConditionStrategy(ConditionStrategy.PARALLEL), WithParallelism(2)},
+		"PARALLEL 0":  {WithConditionStrategy(ConditionStrategy.PARALLEL), WithParallelism(0)},
+		"PARALLEL -1": {WithConditionStrategy(ConditionStrategy.PARALLEL), WithParallelism(-1)},
+		"PARALLEL 64": {WithConditionStrategy(ConditionStrategy.PARALLEL), WithParallelism(64)},
+	}
+}
+
+func TestConditionStrategiesAgree(t *testing.T) {
+	sp, rs := explainSample()
+	rs = append(rs, setting("r", 3, []Condition{
+		intCondition("Adults", RuleConditionCompare.LESS, "5"),
+		intCondition("Price", RuleConditionCompare.IN, "115,120"),
+		{Type: RuleConditionType.STRING, LeftSide: "Name", LeftType: ConditionSideType.FIELD, Compare: RuleConditionCompare.NOT, RightSide: "x", RightType: ConditionSideType.VALUE},
+	}, intModifer(RuleOperand.MLT, "Price", "3")))
+
+	for adults := int64(0); adults < 4; adults++ {
+		want := &booking{Adults: adults, Price: 100}
+		wantResult, wantTrace, wantErr := NewEngine(sp).ExplainSettings(want, rs)
+		for name, opts := range strategies() {
+			got := &booking{Adults: adults, Price: 100}
+			result, tr, err := NewEngine(sp, opts...).ExplainSettings(got, rs)
+			if result != wantResult || err != wantErr || *got != *want {
+				t.Errorf("%s, %d adults: %v, %v, %+v, SEQUENTIAL %v, %v, %+v", name, adults, result, err, got, wantResult, wantErr, want)
+			}
+			for i, st := range tr.Settings {
+				if st.Matched != wantTrace.Settings[i].Matched || st.Result != wantTrace.Settings[i].Result {
+					t.Errorf("%s, %d adults: setting %d traced as %+v, SEQUENTIAL %+v", name, adults, i, st, wantTrace.Settings[i])
+				}
+			}
+		}
+	}
+}
+
+func TestConditionStrategiesFirstError(t *testing.T) {
+	chain := []Condition{
+		intCondition("Adults", RuleConditionCompare.MORE, "1"),
+		intCondition("Missing1", RuleConditionCompare.MORE, "1"),
+		intCondition("Adults", RuleConditionCompare.MORE, "5"),
+		intCondition("Missing3", RuleConditionCompare.MORE, "1"),
+		{Type: 42},
+	}
+	for name, opts := range strategies() {
+		re := NewEngine(stubSupply{}, opts...)
+		for n := 0; n < 50; n++ {
+			_, _, err := re.ApplySetting(&booking{Adults: 2}, setting("r", 1, chain))
+			var rerr *RuleError
+			if !errors.As(err, &rerr) || rerr.Condition != 1 || rerr.Field != "Missing1" {
+				t.Fatalf("%s: ApplySetting = %v, want the error of condition 1", name, err)
+			}
+
+			// a false condition ahead of a failing one decides
+			result, _, err := re.ApplySetting(&booking{Adults: 2}, setting("r", 1, chain[2:]))
+			if result || err != nil {
+				t.Fatalf("%s: ApplySetting = %v, %v, want false from condition 0", name, result, err)
+			}
+		}
+	}
+
+	_, tr, err := NewEngine(stubSupply{}).ExplainSettings(&booking{Adults: 2}, []RuleSetting{setting("r", 1, chain[2:])})
+	if err != nil || len(tr.Settings[0].Conditions) != 1 {
+		t.Errorf("SEQUENTIAL traced %d conditions, %v, want the one checked", len(tr.Settings[0].Conditions), err)
+	}
+	_, tr, err = NewEngine(stubSupply{}, WithConditionStrategy(ConditionStrategy.PARALLEL)).ExplainSettings(&booking{Adults: 2}, []RuleSetting{setting("r", 1, chain[2:])})
+	if err != nil || len(tr.Settings[0].Conditions) != 3 || tr.Settings[0].Conditions[1].Error == "" {
+		t.Errorf("PARALLEL traced %d conditions, %v, want every one checked", len(tr.Settings[0].Conditions), err)
+	}
+
+	if _, _, err := NewEngine(stubSupply{}, WithConditionStrategy(9)).ApplySetting(&booking{}, setting("r", 1, chain)); !errors.Is(err, RuleSettingError.UNSUPPORTED_OPERATION) {
+		t.Errorf("an unknown strategy = %v, want UNSUPPORTED_OPERATION", err)
+	}
+}
+
+func TestConditionStrategiesCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rs := setting("r", 1, []Condition{intCondition("Adults", RuleConditionCompare.EQUAL, "2"), intCondition("Price", RuleConditionCompare.EQUAL, "0")})
+	for name, opts := range strategies() {
+		result, _, err := NewEngine(stubSupply{}, opts...).ApplySettingContext(ctx, &booking{Adults: 2}, rs)
+		if result || !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: ApplySettingContext = %v, %v, want context.Canceled", name, result, err)
+		}
+	}
+}
+
+func TestConditionStrategiesConcurrent(t *testing.T) {
+	// one engine, many requests at once, for go test -race
+	_, rs := explainSample()
+	rs = rs[1:]
+	for name, opts := range strategies() {
+		re := NewEngine(stubSupply{}, opts...)
+		var wg sync.WaitGroup
+		for n := 0; n < 16; n++ {
+			wg.Add(1)
+			go func(adults int64) {
+				defer wg.Done()
+				b := &booking{Adults: adults % 3, Price: 100}
+				if _, tr, err := re.ExplainSettings(b, rs); err != nil || len(tr.Settings) != 1 {
+					t.Errorf("%s: ExplainSettings = %v", name, err)
+				}
+				if want := map[int64]int64{1: 50, 0: 100, 2: 100}[adults%3]; b.Price != want {
+					t.Errorf("%s: Price %d, want %d", name, b.Price, want)
+				}
+			}(int64(n))
+		}
+		wg.Wait()
+	}
+}
+
+func TestParallelismBound(t *testing.T) {
+	chain := make([]Condition, 2000)
+	for i := range chain {
+		chain[i] = intCondition("Adults", RuleConditionCompare.IN, "0,1,2,3,4,5,6,7,8,9")
+	}
+	for _, limit := range []int{1, 3} {
+		done := make(chan struct{})
+		peak := make(chan int)
+		base := runtime.NumGoroutine() + 1 // and the sampler
+		go func() {
+			max := 0
+			for {
+				select {
+				case <-done:
+					peak <- max
+					return
+				default:
+				}
+				if n := runtime.NumGoroutine(); n > max {
+					max = n
+				}
+				runtime.Gosched()
+			}
+		}()
+		re := NewEngine(stubSupply{}, WithConditionStrategy(ConditionStrategy.PARALLEL), WithParallelism(limit))
+		result, _, err := re.ApplySetting(&booking{Adults: 2}, setting("r", 1, chain))
+		close(done)
+		if n := <-peak - base; n > limit {
+			t.Errorf("WithParallelism(%d): %d conditions checked at once", limit, n)
+		}
+		if !result || err != nil {
+			t.Errorf("WithParallelism(%d): ApplySetting = %v, %v", limit, result, err)
+		}
+	}
+}
+
+// disabledSettings a rule set where the disabled setting would both change Price and, failing its
+// condition with BreakOnFail, stop the settings after it
+func disabledSettings() []RuleSetting {
+	disabled := setting("r", 2, []Condition{intCondition("Adults", RuleConditionCompare.MORE, "5")},
+		intModifer(RuleOperand.MLT, "Price", "2"))
+	disabled.Enable = false
+	disabled.BreakOnFail = true
+	return []RuleSetting{
+		setting("r", 1, nil, intModifer(RuleOperand.ADD, "Price", "10")),
+		disabled,
+		setting("r", 3, nil, intModifer(RuleOperand.ADD, "Price", "1")),
+	}
+}
+
+func TestDisabledSettings(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		opts  []Option
+		price int64
+		skip  int
+	}{
+		{"Skipped", nil, 111, SettingSkip.DISABLED},
+		{"Included", []Option{WithIncludeDisabled(true)}, 110, SettingSkip.NONE},
+		{"IncludedOff", []Option{WithIncludeDisabled(false)}, 111, SettingSkip.DISABLED},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			b := &booking{Adults: 2, Price: 100}
+			if _, err := NewEngine(stubSupply{}, tc.opts...).ApplySettings(b, disabledSettings()); err != nil {
+				t.Fatal(err)
+			}
+			if b.Price != tc.price {
+				t.Fatalf("Price = %d, want %d", b.Price, tc.price)
+			}
+
+			_, tr, err := NewEngine(stubSupply{}, tc.opts...).ExplainSettings(&booking{Adults: 2, Price: 100}, disabledSettings())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tr.Settings) < 2 || tr.Settings[1].ID != "r-2" || tr.Settings[1].Skipped != tc.skip {
+				t.Fatalf("traced as %+v, want r-2 skipped as %d", tr.Settings, tc.skip)
+			}
+
+			cs, err := NewEngine(stubSupply{}, tc.opts...).Compile(disabledSettings(), reflect.TypeOf(booking{}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			b = &booking{Adults: 2, Price: 100}
+			if _, err := cs.Apply(b); err != nil {
+				t.Fatal(err)
+			}
+			if b.Price != tc.price {
+				t.Fatalf("compiled: Price = %d, want %d", b.Price, tc.price)
+			}
+		})
+	}
+}
+
+func TestDisabledSettingsCompileError(t *testing.T) {
+	rs := disabledSettings()
+	rs[1].Rule.ConditionChain = []Condition{intCondition("Missing", RuleConditionCompare.EQUAL, "1")}
+
+	cs, err := NewEngine(stubSupply{}).Compile(rs, reflect.TypeOf(booking{}))
+	if err != nil {
+		t.Fatalf("Compile = %v, want the broken disabled setting skipped", err)
+	}
+	b := &booking{Adults: 2, Price: 100}
+	if _, err := cs.Apply(b); err != nil || b.Price != 111 {
+		t.Errorf("Apply = %v and Price %d, want 111", err, b.Price)
+	}
+
+	if _, err := NewEngine(stubSupply{}, WithIncludeDisabled(true)).Compile(rs, reflect.TypeOf(booking{})); !errors.Is(err, RuleSettingError.MODIFER_FEILD_NOT_EXISTED) {
+		t.Errorf("Compile with WithIncludeDisabled = %v, want MODIFER_FEILD_NOT_EXISTED", err)
+	}
+}
+
+func TestDisabledSetting(t *testing.T) {
+	rs := setting("r", 1, nil, intModifer(RuleOperand.ADD, "Price", "10"))
+	rs.Enable = false
+
+	b := &booking{Price: 100}
+	result, br, err := NewEngine(stubSupply{}).ApplySetting(b, rs)
+	if err != nil || result || br || b.Price != 100 {
+		t.Fatalf("ApplySetting = %v, %v, %v and Price %d, want the disabled setting left out", result, br, err, b.Price)
+	}
+	result, _, err = NewEngine(stubSupply{}, WithIncludeDisabled(true)).ApplySetting(b, rs)
+	if err != nil || !result || b.Price != 110 {
+		t.Fatalf("ApplySetting = %v, %v and Price %d, want the disabled setting applied", result, err, b.Price)
+	}
+}
+
+func TestDisabledSettingsAcrossJumps(t *testing.T) {
+	sp := stubSupply{"r": disabledSettings()}
+	entry := []RuleSetting{setting("entry", 1, nil, jumpModifer(ModiferDataType.JRT, "r", 0))}
+
+	for _, tc := range []struct {
+		name  string
+		opts  []Option
+		price int64
+	}{
+		{"Skipped", nil, 111},
+		{"Included", []Option{WithIncludeDisabled(true)}, 110},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			b := &booking{Adults: 2, Price: 100}
+			if _, err := NewEngine(sp, tc.opts...).ApplySettings(b, entry); err != nil {
+				t.Fatal(err)
+			}
+			if b.Price != tc.price {
+				t.Fatalf("Price = %d, want %d", b.Price, tc.price)
+			}
+		})
+	}
+}
@@ -0,0 +1,253 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// JSONSchema a JSON Schema (draft 2020-12) of a rule set as MarshalRuleSettings writes it, an array of RuleSetting.
+// Codes may be written by name or number. Beyond the shape it holds what Validate checks without a request type:
+// the compares of each condition type, the operands of each data type, the side types, a COMPLEX side holding
+// ModiferComplex JSON or, as YAML and TOML write it, the object itself, and JMP and JRT naming a rule set and an
+// integer start. Field paths and values are left to Validate
+func JSONSchema() ([]byte, error) {
+	return json.MarshalIndent(jsonSchema(), "", "  ")
+}
+
+type schema = map[string]interface{}
+
+func jsonSchema() schema {
+	ref := func(name string) schema {
+		return schema{"$ref": "#/$defs/" + name}
+	}
+	nullable := func(s schema) schema {
+		return schema{"oneOf": []interface{}{schema{"type": "null"}, s}}
+	}
+	list := func(items schema) schema {
+		return schema{"type": []string{"array", "null"}, "items": items}
+	}
+	code := schema{"type": []string{"string", "integer"}}
+
+	defs := schema{
+		"CompareCode":        schema{"enum": compareNames.enum(compareNames.all()...)},
+		"ConditionTypeCode":  schema{"enum": conditionTypeNames.enum(conditionTypeNames.all()...)},
+		"ConditionSideCode":  schema{"enum": sideNames.enum(ConditionSideType.FIELD, ConditionSideType.VALUE)},
+		"ModiferSideCode":    schema{"enum": sideNames.enum(sideNames.all()...)},
+		"OperandCode":        schema{"enum": operandNames.enum(operandNames.all()...)},
+		"DataTypeCode":       schema{"enum": dataTypeNames.enum(dataTypeNames.all()...)},
+		"ConditionGroupMode": schema{"enum": groupModeNames.enum(groupModeNames.all()...)},
+		"RoundingMode":       schema{"enum": roundingModeNames.enum(roundingModeNames.all()...)},
+		"RuleSetting": schema{
+			"type": "object",
+			"properties": schema{
+				"id":            schema{"type": "string"},
+				"enable":        schema{"type": "boolean"},
+				"break_on_fail": schema{"type": "boolean"},
+				"sequence":      schema{"type": "integer"},
+				"rule_id":       schema{"type": "string"},
+				"rule_type":     schema{"type": "integer"},
+				"valid_from":    schema{"type": []string{"string", "null"}, "format": "date-time"},
+				"valid_to":      schema{"type": []string{"string", "null"}, "format": "date-time"},
+				"rule":          ref("Rule"),
+			},
+			"additionalProperties": false,
+		},
+		"Rule": schema{
+			"type": "object",
+			"properties": schema{
+				"condition_chain": list(ref("Condition")),
+				"condition_tree":  nullable(ref("ConditionGroup")),
+				"rate_modifer":    list(ref("Modifer")),
+			},
+			"additionalProperties": false,
+		},
+		"ConditionGroup": schema{
+			"type": "object",
+			"properties": schema{
+				"mode":       ref("ConditionGroupMode"),
+				"conditions": list(ref("Condition")),
+				"groups":     list(ref("ConditionGroup")),
+			},
+			"additionalProperties": false,
+		},
+		"Condition": schema{
+			"type": "object",
+			"properties": schema{
+				"type":       ref("ConditionTypeCode"),
+				"left_side":  schema{"type": "string"},
+				"left_type":  code,
+				"compare":    ref("CompareCode"),
+				"right_side": schema{"type": "string"},
+				"right_type": code,
+			},
+			"additionalProperties": false,
+			"allOf":                conditionRules(),
+		},
+		"Modifer": schema{
+			"type": "object",
+			"properties": schema{
+				"sequence":     schema{"type": "integer"},
+				"operand":      ref("OperandCode"),
+				"data_type":    ref("DataTypeCode"),
+				"left_side":    schema{"type": []string{"string", "object"}},
+				"left_type":    code,
+				"right_side":   schema{"type": []string{"string", "object"}},
+				"right_type":   code,
+				"target_field": schema{"type": "string"},
+				"rounding":     nullable(ref("Rounding")),
+			},
+			"additionalProperties": false,
+			"allOf":                modiferRules(),
+		},
+		"Rounding": schema{
+			"type": "object",
+			"properties": schema{
+				"mode":   ref("RoundingMode"),
+				"places": schema{"type": "integer"},
+			},
+			"additionalProperties": false,
+		},
+		"ModiferComplex":        complexSchema(schema{"type": "integer"}),
+		"DecimalModiferComplex": complexSchema(schema{"type": []string{"number", "string"}, "pattern": `^[+-]?([0-9]+\.?[0-9]*|\.[0-9]+)([eE][+-]?[0-9]+)?$`}),
+		"JSONmap": schema{
+			"type": "object",
+			"properties": schema{
+				"name":  schema{"type": "string"},
+				"key":   schema{"type": "string"},
+				"value": schema{"type": "string"},
+			},
+			"additionalProperties": false,
+		},
+		"ComplexSide":        complexSideSchema("ModiferComplex"),
+		"DecimalComplexSide": complexSideSchema("DecimalModiferComplex"),
+	}
+
+	return schema{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title":   "Rule set",
+		"type":    "array",
+		"items":   ref("RuleSetting"),
+		"$defs":   defs,
+	}
+}
+
+// complexSchema a ModiferComplex whose flat and percentage are amount
+func complexSchema(amount schema) schema {
+	return schema{
+		"type": "object",
+		"properties": schema{
+			"flat":       amount,
+			"percentage": amount,
+			"select":     schema{"type": []string{"array", "null"}, "items": schema{"$ref": "#/$defs/JSONmap"}},
+		},
+		"additionalProperties": false,
+	}
+}
+
+// complexSideSchema a COMPLEX side, the JSON of def in a string or def itself
+func complexSideSchema(def string) schema {
+	return schema{
+		"oneOf": []interface{}{
+			schema{
+				"type":             "string",
+				"pattern":          `^\s*\{`,
+				"contentMediaType": "application/json",
+				"contentSchema":    schema{"$ref": "#/$defs/" + def},
+			},
+			schema{"$ref": "#/$defs/" + def},
+		},
+	}
+}
+
+// codeIs a condition on property holding one of codes, a property left out reads as code 0
+func codeIs[T ~int](cn codeNames[T], property string, codes ...T) schema {
+	s := schema{"properties": schema{property: schema{"enum": cn.enum(codes...)}}}
+	for _, code := range codes {
+		if code == 0 {
+			return s
+		}
+	}
+	s["required"] = []string{property}
+	return s
+}
+
+func ifThen(cond, then schema) schema {
+	return schema{"if": cond, "then": then}
+}
+
+func sides(s schema, names ...string) schema {
+	properties := schema{}
+	for _, name := range names {
+		properties[name] = s
+	}
+	return schema{"properties": properties}
+}
+
+// conditionRules what Validate asks of a condition, a MUST condition reads nothing else
+func conditionRules() []interface{} {
+	var types []ConditionTypeCode
+	for t := range conditionCompares {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		return types[i] < types[j]
+	})
+
+	rules := []interface{}{
+		ifThen(schema{"not": codeIs(conditionTypeNames, "type", RuleConditionType.MUST)},
+			sides(schema{"$ref": "#/$defs/ConditionSideCode"}, "left_type", "right_type")),
+	}
+	for _, t := range types {
+		rules = append(rules, ifThen(codeIs(conditionTypeNames, "type", t),
+			schema{"properties": schema{"compare": schema{"enum": compareNames.enum(conditionCompares[t]...)}}}))
+	}
+	return rules
+}
+
+// modiferRules what Validate asks of a modifer, a JMP or JRT reads nothing but the rule set and start
+func modiferRules() []interface{} {
+	jump := codeIs(dataTypeNames, "data_type", ModiferDataType.JMP, ModiferDataType.JRT)
+	decimal := codeIs(dataTypeNames, "data_type", ModiferDataType.DECIMAL)
+
+	var types []DataTypeCode
+	for t := range modiferOperands {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		return types[i] < types[j]
+	})
+
+	rules := []interface{}{
+		ifThen(jump, schema{"properties": schema{
+			"left_side":  schema{"type": "string", "minLength": 1},
+			"right_side": schema{"type": "string", "pattern": `^[+-]?[0-9]+$`},
+		}}),
+	}
+	for _, t := range types {
+		rules = append(rules, ifThen(codeIs(dataTypeNames, "data_type", t),
+			schema{"properties": schema{"operand": schema{"enum": operandNames.enum(modiferOperands[t]...)}}}))
+	}
+
+	for _, side := range []string{"left", "right"} {
+		complex := codeIs(sideNames, side+"_type", ModiferSideType.COMPLEX)
+		rules = append(rules,
+			ifThen(schema{"allOf": []interface{}{complex, schema{"not": decimal}}}, sides(schema{"$ref": "#/$defs/ComplexSide"}, side+"_side")),
+			ifThen(schema{"allOf": []interface{}{complex, decimal}}, sides(schema{"$ref": "#/$defs/DecimalComplexSide"}, side+"_side")),
+			ifThen(schema{"not": complex}, sides(schema{"type": "string"}, side+"_side")),
+		)
+	}
+
+	// a side the operand does not read keeps whatever type it was left with
+	unread := map[string]schema{
+		"left":  codeIs(operandNames, "operand", RuleOperand.SEL, RuleOperand.SUM),
+		"right": codeIs(operandNames, "operand", RuleOperand.SET),
+	}
+	for _, side := range []string{"left", "right"} {
+		rules = append(rules, ifThen(schema{"not": schema{"anyOf": []interface{}{jump, unread[side]}}},
+			sides(schema{"$ref": "#/$defs/ModiferSideCode"}, side+"_type")))
+	}
+	return rules
+}
@@ -0,0 +1,196 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"sigs.k8s.io/yaml"
+)
+
+// compileSchema JSONSchema as a validator would load it, formats and the JSON held in COMPLEX strings asserted
+func compileSchema(t *testing.T) *jsonschema.Schema {
+	t.Helper()
+	b, err := JSONSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := jsonschema.NewCompiler()
+	c.Draft = jsonschema.Draft2020
+	c.AssertFormat = true
+	c.AssertContent = true
+	if err := c.AddResource("rules.json", bytes.NewReader(b)); err != nil {
+		t.Fatal(err)
+	}
+	s, err := c.Compile("rules.json")
+	if err != nil {
+		t.Fatalf("the schema does not compile: %v", err)
+	}
+	return s
+}
+
+// sampleRuleSets the rule sets the other tests run. The DSL samples cover every form the parser reads, some of them
+// rules Validate goes on to reject, so only those it accepts are held to the schema
+func sampleRuleSets() (samples map[string][]RuleSetting, dsl map[string][]RuleSetting) {
+	_, explained := explainSample()
+	from, to := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	valid := setting("promo", 1, nil, intModifer(RuleOperand.ADD, "Price", "10"))
+	valid.ValidFrom, valid.ValidTo = &from, &to
+	complex := func(dataType DataTypeCode, operand OperandCode, left, right string) Modifer {
+		return Modifer{Operand: operand, DataType: dataType, LeftSide: left, LeftType: ModiferSideType.FIELD,
+			RightSide: right, RightType: ModiferSideType.COMPLEX, TargetField: left}
+	}
+	discount := setting("rates", 1, []Condition{intCondition("Adults", RuleConditionCompare.MORE_EQUAL, "2")},
+		withRounding(complex(ModiferDataType.INT, RuleOperand.SUB, "Price", `{"flat":500,"percentage":10}`), &Rounding{Mode: RoundingMode.HALF_UP, Places: -2}))
+	discount.Rule.ConditionTree = &ConditionGroup{
+		Mode:       ConditionGroupMode.ANY,
+		Conditions: []Condition{intCondition("Price", RuleConditionCompare.LESS, "1000")},
+		Groups:     []ConditionGroup{{Mode: ConditionGroupMode.NONE, Conditions: []Condition{intCondition("Adults", RuleConditionCompare.MORE, "8")}}},
+	}
+	fees := setting("rates", 2, nil,
+		complex(ModiferDataType.DECIMAL, RuleOperand.ADD, "Rate", `{"flat":"1.5","percentage":"2.25"}`),
+		complex(ModiferDataType.STRING, RuleOperand.SEL, "Tier", `{"select":[{"key":"gold","name":"Gold","value":"vip"}]}`))
+	fees.Rule.ModiferChain[1].Sequence = 1
+
+	samples = map[string][]RuleSetting{
+		"Complex":   {discount, fees},
+		"Explained": explained,
+		"Disabled":  disabledSettings(),
+		"Validity":  {valid},
+		"Jumps": {
+			setting("jumps", 1, nil, jumpModifer(ModiferDataType.JRT, "pricing/weekend", -5)),
+			setting("jumps", 2, nil, jumpModifer(ModiferDataType.JMP, "fees", 0)),
+		},
+		"Empty": {},
+	}
+	dsl = make(map[string][]RuleSetting)
+	for name, c := range dslConditions() {
+		dsl["Condition"+name] = []RuleSetting{setting("dsl", 1, []Condition{c})}
+	}
+	for name, m := range dslModifers() {
+		dsl["Modifer"+name] = []RuleSetting{setting("dsl", 1, nil, m)}
+	}
+	return samples, dsl
+}
+
+// asJSON the document of a YAML or TOML rule set as JSON, as a CI check would read the file, nothing rewritten
+func asJSON(b []byte, format int) ([]byte, error) {
+	if format == RuleFormat.YAML {
+		return yaml.YAMLToJSON(b)
+	}
+	var doc map[string]interface{}
+	if _, err := toml.Decode(string(b), &doc); err != nil {
+		return nil, err
+	}
+	settings, ok := doc[tomlSettingsKey]
+	if !ok {
+		settings = []interface{}{}
+	}
+	return json.Marshal(settings)
+}
+
+// validateFormats check rs against s as every format writes it
+func validateFormats(t *testing.T, s *jsonschema.Schema, rs []RuleSetting) {
+	t.Helper()
+	for _, format := range []int{RuleFormat.JSON, RuleFormat.YAML, RuleFormat.TOML} {
+		b, err := MarshalRuleSettings(rs, format)
+		if err == nil && format != RuleFormat.JSON {
+			b, err = asJSON(b, format)
+		}
+		if err != nil {
+			t.Fatalf("format %d: %v", format, err)
+		}
+		doc, err := decodeJSONDoc(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Validate(doc); err != nil {
+			t.Fatalf("format %d: %s\n%#v", format, b, err)
+		}
+	}
+}
+
+func TestJSONSchemaSamples(t *testing.T) {
+	s := compileSchema(t)
+	samples, dsl := sampleRuleSets()
+	for name, rs := range samples {
+		t.Run(name, func(t *testing.T) {
+			if err := Validate(rs, nil); err != nil {
+				t.Fatalf("the sample is not valid: %v", err)
+			}
+			validateFormats(t, s, rs)
+		})
+	}
+	for name, rs := range dsl {
+		t.Run(name, func(t *testing.T) {
+			if err := Validate(rs, nil); err != nil {
+				t.Skipf("Validate rejects the sample: %v", err)
+			}
+			validateFormats(t, s, rs)
+		})
+	}
+}
+
+func TestJSONSchemaRejects(t *testing.T) {
+	s := compileSchema(t)
+	setting := func(rule string) string {
+		return `[{"id":"a","rule_id":"r","enable":true,"sequence":1,"rule":` + rule + `}]`
+	}
+	condition := func(c string) string {
+		return setting(`{"condition_chain":[` + c + `],"rate_modifer":[]}`)
+	}
+	modifer := func(m string) string {
+		return setting(`{"condition_chain":[],"rate_modifer":[` + m + `]}`)
+	}
+
+	// each of the documents is valid but for the one thing named
+	if err := s.Validate(mustDecode(t, modifer(`{"operand":"ADD","data_type":"INT","left_side":"Price","left_type":"FIELD","right_side":"{\"flat\":5,\"percentage\":10}","right_type":"COMPLEX","target_field":"Price"}`))); err != nil {
+		t.Fatalf("the base modifer is rejected: %v", err)
+	}
+	for _, tc := range []struct {
+		name string
+		doc  string
+	}{
+		{"NotAnArray", `{"id":"a"}`},
+		{"UnknownSettingProperty", `[{"id":"a","priority":1}]`},
+		{"ValidFromNotATime", `[{"id":"a","valid_from":"tomorrow"}]`},
+		{"UnknownGroupMode", setting(`{"condition_tree":{"mode":"MOST"}}`)},
+		{"GroupModeNumber", setting(`{"condition_tree":{"mode":7}}`)},
+		{"UnknownCompare", condition(`{"type":"INT","left_side":"Adults","left_type":"FIELD","compare":"ABOUT","right_side":"2","right_type":"VALUE"}`)},
+		{"CompareOfAnotherType", condition(`{"type":"STRING","left_side":"Tag","left_type":"FIELD","compare":"MORE","right_side":"a","right_type":"VALUE"}`)},
+		{"ComplexConditionSide", condition(`{"type":"INT","left_side":"Adults","left_type":"FIELD","compare":"EQUAL","right_side":"2","right_type":"COMPLEX"}`)},
+		{"ConditionSideObject", condition(`{"type":"INT","left_side":"Adults","left_type":"FIELD","compare":"EQUAL","right_side":{"flat":1},"right_type":"VALUE"}`)},
+		{"OperandOfAnotherType", modifer(`{"operand":"DIV","data_type":"STRING","left_side":"Tag","left_type":"FIELD","right_side":"a","right_type":"VALUE","target_field":"Tag"}`)},
+		{"UnknownSideType", modifer(`{"operand":"ADD","data_type":"INT","left_side":"Price","left_type":"FIELD","right_side":"1","right_type":"CONSTANT","target_field":"Price"}`)},
+		{"ComplexNotJSON", modifer(`{"operand":"ADD","data_type":"INT","left_side":"Price","left_type":"FIELD","right_side":"{flat:5}","right_type":"COMPLEX","target_field":"Price"}`)},
+		{"ComplexText", modifer(`{"operand":"ADD","data_type":"INT","left_side":"Price","left_type":"FIELD","right_side":"Fee","right_type":"COMPLEX","target_field":"Price"}`)},
+		{"ComplexUnknownKey", modifer(`{"operand":"ADD","data_type":"INT","left_side":"Price","left_type":"FIELD","right_side":"{\"flat\":5,\"pct\":10}","right_type":"COMPLEX","target_field":"Price"}`)},
+		{"ComplexObjectFraction", modifer(`{"operand":"ADD","data_type":"INT","left_side":"Price","left_type":"FIELD","right_side":{"flat":5,"percentage":2.5},"right_type":"COMPLEX","target_field":"Price"}`)},
+		{"DecimalComplexNotANumber", modifer(`{"operand":"ADD","data_type":"DECIMAL","left_side":"Rate","left_type":"FIELD","right_side":{"flat":"five"},"right_type":"COMPLEX","target_field":"Rate"}`)},
+		{"ObjectSideNotComplex", modifer(`{"operand":"ADD","data_type":"INT","left_side":"Price","left_type":"FIELD","right_side":{"flat":5},"right_type":"VALUE","target_field":"Price"}`)},
+		{"UnknownRoundingMode", modifer(`{"operand":"DIV","data_type":"INT","left_side":"Price","left_type":"FIELD","right_side":"3","right_type":"VALUE","target_field":"Price","rounding":{"mode":"BANKERS","places":0}}`)},
+		{"JumpWithoutRuleSet", modifer(`{"data_type":"JMP","left_side":"","right_side":"0"}`)},
+		{"JumpStartNotAnInteger", modifer(`{"data_type":"JRT","left_side":"fees","right_side":"first"}`)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := s.Validate(mustDecode(t, tc.doc)); err == nil {
+				t.Fatalf("%s is accepted, want it rejected", tc.doc)
+			}
+		})
+	}
+}
+
+func mustDecode(t *testing.T, doc string) interface{} {
+	t.Helper()
+	v, err := decodeJSONDoc([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
@@ -0,0 +1,374 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Dialect the statements SQLSupply runs against one kind of database
+type Dialect interface {
+	// Schema create DB_TABLE_RULE and DB_TABLE_INFO when missing
+	Schema() []string
+	// Upsert insert or replace one DB_TABLE_RULE row given id, enable, break_on_fail, sequence, rule_id, rule_type,
+	// valid_from, valid_to and rule
+	Upsert() string
+	// Fetch the columns of Upsert of the rows with a rule_id and sequence >= start, in order
+	Fetch() string
+	// Count the rows with a rule_id
+	Count() string
+	// UpsertInfo insert or replace one DB_TABLE_INFO row given id, name, description, owner, tags, rule_type, status,
+	// created_at and updated_at, keeping created_at of a row already there
+	UpsertInfo() string
+	// FetchInfo the columns of UpsertInfo of the row with an id
+	FetchInfo() string
+	// Placeholder the n-th statement parameter, from 1
+	Placeholder(n int) string
+}
+
+// SQLiteDialect SQLite 3.24 or newer, for local and development use
+type SQLiteDialect struct{}
+
+func (SQLiteDialect) Schema() []string {
+	return []string{
+		`CREATE TABLE IF NOT EXISTS ` + DB_TABLE_RULE + ` (
+			id TEXT PRIMARY KEY,
+			enable BOOLEAN NOT NULL DEFAULT FALSE,
+			break_on_fail BOOLEAN NOT NULL DEFAULT FALSE,
+			sequence BIGINT NOT NULL DEFAULT 0,
+			rule_id TEXT NOT NULL,
+			rule_type INTEGER NOT NULL DEFAULT 0,
+			valid_from DATETIME,
+			valid_to DATETIME,
+			rule TEXT NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_rule_settings_rule_sequence ON ` + DB_TABLE_RULE + ` (rule_id, sequence)`,
+		`CREATE TABLE IF NOT EXISTS ` + DB_TABLE_INFO + ` (
+			id TEXT PRIMARY KEY,
+			name TEXT NOT NULL DEFAULT '',
+			description TEXT NOT NULL DEFAULT '',
+			owner TEXT NOT NULL DEFAULT '',
+			tags TEXT NOT NULL DEFAULT '[]',
+			rule_type INTEGER NOT NULL DEFAULT 0,
+			status INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME,
+			updated_at DATETIME
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_rule_infos_owner ON ` + DB_TABLE_INFO + ` (owner)`,
+	}
+}
+
+func (SQLiteDialect) Upsert() string {
+	return `INSERT INTO ` + DB_TABLE_RULE + ` (id, enable, break_on_fail, sequence, rule_id, rule_type, valid_from, valid_to, rule)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET enable = excluded.enable, break_on_fail = excluded.break_on_fail,
+		sequence = excluded.sequence, rule_id = excluded.rule_id, rule_type = excluded.rule_type,
+		valid_from = excluded.valid_from, valid_to = excluded.valid_to, rule = excluded.rule`
+}
+
+func (SQLiteDialect) Fetch() string {
+	return `SELECT id, enable, break_on_fail, sequence, rule_id, rule_type, valid_from, valid_to, rule FROM ` + DB_TABLE_RULE + `
+		WHERE rule_id = ? AND sequence >= ? ORDER BY sequence`
+}
+
+func (SQLiteDialect) Count() string {
+	return `SELECT COUNT(*) FROM ` + DB_TABLE_RULE + ` WHERE rule_id = ?`
+}
+
+func (SQLiteDialect) UpsertInfo() string {
+	return `INSERT INTO ` + DB_TABLE_INFO + ` (` + infoColumns + `)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET name = excluded.name, description = excluded.description, owner = excluded.owner,
+		tags = excluded.tags, rule_type = excluded.rule_type, status = excluded.status, updated_at = excluded.updated_at`
+}
+
+func (SQLiteDialect) FetchInfo() string {
+	return `SELECT ` + infoColumns + ` FROM ` + DB_TABLE_INFO + ` WHERE id = ?`
+}
+
+func (SQLiteDialect) Placeholder(int) string {
+	return "?"
+}
+
+// PostgresDialect PostgreSQL 9.5 or newer, the rule column is JSONB
+type PostgresDialect struct{}
+
+func (PostgresDialect) Schema() []string {
+	return []string{
+		`CREATE TABLE IF NOT EXISTS ` + DB_TABLE_RULE + ` (
+			id TEXT PRIMARY KEY,
+			enable BOOLEAN NOT NULL DEFAULT FALSE,
+			break_on_fail BOOLEAN NOT NULL DEFAULT FALSE,
+			sequence BIGINT NOT NULL DEFAULT 0,
+			rule_id TEXT NOT NULL,
+			rule_type INTEGER NOT NULL DEFAULT 0,
+			valid_from TIMESTAMPTZ,
+			valid_to TIMESTAMPTZ,
+			rule JSONB NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_rule_settings_rule_sequence ON ` + DB_TABLE_RULE + ` (rule_id, sequence)`,
+		`CREATE TABLE IF NOT EXISTS ` + DB_TABLE_INFO + ` (
+			id TEXT PRIMARY KEY,
+			name TEXT NOT NULL DEFAULT '',
+			description TEXT NOT NULL DEFAULT '',
+			owner TEXT NOT NULL DEFAULT '',
+			tags TEXT NOT NULL DEFAULT '[]',
+			rule_type INTEGER NOT NULL DEFAULT 0,
+			status INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMPTZ,
+			updated_at TIMESTAMPTZ
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_rule_infos_owner ON ` + DB_TABLE_INFO + ` (owner)`,
+	}
+}
+
+func (PostgresDialect) Upsert() string {
+	return `INSERT INTO ` + DB_TABLE_RULE + ` (id, enable, break_on_fail, sequence, rule_id, rule_type, valid_from, valid_to, rule)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9::jsonb)
+		ON CONFLICT (id) DO UPDATE SET enable = EXCLUDED.enable, break_on_fail = EXCLUDED.break_on_fail,
+		sequence = EXCLUDED.sequence, rule_id = EXCLUDED.rule_id, rule_type = EXCLUDED.rule_type,
+		valid_from = EXCLUDED.valid_from, valid_to = EXCLUDED.valid_to, rule = EXCLUDED.rule`
+}
+
+func (PostgresDialect) Fetch() string {
+	return `SELECT id, enable, break_on_fail, sequence, rule_id, rule_type, valid_from, valid_to, rule::text FROM ` + DB_TABLE_RULE + `
+		WHERE rule_id = $1 AND sequence >= $2 ORDER BY sequence`
+}
+
+func (PostgresDialect) Count() string {
+	return `SELECT COUNT(*) FROM ` + DB_TABLE_RULE + ` WHERE rule_id = $1`
+}
+
+func (PostgresDialect) UpsertInfo() string {
+	return `INSERT INTO ` + DB_TABLE_INFO + ` (` + infoColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, description = EXCLUDED.description, owner = EXCLUDED.owner,
+		tags = EXCLUDED.tags, rule_type = EXCLUDED.rule_type, status = EXCLUDED.status, updated_at = EXCLUDED.updated_at`
+}
+
+func (PostgresDialect) FetchInfo() string {
+	return `SELECT ` + infoColumns + ` FROM ` + DB_TABLE_INFO + ` WHERE id = $1`
+}
+
+func (PostgresDialect) Placeholder(n int) string {
+	return fmt.Sprintf("$%d", n)
+}
+
+// infoColumns the DB_TABLE_INFO columns in the order of RuleInfoDB
+const infoColumns = `id, name, description, owner, tags, rule_type, status, created_at, updated_at`
+
+// SQLSupply a Supply over DB_TABLE_RULE and DB_TABLE_INFO through database/sql, its statements are prepared once
+type SQLSupply struct {
+	db         *sql.DB
+	dialect    Dialect
+	upsert     *sql.Stmt
+	fetch      *sql.Stmt
+	count      *sql.Stmt
+	upsertInfo *sql.Stmt
+	fetchInfo  *sql.Stmt
+}
+
+// NewSQLSupply create the schema of d when missing and prepare its statements
+func NewSQLSupply(ctx context.Context, db *sql.DB, d Dialect) (*SQLSupply, error) {
+	for _, stmt := range d.Schema() {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return nil, err
+		}
+	}
+	ss := &SQLSupply{db: db, dialect: d}
+	var err error
+	if ss.upsert, err = db.PrepareContext(ctx, d.Upsert()); err != nil {
+		ss.Close()
+		return nil, err
+	}
+	if ss.fetch, err = db.PrepareContext(ctx, d.Fetch()); err != nil {
+		ss.Close()
+		return nil, err
+	}
+	if ss.count, err = db.PrepareContext(ctx, d.Count()); err != nil {
+		ss.Close()
+		return nil, err
+	}
+	if ss.upsertInfo, err = db.PrepareContext(ctx, d.UpsertInfo()); err != nil {
+		ss.Close()
+		return nil, err
+	}
+	if ss.fetchInfo, err = db.PrepareContext(ctx, d.FetchInfo()); err != nil {
+		ss.Close()
+		return nil, err
+	}
+	return ss, nil
+}
+
+// Close release the prepared statements, the *sql.DB is left open
+func (ss *SQLSupply) Close() error {
+	var first error
+	for _, stmt := range []*sql.Stmt{ss.upsert, ss.fetch, ss.count, ss.upsertInfo, ss.fetchInfo} {
+		if stmt == nil {
+			continue
+		}
+		if err := stmt.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func (ss *SQLSupply) SaveRuleSettings(rs []RuleSetting) error {
+	return ss.SaveRuleSettingsContext(context.Background(), rs)
+}
+
+// SaveRuleSettingsContext insert rs or replace the rows with the same ID in one transaction, settings without an ID get one
+func (ss *SQLSupply) SaveRuleSettingsContext(ctx context.Context, rs []RuleSetting) error {
+	tx, err := ss.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	upsert := tx.StmtContext(ctx, ss.upsert)
+	for _, rule := range rs {
+		ruleDB, err := rule.MakeDBObject()
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		_, err = upsert.ExecContext(ctx, ruleDB.ID, ruleDB.Enable, ruleDB.BreakOnFail, ruleDB.Sequence, ruleDB.RuleID, ruleDB.RuleType, ruleDB.ValidFrom, ruleDB.ValidTo, ruleDB.Rule)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (ss *SQLSupply) FetchRuleSettings(id string, start int) ([]RuleSetting, error) {
+	return ss.FetchRuleSettingsContext(context.Background(), id, start)
+}
+
+// FetchRuleSettingsContext the settings of rule set id with Sequence >= start, in order
+func (ss *SQLSupply) FetchRuleSettingsContext(ctx context.Context, id string, start int) ([]RuleSetting, error) {
+	rows, err := ss.fetch.QueryContext(ctx, id, start)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rs := []RuleSetting{}
+	for rows.Next() {
+		var ruledb RuleSettingDB
+		err := rows.Scan(&ruledb.ID, &ruledb.Enable, &ruledb.BreakOnFail, &ruledb.Sequence, &ruledb.RuleID, &ruledb.RuleType, &ruledb.ValidFrom, &ruledb.ValidTo, &ruledb.Rule)
+		if err != nil {
+			return nil, err
+		}
+		rule, err := ruledb.MakeObject()
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, *rule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(rs) == 0 {
+		var count int
+		if err := ss.count.QueryRowContext(ctx, id).Scan(&count); err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			return nil, fmt.Errorf("%w: %s", RuleSettingError.RULE_NOT_FOUND, id)
+		}
+	}
+	return rs, nil
+}
+
+// SaveRuleInfo insert info or replace the row with the same ID, keeping its CreatedAt
+func (ss *SQLSupply) SaveRuleInfo(info RuleInfo) error {
+	if err := checkInfo(info); err != nil {
+		return err
+	}
+	// created_at of a row already there is left alone by UpsertInfo
+	stampInfo(&info, time.Time{}, time.Now().UTC())
+	infoDB, err := info.MakeDBObject()
+	if err != nil {
+		return err
+	}
+	_, err = ss.upsertInfo.Exec(infoDB.ID, infoDB.Name, infoDB.Description, infoDB.Owner, infoDB.Tags,
+		infoDB.RuleType, infoDB.Status, infoDB.CreatedAt, infoDB.UpdatedAt)
+	return err
+}
+
+// FetchRuleInfo the RuleInfo of rule set id
+func (ss *SQLSupply) FetchRuleInfo(id string) (*RuleInfo, error) {
+	infodb, err := scanInfo(ss.fetchInfo.QueryRow(id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", RuleSettingError.RULE_NOT_FOUND, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return infodb.MakeObject()
+}
+
+// ListRuleInfos every RuleInfo in ID order, paged
+func (ss *SQLSupply) ListRuleInfos(offset, limit int) ([]RuleInfo, error) {
+	return ss.SearchRuleInfos(InfoQuery{Offset: offset, Limit: limit})
+}
+
+// SearchRuleInfos the RuleInfo matching q in ID order
+func (ss *SQLSupply) SearchRuleInfos(q InfoQuery) ([]RuleInfo, error) {
+	query := `SELECT ` + infoColumns + ` FROM ` + DB_TABLE_INFO
+	where, args := q.where(ss.dialect.Placeholder)
+	if where != "" {
+		query += ` WHERE ` + where
+	}
+	query += ` ORDER BY id`
+	if q.Limit > 0 {
+		query += fmt.Sprintf(` LIMIT %d OFFSET %d`, q.Limit, max(q.Offset, 0))
+	}
+	rows, err := ss.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	infos := []RuleInfo{}
+	for skip := 0; rows.Next(); skip++ {
+		if q.Limit <= 0 && skip < q.Offset {
+			// OFFSET without LIMIT is not portable, those rows are skipped here
+			continue
+		}
+		infodb, err := scanInfo(rows)
+		if err != nil {
+			return nil, err
+		}
+		info, err := infodb.MakeObject()
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, *info)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return infos, nil
+}
+
+// scanner a *sql.Row or *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanInfo(row scanner) (*RuleInfoDB, error) {
+	var infodb RuleInfoDB
+	err := row.Scan(&infodb.ID, &infodb.Name, &infodb.Description, &infodb.Owner, &infodb.Tags,
+		&infodb.RuleType, &infodb.Status, &infodb.CreatedAt, &infodb.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &infodb, nil
+}
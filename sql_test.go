@@ -0,0 +1,176 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	rule "github.com/007lock/go-turner"
+	"github.com/007lock/go-turner/supplytest"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// newSQLSupply an SQLSupply over an empty in-memory SQLite database, one connection so every statement sees the same
+// database
+func newSQLSupply(t *testing.T) *rule.SQLSupply {
+	t.Helper()
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	ss, err := rule.NewSQLSupply(context.Background(), db, rule.SQLiteDialect{})
+	if err != nil {
+		db.Close()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		ss.Close()
+		db.Close()
+	})
+	return ss
+}
+
+func TestSQLSupplyConformance(t *testing.T) {
+	supplytest.Run(t, func(t *testing.T) rule.Supply {
+		return newSQLSupply(t)
+	})
+}
+
+func TestCachingSupplyConformance(t *testing.T) {
+	supplytest.Run(t, func(t *testing.T) rule.Supply {
+		return rule.NewCachingSupply(newSQLSupply(t), time.Minute, 16)
+	})
+}
+
+func TestSQLSupplySchemaKept(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ss, err := rule.NewSQLSupply(context.Background(), db, rule.SQLiteDialect{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ss.SaveRuleSettings([]rule.RuleSetting{{ID: "a", RuleID: "r", Sequence: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	ss.Close()
+
+	again, err := rule.NewSQLSupply(context.Background(), db, rule.SQLiteDialect{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer again.Close()
+	if rs, err := again.FetchRuleSettings("r", 0); err != nil || len(rs) != 1 {
+		t.Fatalf("FetchRuleSettings = %+v, %v, want a kept when the schema is created again", rs, err)
+	}
+}
+
+func TestSQLSupplyValidity(t *testing.T) {
+	ss := newSQLSupply(t)
+	from := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)
+	err := ss.SaveRuleSettings([]rule.RuleSetting{
+		{ID: "a", RuleID: "r", Sequence: 1, Enable: true, BreakOnFail: true, ValidFrom: &from, ValidTo: &to},
+		{ID: "b", RuleID: "r", Sequence: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs, err := ss.FetchRuleSettings("r", 0)
+	if err != nil || len(rs) != 2 {
+		t.Fatalf("FetchRuleSettings = %+v, %v, want a and b", rs, err)
+	}
+	a, b := rs[0], rs[1]
+	if !a.Enable || !a.BreakOnFail || a.ValidFrom == nil || !a.ValidFrom.Equal(from) || a.ValidTo == nil || !a.ValidTo.Equal(to) {
+		t.Errorf("a changed on the way through: %+v", a)
+	}
+	if b.Enable || b.ValidFrom != nil || b.ValidTo != nil {
+		t.Errorf("b changed on the way through: %+v", b)
+	}
+}
+
+func TestSQLSupplyRuleInfo(t *testing.T) {
+	ss := newSQLSupply(t)
+	if err := ss.SaveRuleInfo(rule.RuleInfo{}); !errors.Is(err, rule.RuleSettingError.VALUE_INVALID) {
+		t.Fatalf("SaveRuleInfo without an id = %v, want VALUE_INVALID", err)
+	}
+	for _, info := range []rule.RuleInfo{
+		{ID: "weekend", Name: "Weekend rates", Owner: "revenue", Tags: []string{"rate"}},
+		{ID: "loyalty", Name: "Loyalty discount", Owner: "crm", Tags: []string{"rate", "member"}},
+		{ID: "promo_100%", Name: "Promotion", Owner: "crm"},
+	} {
+		if err := ss.SaveRuleInfo(info); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	info, err := ss.FetchRuleInfo("weekend")
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := info.CreatedAt
+	info.Description = "Saturday and Sunday"
+	info.CreatedAt = created.Add(time.Hour)
+	if err := ss.SaveRuleInfo(*info); err != nil {
+		t.Fatal(err)
+	}
+	info, err = ss.FetchRuleInfo("weekend")
+	if err != nil || info.Description != "Saturday and Sunday" || !info.CreatedAt.Equal(created) {
+		t.Fatalf("FetchRuleInfo = %+v, %v, want the description replaced and CreatedAt kept", info, err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		q    rule.InfoQuery
+		ids  []string
+	}{
+		{"Owner", rule.InfoQuery{Owner: "crm"}, []string{"loyalty", "promo_100%"}},
+		{"Tag", rule.InfoQuery{Tags: []string{"member"}}, []string{"loyalty"}},
+		{"Text", rule.InfoQuery{Text: "RATES"}, []string{"weekend"}},
+		{"LikeEscaped", rule.InfoQuery{Text: "_100%"}, []string{"promo_100%"}},
+		{"Offset", rule.InfoQuery{Offset: 1}, []string{"promo_100%", "weekend"}},
+		{"Page", rule.InfoQuery{Offset: 1, Limit: 1}, []string{"promo_100%"}},
+		{"PastEnd", rule.InfoQuery{Offset: 5}, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			infos, err := ss.SearchRuleInfos(tc.q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(infos) != len(tc.ids) {
+				t.Fatalf("%+v, want %v", infos, tc.ids)
+			}
+			for i := range infos {
+				if infos[i].ID != tc.ids[i] {
+					t.Fatalf("%+v, want %v", infos, tc.ids)
+				}
+			}
+		})
+	}
+
+	if _, err := ss.FetchRuleInfo("missing"); !errors.Is(err, rule.RuleSettingError.RULE_NOT_FOUND) {
+		t.Fatalf("FetchRuleInfo = %v, want RULE_NOT_FOUND", err)
+	}
+}
+
+func TestPostgresDialectPlaceholder(t *testing.T) {
+	var d rule.Dialect = rule.PostgresDialect{}
+	for n, want := range map[int]string{1: "$1", 2: "$2", 12: "$12"} {
+		if got := d.Placeholder(n); got != want {
+			t.Errorf("Placeholder(%d) = %s, want %s", n, got, want)
+		}
+	}
+	if got := (rule.SQLiteDialect{}).Placeholder(3); got != "?" {
+		t.Errorf("SQLite Placeholder(3) = %s, want ?", got)
+	}
+}
@@ -0,0 +1,208 @@
+// Package supplytest the conformance suite every rule.Supply implementation is expected to pass
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package supplytest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	rule "github.com/007lock/go-turner"
+)
+
+// Run the suite against the empty Supply returned by newSupply for every case
+func Run(t *testing.T, newSupply func(t *testing.T) rule.Supply) {
+	t.Run("FetchInOrder", func(t *testing.T) {
+		sp := newSupply(t)
+		save(t, sp, setting("c", "r", 30), setting("a", "r", 10), setting("b", "r", 20))
+		expectIDs(t, fetch(t, sp, "r", 0), "a", "b", "c")
+	})
+
+	t.Run("FetchFromStart", func(t *testing.T) {
+		sp := newSupply(t)
+		save(t, sp, setting("a", "r", 10), setting("b", "r", 20), setting("c", "r", 30))
+		expectIDs(t, fetch(t, sp, "r", 20), "b", "c")
+		expectIDs(t, fetch(t, sp, "r", 31))
+	})
+
+	t.Run("RuleSetsKeptApart", func(t *testing.T) {
+		sp := newSupply(t)
+		save(t, sp, setting("a", "r", 10), setting("b", "q", 10))
+		expectIDs(t, fetch(t, sp, "r", 0), "a")
+		expectIDs(t, fetch(t, sp, "q", 0), "b")
+	})
+
+	t.Run("SaveReplacesByID", func(t *testing.T) {
+		sp := newSupply(t)
+		save(t, sp, setting("a", "r", 10), setting("b", "r", 20))
+		moved := setting("a", "r", 30)
+		moved.BreakOnFail = true
+		save(t, sp, moved)
+		rs := fetch(t, sp, "r", 0)
+		expectIDs(t, rs, "b", "a")
+		if !rs[1].BreakOnFail {
+			t.Errorf("saved setting a was not replaced: %+v", rs[1])
+		}
+	})
+
+	t.Run("SaveMovesBetweenRuleSets", func(t *testing.T) {
+		sp := newSupply(t)
+		save(t, sp, setting("a", "r", 10), setting("b", "q", 10), setting("c", "q", 20))
+		expectIDs(t, fetch(t, sp, "r", 0), "a")
+		expectIDs(t, fetch(t, sp, "q", 0), "b", "c")
+		if err := sp.SaveRuleSettings([]rule.RuleSetting{setting("b", "r", 20)}); err != nil {
+			t.Skipf("the supply does not move settings between rule sets: %v", err)
+		}
+		expectIDs(t, fetch(t, sp, "r", 0), "a", "b")
+		expectIDs(t, fetch(t, sp, "q", 0), "c")
+	})
+
+	t.Run("SaveAssignsID", func(t *testing.T) {
+		sp := newSupply(t)
+		save(t, sp, setting("", "r", 10))
+		rs := fetch(t, sp, "r", 0)
+		if len(rs) != 1 || rs[0].ID == "" {
+			t.Errorf("FetchRuleSettings = %+v, want one setting with an ID", rs)
+		}
+	})
+
+	t.Run("RuleRoundTrip", func(t *testing.T) {
+		sp := newSupply(t)
+		want := setting("a", "r", 10)
+		save(t, sp, want)
+		rs := fetch(t, sp, "r", 0)
+		expectIDs(t, rs, "a")
+		if got, exp := marshal(t, rs[0].Rule), marshal(t, want.Rule); got != exp {
+			t.Errorf("rule changed on the way through the supply\n got: %s\nwant: %s", got, exp)
+		}
+	})
+
+	t.Run("FetchedCopiesIsolated", func(t *testing.T) {
+		sp := newSupply(t)
+		save(t, sp, setting("a", "r", 10))
+		rs := fetch(t, sp, "r", 0)
+		rs[0].Sequence = 99
+		rs[0].Rule.ConditionChain[0].RightSide = "changed"
+		rs[0].Rule.ModiferChain[0].Rounding.Places = 7
+		again := fetch(t, sp, "r", 0)
+		if got, exp := marshal(t, again[0]), marshal(t, setting("a", "r", 10)); got != exp {
+			t.Errorf("changing a fetched setting changed the supply\n got: %s\nwant: %s", got, exp)
+		}
+	})
+
+	t.Run("UnknownRuleSet", func(t *testing.T) {
+		sp := newSupply(t)
+		save(t, sp, setting("a", "r", 10))
+		if _, err := sp.FetchRuleSettings("missing", 0); !errors.Is(err, rule.RuleSettingError.RULE_NOT_FOUND) {
+			t.Errorf("FetchRuleSettings = %v, want RULE_NOT_FOUND", err)
+		}
+	})
+
+	t.Run("ContextCanceled", func(t *testing.T) {
+		sp := newSupply(t)
+		csp, ok := sp.(rule.ContextSupply)
+		if !ok {
+			t.Skip("not a ContextSupply")
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := csp.SaveRuleSettingsContext(ctx, []rule.RuleSetting{setting("a", "r", 10)}); err == nil {
+			t.Error("save with a canceled context succeeded")
+		}
+		if _, err := csp.FetchRuleSettingsContext(ctx, "r", 0); err == nil {
+			t.Error("fetch with a canceled context succeeded")
+		}
+	})
+}
+
+// Setting an enabled RuleSetting of rule set ruleID adding value to Price, for the tests of supplies and what runs on them
+func Setting(id, ruleID string, sequence int64, value string) rule.RuleSetting {
+	return rule.RuleSetting{
+		ID:       id,
+		RuleID:   ruleID,
+		Enable:   true,
+		Sequence: sequence,
+		Rule: rule.Rule{
+			ModiferChain: []rule.Modifer{{
+				Operand:     rule.RuleOperand.ADD,
+				DataType:    rule.ModiferDataType.INT,
+				LeftSide:    "Price",
+				LeftType:    rule.ModiferSideType.FIELD,
+				RightSide:   value,
+				RightType:   rule.ModiferSideType.VALUE,
+				TargetField: "Price",
+			}},
+		},
+	}
+}
+
+// setting a Setting exercising conditions, a condition tree and a rounded COMPLEX modifer
+func setting(id, ruleID string, sequence int64) rule.RuleSetting {
+	rs := Setting(id, ruleID, sequence, `{"flat":500,"percentage":10}`)
+	m := &rs.Rule.ModiferChain[0]
+	m.Sequence, m.RightType = 1, rule.ModiferSideType.COMPLEX
+	m.Rounding = &rule.Rounding{Mode: rule.RoundingMode.HALF_UP, Places: -2}
+	rs.Rule.ConditionChain = []rule.Condition{{
+		Type:      rule.RuleConditionType.INT,
+		LeftSide:  "Adults",
+		LeftType:  rule.ConditionSideType.FIELD,
+		Compare:   rule.RuleConditionCompare.MORE_EQUAL,
+		RightSide: "2",
+		RightType: rule.ConditionSideType.VALUE,
+	}}
+	rs.Rule.ConditionTree = &rule.ConditionGroup{
+		Mode: rule.ConditionGroupMode.ANY,
+		Conditions: []rule.Condition{{
+			Type:      rule.RuleConditionType.STRING,
+			LeftSide:  "Channel",
+			LeftType:  rule.ConditionSideType.FIELD,
+			Compare:   rule.RuleConditionCompare.EQUAL,
+			RightSide: "web",
+			RightType: rule.ConditionSideType.VALUE,
+		}},
+	}
+	return rs
+}
+
+func save(t *testing.T, sp rule.Supply, rs ...rule.RuleSetting) {
+	t.Helper()
+	if err := sp.SaveRuleSettings(rs); err != nil {
+		t.Fatalf("SaveRuleSettings: %v", err)
+	}
+}
+
+func fetch(t *testing.T, sp rule.Supply, id string, start int) []rule.RuleSetting {
+	t.Helper()
+	rs, err := sp.FetchRuleSettings(id, start)
+	if err != nil {
+		t.Fatalf("FetchRuleSettings(%q, %d): %v", id, start, err)
+	}
+	return rs
+}
+
+func expectIDs(t *testing.T, rs []rule.RuleSetting, ids ...string) {
+	t.Helper()
+	got := make([]string, len(rs))
+	for i, setting := range rs {
+		got[i] = setting.ID
+	}
+	if len(got) != len(ids) {
+		t.Fatalf("settings %v, want %v", got, ids)
+	}
+	for i := range ids {
+		if got[i] != ids[i] {
+			t.Fatalf("settings %v, want %v", got, ids)
+		}
+	}
+}
+
+func marshal(t *testing.T, v interface{}) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
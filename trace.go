@@ -0,0 +1,197 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule
+
+// Trace the explained result of an evaluation, safe to serialise as JSON
+type Trace struct {
+	Result   bool            `json:"result"`
+	Error    string          `json:"error,omitempty"`
+	Settings []*SettingTrace `json:"settings"`
+}
+
+// SettingTrace one visited RuleSetting
+type SettingTrace struct {
+	ID         string            `json:"id"`
+	RuleID     string            `json:"rule_id"`
+	Sequence   int64             `json:"sequence"`
+	Skipped    int               `json:"skipped,omitempty"` // see SettingSkip
+	Matched    bool              `json:"matched"`
+	Result     bool              `json:"result"`
+	Break      bool              `json:"break"`
+	Error      string            `json:"error,omitempty"`
+	Conditions []*ConditionTrace `json:"conditions"`
+	Tree       *GroupTrace       `json:"tree,omitempty"`
+	Modifers   []*ModiferTrace   `json:"modifers"`
+}
+
+// GroupTrace one checked ConditionGroup
+type GroupTrace struct {
+	Mode       GroupModeCode     `json:"mode"`
+	Result     bool              `json:"result"`
+	Error      string            `json:"error,omitempty"`
+	Conditions []*ConditionTrace `json:"conditions"`
+	Groups     []*GroupTrace     `json:"groups,omitempty"`
+}
+
+// ConditionTrace one checked Condition with its resolved sides
+type ConditionTrace struct {
+	Index     int         `json:"index"`
+	Condition Condition   `json:"condition"`
+	Left      interface{} `json:"left"`
+	Right     interface{} `json:"right"`
+	Result    bool        `json:"result"`
+	Error     string      `json:"error,omitempty"`
+}
+
+// ModiferTrace one applied Modifer with the target field before and after
+type ModiferTrace struct {
+	Index   int         `json:"index"`
+	Modifer Modifer     `json:"modifer"`
+	Before  interface{} `json:"before,omitempty"`
+	After   interface{} `json:"after,omitempty"`
+	Result  bool        `json:"result"`
+	Error   string      `json:"error,omitempty"`
+	Jump    *JumpTrace  `json:"jump,omitempty"`
+}
+
+// JumpTrace a JMP or JRT hop into another rule set
+type JumpTrace struct {
+	RuleID   string          `json:"rule_id"`
+	Start    int             `json:"start"`
+	Return   bool            `json:"return"`
+	Settings []*SettingTrace `json:"settings"`
+}
+
+func (st *SettingTrace) done(result, br bool, err error) {
+	if st == nil {
+		return
+	}
+	st.Result = result
+	st.Break = br
+	st.Error = errorString(err)
+}
+
+// skip record that the setting was passed over, and why
+func (st *SettingTrace) skip(reason int) {
+	if st == nil {
+		return
+	}
+	st.Skipped = reason
+}
+
+// conditions slots are allocated up front so concurrent checks never share one
+func (st *SettingTrace) conditions(cs []Condition) []*ConditionTrace {
+	cts := make([]*ConditionTrace, len(cs))
+	if st == nil {
+		return cts
+	}
+	for i, c := range cs {
+		cts[i] = &ConditionTrace{Index: i, Condition: c}
+	}
+	st.Conditions = cts
+	return cts
+}
+
+// checked drop the slots of conditions never reached
+func (st *SettingTrace) checked(n int) {
+	if st != nil {
+		st.Conditions = st.Conditions[:n]
+	}
+}
+
+func (st *SettingTrace) tree(g *ConditionGroup) *GroupTrace {
+	if st == nil {
+		return nil
+	}
+	st.Tree = &GroupTrace{Mode: g.Mode}
+	return st.Tree
+}
+
+func (st *SettingTrace) modifer(i int, rm Modifer) *ModiferTrace {
+	if st == nil {
+		return nil
+	}
+	mt := &ModiferTrace{Index: i, Modifer: rm}
+	st.Modifers = append(st.Modifers, mt)
+	return mt
+}
+
+func (gt *GroupTrace) condition(i int, c Condition) *ConditionTrace {
+	if gt == nil {
+		return nil
+	}
+	ct := &ConditionTrace{Index: i, Condition: c}
+	gt.Conditions = append(gt.Conditions, ct)
+	return ct
+}
+
+func (gt *GroupTrace) group(g ConditionGroup) *GroupTrace {
+	if gt == nil {
+		return nil
+	}
+	sub := &GroupTrace{Mode: g.Mode}
+	gt.Groups = append(gt.Groups, sub)
+	return sub
+}
+
+func (gt *GroupTrace) done(result bool, err error) {
+	if gt == nil {
+		return
+	}
+	gt.Result = result
+	gt.Error = errorString(err)
+}
+
+func (ct *ConditionTrace) left(v interface{}) {
+	if ct != nil {
+		ct.Left = v
+	}
+}
+
+func (ct *ConditionTrace) right(v interface{}) {
+	if ct != nil {
+		ct.Right = v
+	}
+}
+
+func (ct *ConditionTrace) done(result bool, err error) {
+	if ct == nil {
+		return
+	}
+	ct.Result = result
+	ct.Error = errorString(err)
+}
+
+func (mt *ModiferTrace) before(rqr interface{}) {
+	if mt != nil {
+		mt.Before = traceField(rqr, mt.Modifer.TargetField)
+	}
+}
+
+func (mt *ModiferTrace) done(rqr interface{}, result bool, err error) {
+	if mt == nil {
+		return
+	}
+	mt.After = traceField(rqr, mt.Modifer.TargetField)
+	mt.Result = result
+	mt.Error = errorString(err)
+}
+
+func traceField(rqr interface{}, path string) interface{} {
+	if path == "" {
+		return nil
+	}
+	v, err := fieldValue(rqr, path)
+	if err != nil || !v.CanInterface() {
+		return nil
+	}
+	return v.Interface()
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,117 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func explainSample() (stubSupply, []RuleSetting) {
+	sp := stubSupply{
+		"extra": {setting("extra", 0, nil, intModifer(RuleOperand.ADD, "Price", "5"))},
+	}
+	rs := []RuleSetting{
+		setting("r", 1, []Condition{
+			intCondition("Adults", RuleConditionCompare.MORE_EQUAL, "2"),
+			intCondition("Price", RuleConditionCompare.LESS, "1000"),
+		}, intModifer(RuleOperand.ADD, "Price", "10"), jumpModifer(ModiferDataType.JRT, "extra", 0)),
+		setting("r", 2, []Condition{intCondition("Adults", RuleConditionCompare.EQUAL, "1")},
+			intModifer(RuleOperand.SUB, "Price", "50")),
+	}
+	rs[0].Rule.ModiferChain[1].Sequence = 1
+	return sp, rs
+}
+
+func TestExplainSettings(t *testing.T) {
+	sp, rs := explainSample()
+	b := &booking{Adults: 2, Price: 100}
+	result, tr, err := NewEngine(sp).ExplainSettings(b, rs)
+	if err != nil || !result || !tr.Result || tr.Error != "" {
+		t.Fatalf("ExplainSettings = %v, %v, trace %v %q", result, err, tr.Result, tr.Error)
+	}
+	if b.Price != 115 {
+		t.Errorf("Price = %d, want 115", b.Price)
+	}
+	if len(tr.Settings) != 2 {
+		t.Fatalf("%d settings traced, want 2", len(tr.Settings))
+	}
+
+	st := tr.Settings[0]
+	if st.ID != "r-1" || !st.Matched || !st.Result || len(st.Conditions) != 2 || len(st.Modifers) != 2 {
+		t.Fatalf("first setting traced as %+v", st)
+	}
+	if ct := st.Conditions[0]; ct.Left != int64(2) || ct.Right != int64(2) || !ct.Result {
+		t.Errorf("Adults >= 2 traced as %v >= %v = %v", ct.Left, ct.Right, ct.Result)
+	}
+	if mt := st.Modifers[0]; mt.Before != int64(100) || mt.After != int64(110) || !mt.Result {
+		t.Errorf("Price + 10 traced from %v to %v", mt.Before, mt.After)
+	}
+	jt := st.Modifers[1].Jump
+	if jt == nil || jt.RuleID != "extra" || jt.Start != 0 || !jt.Return || len(jt.Settings) != 1 {
+		t.Fatalf("JRT traced as %+v", jt)
+	}
+	if mt := jt.Settings[0].Modifers[0]; mt.Before != int64(110) || mt.After != int64(115) {
+		t.Errorf("the jumped to Price + 5 traced from %v to %v", mt.Before, mt.After)
+	}
+
+	st = tr.Settings[1]
+	if st.Matched || st.Result || len(st.Conditions) != 1 || st.Conditions[0].Result || len(st.Modifers) != 0 {
+		t.Errorf("unmatched setting traced as %+v", st)
+	}
+}
+
+func TestExplainSettingsJSON(t *testing.T) {
+	sp, rs := explainSample()
+	_, tr, err := NewEngine(sp).ExplainSettings(&booking{Adults: 2, Price: 100}, rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(tr)
+	if err != nil {
+		t.Fatalf("the trace does not serialise: %v", err)
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		t.Fatal(err)
+	}
+	settings := doc["settings"].([]interface{})
+	first := settings[0].(map[string]interface{})
+	condition := first["conditions"].([]interface{})[0].(map[string]interface{})
+	if condition["left"] != float64(2) || condition["result"] != true {
+		t.Errorf("condition written as %v", condition)
+	}
+	if c := condition["condition"].(map[string]interface{}); c["compare"] != "MORE_EQUAL" {
+		t.Errorf("condition compare written as %v", c["compare"])
+	}
+	jump := first["modifers"].([]interface{})[1].(map[string]interface{})["jump"].(map[string]interface{})
+	if jump["rule_id"] != "extra" || jump["return"] != true || len(jump["settings"].([]interface{})) != 1 {
+		t.Errorf("jump written as %v", jump)
+	}
+
+	var back Trace
+	if err := json.Unmarshal(b, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back.Settings[0].Modifers[1].Jump.Settings[0].RuleID != "extra" || !reflect.DeepEqual(back.Settings[0].Conditions[0].Condition, rs[0].Rule.ConditionChain[0]) {
+		t.Errorf("the trace does not read back: %+v", back.Settings[0])
+	}
+}
+
+func TestExplainSettingError(t *testing.T) {
+	rs := setting("r", 1, []Condition{intCondition("Missing", RuleConditionCompare.EQUAL, "1")})
+	result, br, tr, err := NewEngine(stubSupply{}).ExplainSetting(&booking{}, rs)
+	if err == nil || result || br {
+		t.Fatalf("ExplainSetting = %v, %v, %v", result, br, err)
+	}
+	if !errors.Is(err, RuleSettingError.MODIFER_FEILD_NOT_EXISTED) {
+		t.Errorf("err = %v, want MODIFER_FEILD_NOT_EXISTED", err)
+	}
+	if tr.Error != err.Error() || tr.Settings[0].Error == "" || tr.Settings[0].Conditions[0].Error == "" {
+		t.Errorf("the error is missing from the trace: %q %+v", tr.Error, tr.Settings[0])
+	}
+}
@@ -0,0 +1,414 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// ValidationError one problem found by Validate, Location follows the JSON form of the setting
+type ValidationError struct {
+	Setting   int    `json:"setting"`
+	SettingID string `json:"setting_id"`
+	Location  string `json:"location"`
+	Err       error  `json:"-"`
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("setting %d (%s) %s: %s", e.Setting, e.SettingID, e.Location, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationErrors every problem found by Validate, in the order of the settings
+type ValidationErrors []*ValidationError
+
+func (es ValidationErrors) Error() string {
+	msgs := make([]string, len(es))
+	for i, e := range es {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (es ValidationErrors) Unwrap() []error {
+	errs := make([]error, len(es))
+	for i, e := range es {
+		errs[i] = e
+	}
+	return errs
+}
+
+var conditionCompares = map[ConditionTypeCode][]CompareCode{
+	RuleConditionType.DAY_OF_WEEK: {RuleConditionCompare.EQUAL, RuleConditionCompare.NOT, RuleConditionCompare.IN, RuleConditionCompare.NOT_IN},
+	RuleConditionType.DATE:        orderedCompares,
+	RuleConditionType.STRING:      {RuleConditionCompare.EQUAL, RuleConditionCompare.NOT},
+	RuleConditionType.INT:         orderedCompares,
+	RuleConditionType.DECIMAL:     orderedCompares,
+}
+
+var orderedCompares = []CompareCode{
+	RuleConditionCompare.EQUAL,
+	RuleConditionCompare.NOT,
+	RuleConditionCompare.MORE,
+	RuleConditionCompare.LESS,
+	RuleConditionCompare.MORE_EQUAL,
+	RuleConditionCompare.LESS_EQUAL,
+	RuleConditionCompare.IN,
+	RuleConditionCompare.NOT_IN,
+}
+
+var modiferOperands = map[DataTypeCode][]OperandCode{
+	ModiferDataType.STRING:  {RuleOperand.SET, RuleOperand.SEL},
+	ModiferDataType.INT:     numericOperands,
+	ModiferDataType.DECIMAL: numericOperands,
+}
+
+var numericOperands = []OperandCode{
+	RuleOperand.SET,
+	RuleOperand.ADD,
+	RuleOperand.SUB,
+	RuleOperand.MLT,
+	RuleOperand.DIV,
+	RuleOperand.SEL,
+	RuleOperand.SUM,
+}
+
+// Validate check rs against the request type sample without evaluating anything and report every problem found,
+// a nil sample skips the field checks. The error is ValidationErrors when rs has problems
+func Validate(rs []RuleSetting, sample reflect.Type) error {
+	for sample != nil && sample.Kind() == reflect.Ptr {
+		sample = sample.Elem()
+	}
+	v := &validator{sample: sample}
+	for i, setting := range rs {
+		v.index, v.id = i, setting.ID
+		if i > 0 && rs[i-1].Sequence > setting.Sequence {
+			v.fail("sequence", RuleSettingError.SETTING_NOT_IN_ORDER)
+		}
+		if setting.ValidFrom != nil && setting.ValidTo != nil && !setting.ValidTo.After(*setting.ValidFrom) {
+			v.fail("valid_to", fmt.Errorf("%w: valid_to is not after valid_from", RuleSettingError.VALUE_INVALID))
+		}
+		v.rule(setting.Rule)
+	}
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+type validator struct {
+	sample reflect.Type
+	index  int
+	id     string
+	errs   ValidationErrors
+}
+
+func (v *validator) fail(loc string, err error) {
+	v.errs = append(v.errs, &ValidationError{
+		Setting:   v.index,
+		SettingID: v.id,
+		Location:  loc,
+		Err:       err,
+	})
+}
+
+func (v *validator) rule(r Rule) {
+	for i, c := range r.ConditionChain {
+		v.condition(fmt.Sprintf("rule.condition_chain[%d]", i), c)
+	}
+	if r.ConditionTree != nil {
+		v.group("rule.condition_tree", *r.ConditionTree)
+	}
+
+	sequences := make(map[int]bool, len(r.ModiferChain))
+	jump := -1
+	for i, m := range r.ModiferChain {
+		loc := fmt.Sprintf("rule.rate_modifer[%d]", i)
+		if sequences[m.Sequence] {
+			// the chain is sorted by sequence before it runs, equal sequences have no defined order
+			v.fail(loc+".sequence", RuleSettingError.SETTING_NOT_IN_ORDER)
+		}
+		sequences[m.Sequence] = true
+		if m.DataType == ModiferDataType.JMP && (jump < 0 || m.Sequence < r.ModiferChain[jump].Sequence) {
+			jump = i
+		}
+		v.modifer(loc, m)
+	}
+	if jump >= 0 {
+		for _, m := range r.ModiferChain {
+			if m.Sequence > r.ModiferChain[jump].Sequence {
+				v.fail(fmt.Sprintf("rule.rate_modifer[%d]", jump), fmt.Errorf("%w: modifers after a JMP never run", RuleSettingError.SETTING_NOT_IN_ORDER))
+				break
+			}
+		}
+	}
+}
+
+func (v *validator) group(loc string, g ConditionGroup) {
+	switch g.Mode {
+	case ConditionGroupMode.ALL, ConditionGroupMode.ANY, ConditionGroupMode.NONE:
+	default:
+		v.fail(loc+".mode", RuleSettingError.UNSUPPORTED_OPERATION)
+	}
+	for i, c := range g.Conditions {
+		v.condition(fmt.Sprintf("%s.conditions[%d]", loc, i), c)
+	}
+	for i, sub := range g.Groups {
+		v.group(fmt.Sprintf("%s.groups[%d]", loc, i), sub)
+	}
+}
+
+func (v *validator) condition(loc string, c Condition) {
+	if c.Type == RuleConditionType.MUST {
+		return
+	}
+	compares, ok := conditionCompares[c.Type]
+	if !ok {
+		v.fail(loc+".type", RuleSettingError.UNSUPPORTED_OPERATION)
+		return
+	}
+	if !slices.Contains(compares, c.Compare) {
+		v.fail(loc+".compare", RuleSettingError.UNSUPPORTED_OPERATION)
+	}
+	list := c.Compare == RuleConditionCompare.IN || c.Compare == RuleConditionCompare.NOT_IN
+	v.conditionSide(loc, "left", c.Type, c.LeftSide, c.LeftType, false)
+	v.conditionSide(loc, "right", c.Type, c.RightSide, c.RightType, list)
+}
+
+func (v *validator) conditionSide(loc, side string, conditionType ConditionTypeCode, value string, sideType SideCode, list bool) {
+	switch sideType {
+	case ConditionSideType.VALUE:
+		values := []string{value}
+		if list {
+			values = strings.Split(value, ",")
+		}
+		for _, item := range values {
+			if !conditionValueValid(conditionType, item) {
+				v.fail(fmt.Sprintf("%s.%s_side", loc, side), fmt.Errorf("%w: %s", RuleSettingError.VALUE_INVALID, item))
+				return
+			}
+		}
+	case ConditionSideType.FIELD:
+		t, ok := v.field(fmt.Sprintf("%s.%s_side", loc, side), value)
+		if !ok {
+			return
+		}
+		fits := conditionFieldFits(conditionType, t)
+		if list {
+			fits = t.Kind() == reflect.String
+		}
+		if !fits {
+			v.mismatch(fmt.Sprintf("%s.%s_side", loc, side), value, t)
+		}
+	default:
+		v.fail(fmt.Sprintf("%s.%s_type", loc, side), RuleSettingError.CONDITION_SIDE_INVALID)
+	}
+}
+
+func (v *validator) modifer(loc string, m Modifer) {
+	if m.Rounding != nil {
+		switch m.Rounding.Mode {
+		case RoundingMode.TRUNCATE, RoundingMode.HALF_UP, RoundingMode.HALF_EVEN, RoundingMode.FLOOR, RoundingMode.CEIL:
+		default:
+			v.fail(loc+".rounding.mode", RuleSettingError.UNSUPPORTED_OPERATION)
+		}
+		if m.DataType == ModiferDataType.INT && m.Rounding.Places < minIntPlaces {
+			v.fail(loc+".rounding.places", fmt.Errorf("%w: %d", RuleSettingError.VALUE_INVALID, m.Rounding.Places))
+		}
+	}
+	if m.DataType == ModiferDataType.JMP || m.DataType == ModiferDataType.JRT {
+		if m.LeftSide == "" {
+			v.fail(loc+".left_side", RuleSettingError.MODIFER_SIDE_INVALID)
+		}
+		if _, err := strconv.ParseInt(m.RightSide, 10, 64); err != nil {
+			v.fail(loc+".right_side", fmt.Errorf("%w: %s", RuleSettingError.VALUE_INVALID, m.RightSide))
+		}
+		return
+	}
+	operands, ok := modiferOperands[m.DataType]
+	if !ok {
+		v.fail(loc+".data_type", RuleSettingError.UNSUPPORTED_OPERATION)
+		return
+	}
+	if !slices.Contains(operands, m.Operand) {
+		v.fail(loc+".operand", RuleSettingError.UNSUPPORTED_OPERATION)
+		return
+	}
+
+	if t, ok := v.field(loc+".target_field", m.TargetField); ok && !modiferTargetFits(m.DataType, t) {
+		v.mismatch(loc+".target_field", m.TargetField, t)
+	}
+
+	switch m.Operand {
+	case RuleOperand.SEL, RuleOperand.SUM:
+		// the left side always names the field holding the selection keys
+		if t, ok := v.field(loc+".left_side", m.LeftSide); ok {
+			fits := t.Kind() == reflect.String
+			if m.Operand == RuleOperand.SUM {
+				fits = t.Kind() == reflect.Slice || t.Kind() == reflect.Array
+			}
+			if !fits {
+				v.mismatch(loc+".left_side", m.LeftSide, t)
+			}
+		}
+		v.modiferComplex(loc, m)
+	case RuleOperand.SET:
+		v.modiferSide(loc, "left", m.DataType, m.LeftSide, m.LeftType)
+	default:
+		v.modiferSide(loc, "left", m.DataType, m.LeftSide, m.LeftType)
+		if m.RightType == ModiferSideType.COMPLEX {
+			v.modiferComplex(loc, m)
+			return
+		}
+		v.modiferSide(loc, "right", m.DataType, m.RightSide, m.RightType)
+		if m.Operand == RuleOperand.DIV && m.RightType == ModiferSideType.VALUE {
+			if d, err := decimal.NewFromString(m.RightSide); err == nil && d.IsZero() {
+				v.fail(loc+".right_side", RuleSettingError.DIV_BY_ZERO)
+			}
+		}
+	}
+}
+
+func (v *validator) modiferSide(loc, side string, dataType DataTypeCode, value string, sideType SideCode) {
+	switch sideType {
+	case ModiferSideType.VALUE:
+		if !modiferValueValid(dataType, value) {
+			v.fail(fmt.Sprintf("%s.%s_side", loc, side), fmt.Errorf("%w: %s", RuleSettingError.VALUE_INVALID, value))
+		}
+	case ModiferSideType.FIELD:
+		t, ok := v.field(fmt.Sprintf("%s.%s_side", loc, side), value)
+		if ok && !modiferFieldFits(dataType, t) {
+			v.mismatch(fmt.Sprintf("%s.%s_side", loc, side), value, t)
+		}
+	default:
+		v.fail(fmt.Sprintf("%s.%s_type", loc, side), RuleSettingError.MODIFER_SIDE_INVALID)
+	}
+}
+
+func (v *validator) modiferComplex(loc string, m Modifer) {
+	switch m.RightType {
+	case ModiferSideType.COMPLEX:
+		var err error
+		if m.DataType == ModiferDataType.DECIMAL {
+			err = json.Unmarshal([]byte(m.RightSide), new(decimalComplex))
+		} else {
+			err = json.Unmarshal([]byte(m.RightSide), new(ModiferComplex))
+		}
+		if err != nil {
+			v.fail(loc+".right_side", fmt.Errorf("%w: %v", RuleSettingError.MODIFER_SIDE_INVALID, err))
+		}
+	case ModiferSideType.FIELD:
+		t, ok := v.field(loc+".right_side", m.RightSide)
+		if ok && t != complexType && t.Kind() != reflect.Map {
+			v.mismatch(loc+".right_side", m.RightSide, t)
+		}
+	default:
+		v.fail(loc+".right_type", RuleSettingError.MODIFER_SIDE_INVALID)
+	}
+}
+
+// field resolve path against the sample, ok is false when there is nothing further to check
+func (v *validator) field(loc, path string) (reflect.Type, bool) {
+	if path == "" {
+		v.fail(loc, RuleSettingError.MODIFER_FEILD_NOT_EXISTED)
+		return nil, false
+	}
+	if v.sample == nil {
+		if _, err := parsePath(path); err != nil {
+			v.fail(loc, err)
+		}
+		return nil, false
+	}
+	t, err := fieldType(v.sample, path)
+	if err != nil {
+		v.fail(loc, err)
+		return nil, false
+	}
+	return t, t != nil
+}
+
+func (v *validator) mismatch(loc, path string, t reflect.Type) {
+	v.fail(loc, fmt.Errorf("%w: %s is %s", RuleSettingError.FIELD_TYPE_MISMATCH, path, t))
+}
+
+func conditionValueValid(conditionType ConditionTypeCode, value string) bool {
+	switch conditionType {
+	case RuleConditionType.DAY_OF_WEEK, RuleConditionType.DATE, RuleConditionType.INT:
+		_, err := strconv.ParseInt(value, 10, 64)
+		return err == nil
+	case RuleConditionType.DECIMAL:
+		_, err := decimal.NewFromString(strings.TrimSpace(value))
+		return err == nil
+	}
+	return true
+}
+
+func modiferValueValid(dataType DataTypeCode, value string) bool {
+	switch dataType {
+	case ModiferDataType.INT:
+		_, err := strconv.ParseInt(value, 10, 64)
+		return err == nil
+	case ModiferDataType.DECIMAL:
+		_, err := decimal.NewFromString(value)
+		return err == nil
+	}
+	return true
+}
+
+func conditionFieldFits(conditionType ConditionTypeCode, t reflect.Type) bool {
+	switch conditionType {
+	case RuleConditionType.DAY_OF_WEEK, RuleConditionType.DATE:
+		return t == timeType || isIntKind(t.Kind()) || t.Kind() == reflect.String
+	case RuleConditionType.STRING:
+		return t.Kind() == reflect.String
+	case RuleConditionType.INT:
+		return isIntKind(t.Kind())
+	case RuleConditionType.DECIMAL:
+		return isDecimalType(t)
+	}
+	return true
+}
+
+func modiferFieldFits(dataType DataTypeCode, t reflect.Type) bool {
+	switch dataType {
+	case ModiferDataType.STRING:
+		return t.Kind() == reflect.String
+	case ModiferDataType.INT:
+		return isIntKind(t.Kind())
+	case ModiferDataType.DECIMAL:
+		return isDecimalType(t)
+	}
+	return true
+}
+
+func modiferTargetFits(dataType DataTypeCode, t reflect.Type) bool {
+	switch dataType {
+	case ModiferDataType.STRING:
+		return t.Kind() == reflect.String
+	case ModiferDataType.INT:
+		return isIntKind(t.Kind()) && t.Kind() < reflect.Uint
+	case ModiferDataType.DECIMAL:
+		return t == decimalType || t.Kind() == reflect.String
+	}
+	return true
+}
+
+func isIntKind(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Uint64
+}
+
+func isDecimalType(t reflect.Type) bool {
+	k := t.Kind()
+	return t == decimalType || k == reflect.String || isIntKind(k) || k == reflect.Float32 || k == reflect.Float64
+}
@@ -0,0 +1,166 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type validated struct {
+	Adults   int64
+	Price    int64
+	Name     string
+	Extras   []string
+	CheckIn  time.Time
+	Rate     decimal.Decimal
+	Count    uint
+	Complex  ModiferComplex
+	Guest    *guest
+	Anything interface{}
+}
+
+type wantError struct {
+	loc string
+	err error
+}
+
+func validationErrors(t *testing.T, err error) []wantError {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var ves ValidationErrors
+	if !errors.As(err, &ves) {
+		t.Fatalf("Validate = %v, want ValidationErrors", err)
+	}
+	got := make([]wantError, len(ves))
+	for i, ve := range ves {
+		got[i] = wantError{ve.Location, ve.Err}
+	}
+	return got
+}
+
+func TestValidateLocations(t *testing.T) {
+	str := func(field, value string) Condition {
+		return Condition{Type: RuleConditionType.STRING, LeftSide: field, LeftType: ConditionSideType.FIELD, RightSide: value, RightType: ConditionSideType.VALUE}
+	}
+	cases := []struct {
+		name string
+		rule Rule
+		want []wantError
+	}{
+		{"a clean rule", Rule{
+			ConditionChain: []Condition{intCondition("Adults", RuleConditionCompare.IN, "1,2"), str("Guest.Loyalty.Tier", "gold"), {Type: RuleConditionType.MUST}},
+			ModiferChain:   []Modifer{intModifer(RuleOperand.ADD, "Price", "10")},
+		}, nil},
+		{"a value of the wrong type", Rule{ConditionChain: []Condition{intCondition("Adults", RuleConditionCompare.EQUAL, "x")}},
+			[]wantError{{"rule.condition_chain[0].right_side", RuleSettingError.VALUE_INVALID}}},
+		{"one of a list", Rule{ConditionChain: []Condition{intCondition("Adults", RuleConditionCompare.NOT_IN, "1,x")}},
+			[]wantError{{"rule.condition_chain[0].right_side", RuleSettingError.VALUE_INVALID}}},
+		{"an unknown field", Rule{ConditionChain: []Condition{intCondition("Adult", RuleConditionCompare.EQUAL, "1")}},
+			[]wantError{{"rule.condition_chain[0].left_side", RuleSettingError.MODIFER_FEILD_NOT_EXISTED}}},
+		{"a field of the wrong type", Rule{ConditionChain: []Condition{intCondition("Name", RuleConditionCompare.EQUAL, "1")}},
+			[]wantError{{"rule.condition_chain[0].left_side", RuleSettingError.FIELD_TYPE_MISMATCH}}},
+		{"a compare the type has not", Rule{ConditionChain: []Condition{{Type: RuleConditionType.STRING, LeftSide: "Name", LeftType: ConditionSideType.FIELD, Compare: RuleConditionCompare.MORE, RightSide: "a", RightType: ConditionSideType.VALUE}}},
+			[]wantError{{"rule.condition_chain[0].compare", RuleSettingError.UNSUPPORTED_OPERATION}}},
+		{"an unknown type", Rule{ConditionChain: []Condition{{Type: 42}}},
+			[]wantError{{"rule.condition_chain[0].type", RuleSettingError.UNSUPPORTED_OPERATION}}},
+		{"an unknown side type", Rule{ConditionChain: []Condition{{Type: RuleConditionType.INT, LeftSide: "Adults", LeftType: ConditionSideType.FIELD, RightSide: "1", RightType: 7}}},
+			[]wantError{{"rule.condition_chain[0].right_type", RuleSettingError.CONDITION_SIDE_INVALID}}},
+		{"a bad path", Rule{ConditionChain: []Condition{intCondition("Guest..Points", RuleConditionCompare.EQUAL, "1")}},
+			[]wantError{{"rule.condition_chain[0].left_side", RuleSettingError.FIELD_PATH_INVALID}}},
+		{"through an interface", Rule{ConditionChain: []Condition{intCondition("Anything.Whatever", RuleConditionCompare.EQUAL, "1")}}, nil},
+		{"in the tree", Rule{ConditionTree: &ConditionGroup{Mode: ConditionGroupMode.ANY, Groups: []ConditionGroup{
+			{Mode: 9, Conditions: []Condition{intCondition("Adults", RuleConditionCompare.EQUAL, "1"), intCondition("Price", RuleConditionCompare.EQUAL, "y")}},
+		}}}, []wantError{
+			{"rule.condition_tree.groups[0].mode", RuleSettingError.UNSUPPORTED_OPERATION},
+			{"rule.condition_tree.groups[0].conditions[1].right_side", RuleSettingError.VALUE_INVALID},
+		}},
+		{"a target of the wrong type", Rule{ModiferChain: []Modifer{
+			{Operand: RuleOperand.SET, DataType: ModiferDataType.STRING, LeftSide: "x", LeftType: ModiferSideType.VALUE, TargetField: "Price"},
+			{Sequence: 1, Operand: RuleOperand.SET, DataType: ModiferDataType.INT, LeftSide: "1", LeftType: ModiferSideType.VALUE, TargetField: "Count"},
+		}}, []wantError{
+			{"rule.rate_modifer[0].target_field", RuleSettingError.FIELD_TYPE_MISMATCH},
+			{"rule.rate_modifer[1].target_field", RuleSettingError.FIELD_TYPE_MISMATCH},
+		}},
+		{"an operand the data type has not", Rule{ModiferChain: []Modifer{{Operand: RuleOperand.ADD, DataType: ModiferDataType.STRING}}},
+			[]wantError{{"rule.rate_modifer[0].operand", RuleSettingError.UNSUPPORTED_OPERATION}}},
+		{"an unknown data type", Rule{ModiferChain: []Modifer{{DataType: 42}}},
+			[]wantError{{"rule.rate_modifer[0].data_type", RuleSettingError.UNSUPPORTED_OPERATION}}},
+		{"broken complex JSON", Rule{ModiferChain: []Modifer{{Operand: RuleOperand.ADD, DataType: ModiferDataType.INT, LeftSide: "Price", LeftType: ModiferSideType.FIELD, RightSide: "{bad", RightType: ModiferSideType.COMPLEX, TargetField: "Price"}}},
+			[]wantError{{"rule.rate_modifer[0].right_side", RuleSettingError.MODIFER_SIDE_INVALID}}},
+		{"a fractional complex for INT", Rule{ModiferChain: []Modifer{{Operand: RuleOperand.ADD, DataType: ModiferDataType.INT, LeftSide: "Price", LeftType: ModiferSideType.FIELD, RightSide: `{"flat": 1.5}`, RightType: ModiferSideType.COMPLEX, TargetField: "Price"}}},
+			[]wantError{{"rule.rate_modifer[0].right_side", RuleSettingError.MODIFER_SIDE_INVALID}}},
+		{"a fractional complex for DECIMAL", Rule{ModiferChain: []Modifer{{Operand: RuleOperand.ADD, DataType: ModiferDataType.DECIMAL, LeftSide: "Rate", LeftType: ModiferSideType.FIELD, RightSide: `{"flat": 1.5}`, RightType: ModiferSideType.COMPLEX, TargetField: "Rate"}}}, nil},
+		{"a complex field", Rule{ModiferChain: []Modifer{
+			{Operand: RuleOperand.SEL, DataType: ModiferDataType.INT, LeftSide: "Name", RightSide: "Complex", RightType: ModiferSideType.FIELD, TargetField: "Price"},
+			{Sequence: 1, Operand: RuleOperand.SUM, DataType: ModiferDataType.INT, LeftSide: "Name", RightSide: "Name", RightType: ModiferSideType.FIELD, TargetField: "Price"},
+		}}, []wantError{
+			{"rule.rate_modifer[1].left_side", RuleSettingError.FIELD_TYPE_MISMATCH},
+			{"rule.rate_modifer[1].right_side", RuleSettingError.FIELD_TYPE_MISMATCH},
+		}},
+		{"DIV by zero", Rule{ModiferChain: []Modifer{intModifer(RuleOperand.DIV, "Price", "0")}},
+			[]wantError{{"rule.rate_modifer[0].right_side", RuleSettingError.DIV_BY_ZERO}}},
+		{"an unknown rounding mode", Rule{ModiferChain: []Modifer{withRounding(intModifer(RuleOperand.DIV, "Price", "2"), &Rounding{Mode: 9})}},
+			[]wantError{{"rule.rate_modifer[0].rounding.mode", RuleSettingError.UNSUPPORTED_OPERATION}}},
+		{"modifers of one sequence", Rule{ModiferChain: []Modifer{intModifer(RuleOperand.ADD, "Price", "1"), intModifer(RuleOperand.ADD, "Price", "2")}},
+			[]wantError{{"rule.rate_modifer[1].sequence", RuleSettingError.SETTING_NOT_IN_ORDER}}},
+		{"modifers after a JMP", Rule{ModiferChain: []Modifer{
+			{Sequence: 2, DataType: ModiferDataType.JMP, LeftSide: "other", RightSide: "0"},
+			{Sequence: 3, Operand: RuleOperand.ADD, DataType: ModiferDataType.INT, LeftSide: "Price", LeftType: ModiferSideType.FIELD, RightSide: "1", RightType: ModiferSideType.VALUE, TargetField: "Price"},
+		}}, []wantError{{"rule.rate_modifer[0]", RuleSettingError.SETTING_NOT_IN_ORDER}}},
+		{"a jump without a rule set or start", Rule{ModiferChain: []Modifer{{DataType: ModiferDataType.JRT, RightSide: "x"}}}, []wantError{
+			{"rule.rate_modifer[0].left_side", RuleSettingError.MODIFER_SIDE_INVALID},
+			{"rule.rate_modifer[0].right_side", RuleSettingError.VALUE_INVALID},
+		}},
+	}
+	for _, c := range cases {
+		rs := []RuleSetting{{ID: "s", Rule: c.rule}}
+		got := validationErrors(t, Validate(rs, reflect.TypeOf(&validated{})))
+		if len(got) != len(c.want) {
+			t.Errorf("%s: Validate = %v, want %v", c.name, got, c.want)
+			continue
+		}
+		for i := range got {
+			if got[i].loc != c.want[i].loc || !errors.Is(got[i].err, c.want[i].err) {
+				t.Errorf("%s: error %d is %s %v, want %s %v", c.name, i, got[i].loc, got[i].err, c.want[i].loc, c.want[i].err)
+			}
+		}
+	}
+}
+
+func TestValidateSettings(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	rs := []RuleSetting{
+		{ID: "a", Sequence: 2, Rule: Rule{ConditionChain: []Condition{intCondition("Adults", RuleConditionCompare.EQUAL, "x")}}},
+		{ID: "b", Sequence: 1, ValidFrom: &from, ValidTo: &from},
+	}
+	err := Validate(rs, nil)
+	var ves ValidationErrors
+	if !errors.As(err, &ves) || len(ves) != 3 {
+		t.Fatalf("Validate = %v, want three errors", err)
+	}
+	if ves[0].Setting != 0 || ves[0].SettingID != "a" || ves[1].Setting != 1 || ves[1].Location != "sequence" || ves[2].Location != "valid_to" {
+		t.Errorf("Validate = %v", err)
+	}
+	if !errors.Is(err, RuleSettingError.SETTING_NOT_IN_ORDER) || !errors.Is(err, RuleSettingError.VALUE_INVALID) {
+		t.Errorf("ValidationErrors do not unwrap to each error: %v", err)
+	}
+	if want := `setting 1 (b) sequence: Rule Settings not in order`; ves[1].Error() != want {
+		t.Errorf("Error() = %q, want %q", ves[1].Error(), want)
+	}
+
+	// without a sample only the paths are checked
+	if err := Validate([]RuleSetting{setting("r", 1, []Condition{intCondition("Whatever", RuleConditionCompare.EQUAL, "1")})}, nil); err != nil {
+		t.Errorf("Validate without a sample = %v", err)
+	}
+	if err := Validate(nil, reflect.TypeOf(validated{})); err != nil {
+		t.Errorf("Validate of nothing = %v", err)
+	}
+}
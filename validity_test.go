@@ -0,0 +1,185 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var (
+	promoStart = time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	promoEnd   = time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// promoSetting a setting adding 10 to Price, live from promoStart until promoEnd
+func promoSetting(ruleID string, seq int64) RuleSetting {
+	rs := setting(ruleID, seq, nil, intModifer(RuleOperand.ADD, "Price", "10"))
+	rs.ValidFrom, rs.ValidTo = &promoStart, &promoEnd
+	return rs
+}
+
+func clockAt(t time.Time) Option {
+	return WithClock(func() time.Time { return t })
+}
+
+func validityCases() []struct {
+	name  string
+	now   time.Time
+	skip  int
+	price int64
+} {
+	return []struct {
+		name  string
+		now   time.Time
+		skip  int
+		price int64
+	}{
+		{"Before", promoStart.Add(-time.Second), SettingSkip.NOT_YET_VALID, 100},
+		{"AtStart", promoStart, SettingSkip.NONE, 110},
+		{"Within", promoStart.Add(15 * 24 * time.Hour), SettingSkip.NONE, 110},
+		{"AtEnd", promoEnd, SettingSkip.EXPIRED, 100},
+		{"After", promoEnd.Add(time.Hour), SettingSkip.EXPIRED, 100},
+	}
+}
+
+func TestValidityWindow(t *testing.T) {
+	for _, tc := range validityCases() {
+		t.Run(tc.name, func(t *testing.T) {
+			rs := []RuleSetting{promoSetting("promo", 1)}
+			b := &booking{Price: 100}
+			if _, err := NewEngine(stubSupply{}, clockAt(tc.now)).ApplySettings(b, rs); err != nil {
+				t.Fatal(err)
+			}
+			if b.Price != tc.price {
+				t.Fatalf("Price = %d, want %d", b.Price, tc.price)
+			}
+
+			_, tr, err := NewEngine(stubSupply{}, clockAt(tc.now)).ExplainSettings(&booking{Price: 100}, rs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tr.Settings) != 1 || tr.Settings[0].Skipped != tc.skip {
+				t.Fatalf("traced as %+v, want the setting skipped as %d", tr.Settings, tc.skip)
+			}
+		})
+	}
+}
+
+func TestValidityOpenEnded(t *testing.T) {
+	from := setting("promo", 1, nil, intModifer(RuleOperand.ADD, "Price", "10"))
+	from.ValidFrom = &promoStart
+	to := setting("promo", 2, nil, intModifer(RuleOperand.ADD, "Price", "1"))
+	to.ValidTo = &promoEnd
+
+	for _, tc := range []struct {
+		name  string
+		now   time.Time
+		price int64
+	}{
+		{"Before", promoStart.Add(-time.Hour), 101},
+		{"Within", promoStart, 111},
+		{"After", promoEnd, 110},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			b := &booking{Price: 100}
+			if _, err := NewEngine(stubSupply{}, clockAt(tc.now)).ApplySettings(b, []RuleSetting{from, to}); err != nil {
+				t.Fatal(err)
+			}
+			if b.Price != tc.price {
+				t.Fatalf("Price = %d, want %d", b.Price, tc.price)
+			}
+		})
+	}
+}
+
+func TestValidityAcrossJumps(t *testing.T) {
+	sp := stubSupply{"promo": {promoSetting("promo", 1)}}
+	entry := []RuleSetting{setting("entry", 1, nil, jumpModifer(ModiferDataType.JRT, "promo", 0))}
+
+	for _, tc := range validityCases() {
+		t.Run(tc.name, func(t *testing.T) {
+			b := &booking{Price: 100}
+			if _, err := NewEngine(sp, clockAt(tc.now)).ApplySettings(b, entry); err != nil {
+				t.Fatal(err)
+			}
+			if b.Price != tc.price {
+				t.Fatalf("ApplySettings: Price = %d, want %d", b.Price, tc.price)
+			}
+
+			b = &booking{Price: 100}
+			if _, err := NewEngine(sp, clockAt(tc.now)).ApplyModifer(b, jumpModifer(ModiferDataType.JRT, "promo", 0)); err != nil {
+				t.Fatal(err)
+			}
+			if b.Price != tc.price {
+				t.Fatalf("ApplyModifer: Price = %d, want %d", b.Price, tc.price)
+			}
+		})
+	}
+}
+
+func TestValidityCompiled(t *testing.T) {
+	for _, tc := range validityCases() {
+		t.Run(tc.name, func(t *testing.T) {
+			cs, err := NewEngine(stubSupply{}, clockAt(tc.now)).Compile([]RuleSetting{promoSetting("promo", 1)}, reflect.TypeOf(booking{}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			b := &booking{Price: 100}
+			if _, err := cs.Apply(b); err != nil {
+				t.Fatal(err)
+			}
+			if b.Price != tc.price {
+				t.Fatalf("Price = %d, want %d", b.Price, tc.price)
+			}
+		})
+	}
+}
+
+func TestValidityClockReadOnce(t *testing.T) {
+	// the clock moves past the end of the window between settings, the evaluation keeps the time it started at
+	now := promoEnd.Add(-time.Nanosecond)
+	clock := func() time.Time {
+		t := now
+		now = promoEnd.Add(time.Hour)
+		return t
+	}
+	b := &booking{Price: 100}
+	rs := []RuleSetting{promoSetting("promo", 1), promoSetting("promo", 2)}
+	if _, err := NewEngine(stubSupply{}, WithClock(clock)).ApplySettings(b, rs); err != nil {
+		t.Fatal(err)
+	}
+	if b.Price != 120 {
+		t.Fatalf("Price = %d, want 120 with both settings applied", b.Price)
+	}
+}
+
+func TestValidityCompiledError(t *testing.T) {
+	broken := setting("promo", 1, []Condition{intCondition("Missing", RuleConditionCompare.EQUAL, "1")})
+	broken.ValidFrom, broken.ValidTo = &promoStart, &promoEnd
+	rs := []RuleSetting{broken, promoSetting("promo", 2)}
+
+	for _, tc := range validityCases() {
+		t.Run(tc.name, func(t *testing.T) {
+			re := NewEngine(stubSupply{}, clockAt(tc.now))
+			cs, err := re.Compile(rs, reflect.TypeOf(booking{}))
+			if err != nil {
+				t.Fatalf("Compile = %v, want the broken setting left until it is live", err)
+			}
+			_, wantErr := re.ApplySettings(&booking{Price: 100}, rs)
+			_, err = cs.Apply(&booking{Price: 100})
+			if (err == nil) != (tc.skip != SettingSkip.NONE) || (err == nil) != (wantErr == nil) {
+				t.Errorf("Apply = %v, the engine %v", err, wantErr)
+			}
+		})
+	}
+
+	always := broken
+	always.ValidFrom, always.ValidTo = nil, nil
+	if _, err := NewEngine(stubSupply{}).Compile([]RuleSetting{always}, reflect.TypeOf(booking{})); !errors.Is(err, RuleSettingError.MODIFER_FEILD_NOT_EXISTED) {
+		t.Errorf("Compile = %v, want MODIFER_FEILD_NOT_EXISTED for a setting always live", err)
+	}
+}
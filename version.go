@@ -0,0 +1,252 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// VersionDiff how rule set RuleID changed from version From to version To, settings are matched by ID
+type VersionDiff struct {
+	RuleID  string          `json:"rule_id"`
+	From    int             `json:"from"`
+	To      int             `json:"to"`
+	Added   []RuleSetting   `json:"added"`
+	Removed []RuleSetting   `json:"removed"`
+	Changed []SettingChange `json:"changed"`
+}
+
+// SettingChange one setting as it was in the older and the newer version
+type SettingChange struct {
+	From RuleSetting `json:"from"`
+	To   RuleSetting `json:"to"`
+}
+
+// Empty whether both versions hold the same settings
+func (d *VersionDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffVersions compare versions from and to of rule set id kept by sp
+func DiffVersions(sp VersionedSupply, id string, from, to int) (*VersionDiff, error) {
+	a, err := sp.FetchVersion(id, from, math.MinInt)
+	if err != nil {
+		return nil, err
+	}
+	b, err := sp.FetchVersion(id, to, math.MinInt)
+	if err != nil {
+		return nil, err
+	}
+	d := DiffSettings(a, b)
+	d.RuleID, d.From, d.To = id, from, to
+	return d, nil
+}
+
+// DiffSettings compare two lists of settings, Added and Changed follow the order of to, Removed the order of from
+func DiffSettings(from, to []RuleSetting) *VersionDiff {
+	d := &VersionDiff{Added: []RuleSetting{}, Removed: []RuleSetting{}, Changed: []SettingChange{}}
+	old := make(map[string]RuleSetting, len(from))
+	for _, setting := range from {
+		old[setting.ID] = setting
+	}
+	kept := make(map[string]bool, len(to))
+	for _, setting := range to {
+		prev, ok := old[setting.ID]
+		if !ok {
+			d.Added = append(d.Added, setting)
+			continue
+		}
+		kept[setting.ID] = true
+		if !sameSetting(prev, setting) {
+			d.Changed = append(d.Changed, SettingChange{From: prev, To: setting})
+		}
+	}
+	for _, setting := range from {
+		if !kept[setting.ID] {
+			d.Removed = append(d.Removed, setting)
+		}
+	}
+	return d
+}
+
+// sameSetting compared as JSON, so a nil and an empty chain read back from storage are alike
+func sameSetting(a, b RuleSetting) bool {
+	ja, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	jb, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return string(ja) == string(jb)
+}
+
+// VersionedMemorySupply a VersionedSupply kept in memory, safe for concurrent use
+type VersionedMemorySupply struct {
+	mu    sync.RWMutex
+	rules map[string]*ruleVersions
+}
+
+// ruleVersions every version of one rule set
+type ruleVersions struct {
+	versions  []RuleVersion
+	settings  [][]RuleSetting // by Version-1
+	published []int           // publish history, the current one last
+}
+
+func NewVersionedMemorySupply() *VersionedMemorySupply {
+	return &VersionedMemorySupply{
+		rules: make(map[string]*ruleVersions),
+	}
+}
+
+// SaveRuleSettings make a new draft version of every rule set touched, holding its latest version
+// with rs put in place of the settings with the same ID. Settings without an ID get one
+func (vs *VersionedMemorySupply) SaveRuleSettings(rs []RuleSetting) error {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	byRule := make(map[string][]RuleSetting)
+	var order []string
+	for _, setting := range rs {
+		setting = setting.clone()
+		if setting.ID == "" {
+			setting.ID = uuid.NewV4().String()
+		}
+		if _, ok := byRule[setting.RuleID]; !ok {
+			order = append(order, setting.RuleID)
+		}
+		byRule[setting.RuleID] = append(byRule[setting.RuleID], setting)
+	}
+
+	now := time.Now()
+	for _, ruleID := range order {
+		rv, ok := vs.rules[ruleID]
+		if !ok {
+			rv = &ruleVersions{}
+			vs.rules[ruleID] = rv
+		}
+		var settings []RuleSetting
+		if n := len(rv.settings); n > 0 {
+			settings = cloneSettings(rv.settings[n-1])
+		}
+		rv.settings = append(rv.settings, upsertSettings(settings, byRule[ruleID]))
+		rv.versions = append(rv.versions, RuleVersion{RuleID: ruleID, Version: len(rv.settings), CreatedAt: now})
+	}
+	return nil
+}
+
+// FetchRuleSettings the settings of the published version of rule set id with Sequence >= start, in order
+func (vs *VersionedMemorySupply) FetchRuleSettings(id string, start int) ([]RuleSetting, error) {
+	vs.mu.RLock()
+	defer vs.mu.RUnlock()
+
+	rv, ok := vs.rules[id]
+	if !ok || len(rv.published) == 0 {
+		return nil, fmt.Errorf("%w: %s has no published version", RuleSettingError.RULE_NOT_FOUND, id)
+	}
+	return rv.fetch(rv.published[len(rv.published)-1], start), nil
+}
+
+// FetchVersion the settings of version of rule set id with Sequence >= start, in order
+func (vs *VersionedMemorySupply) FetchVersion(id string, version int, start int) ([]RuleSetting, error) {
+	vs.mu.RLock()
+	defer vs.mu.RUnlock()
+
+	rv, err := vs.ruleVersions(id, version)
+	if err != nil {
+		return nil, err
+	}
+	return rv.fetch(version, start), nil
+}
+
+// ListVersions every version of rule set id, oldest first
+func (vs *VersionedMemorySupply) ListVersions(id string) ([]RuleVersion, error) {
+	vs.mu.RLock()
+	defer vs.mu.RUnlock()
+
+	rv, ok := vs.rules[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", RuleSettingError.RULE_NOT_FOUND, id)
+	}
+	versions := append([]RuleVersion(nil), rv.versions...)
+	if n := len(rv.published); n > 0 {
+		versions[rv.published[n-1]-1].Published = true
+	}
+	return versions, nil
+}
+
+// PublishedVersion the version of rule set id FetchRuleSettings serves
+func (vs *VersionedMemorySupply) PublishedVersion(id string) (int, error) {
+	vs.mu.RLock()
+	defer vs.mu.RUnlock()
+
+	rv, ok := vs.rules[id]
+	if !ok || len(rv.published) == 0 {
+		return 0, fmt.Errorf("%w: %s has no published version", RuleSettingError.RULE_NOT_FOUND, id)
+	}
+	return rv.published[len(rv.published)-1], nil
+}
+
+// Publish serve version of rule set id from now on
+func (vs *VersionedMemorySupply) Publish(id string, version int) error {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	rv, err := vs.ruleVersions(id, version)
+	if err != nil {
+		return err
+	}
+	if n := len(rv.published); n > 0 && rv.published[n-1] == version {
+		return nil
+	}
+	rv.published = append(rv.published, version)
+	return nil
+}
+
+// Rollback serve again the version of rule set id published before the current one, and return it
+func (vs *VersionedMemorySupply) Rollback(id string) (int, error) {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	rv, ok := vs.rules[id]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", RuleSettingError.RULE_NOT_FOUND, id)
+	}
+	n := len(rv.published)
+	if n < 2 {
+		return 0, fmt.Errorf("%w: %s has no earlier published version", RuleSettingError.VERSION_NOT_FOUND, id)
+	}
+	rv.published = rv.published[:n-1]
+	return rv.published[n-2], nil
+}
+
+func (vs *VersionedMemorySupply) ruleVersions(id string, version int) (*ruleVersions, error) {
+	rv, ok := vs.rules[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", RuleSettingError.RULE_NOT_FOUND, id)
+	}
+	if version < 1 || version > len(rv.settings) {
+		return nil, fmt.Errorf("%w: %s version %d", RuleSettingError.VERSION_NOT_FOUND, id, version)
+	}
+	return rv, nil
+}
+
+func (rv *ruleVersions) fetch(version int, start int) []RuleSetting {
+	settings := rv.settings[version-1]
+	rs := make([]RuleSetting, 0, len(settings))
+	for _, setting := range settings {
+		if setting.Sequence >= int64(start) {
+			rs = append(rs, setting.clone())
+		}
+	}
+	return rs
+}
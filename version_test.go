@@ -0,0 +1,259 @@
+// Package rule ...
+// Maintainer : LibertusDio
+// DO NOT EDIT directly
+package rule_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	rule "github.com/007lock/go-turner"
+	"github.com/007lock/go-turner/supplytest"
+)
+
+func versionedSupplies() map[string]func(t *testing.T) rule.VersionedSupply {
+	return map[string]func(t *testing.T) rule.VersionedSupply{
+		"Memory": func(t *testing.T) rule.VersionedSupply {
+			return rule.NewVersionedMemorySupply()
+		},
+		"Gorm": func(t *testing.T) rule.VersionedSupply {
+			gv := rule.NewGormVersionedSupply(openGorm(t))
+			if err := gv.Migrate(); err != nil {
+				t.Fatal(err)
+			}
+			return gv
+		},
+	}
+}
+
+func saveVersion(t *testing.T, sp rule.VersionedSupply, rs ...rule.RuleSetting) {
+	t.Helper()
+	if err := sp.SaveRuleSettings(rs); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func expectPrices(t *testing.T, rs []rule.RuleSetting, values ...string) {
+	t.Helper()
+	if len(rs) != len(values) {
+		t.Fatalf("%+v, want %d settings", rs, len(values))
+	}
+	for i, setting := range rs {
+		if got := setting.Rule.ModiferChain[0].RightSide; got != values[i] {
+			t.Fatalf("setting %d adds %s, want %s", i, got, values[i])
+		}
+	}
+}
+
+func TestVersionedSupplyPublish(t *testing.T) {
+	for name, newSupply := range versionedSupplies() {
+		t.Run(name, func(t *testing.T) {
+			sp := newSupply(t)
+			saveVersion(t, sp, supplytest.Setting("a", "r", 1, "10"), supplytest.Setting("b", "r", 2, "20"))
+			if _, err := sp.FetchRuleSettings("r", 0); !errors.Is(err, rule.RuleSettingError.RULE_NOT_FOUND) {
+				t.Fatalf("FetchRuleSettings = %v, want a draft not served", err)
+			}
+
+			saveVersion(t, sp, supplytest.Setting("a", "r", 1, "15"))
+			if err := sp.Publish("r", 1); err != nil {
+				t.Fatal(err)
+			}
+			rs, err := sp.FetchRuleSettings("r", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expectPrices(t, rs, "10", "20")
+
+			if err := sp.Publish("r", 2); err != nil {
+				t.Fatal(err)
+			}
+			rs, err = sp.FetchRuleSettings("r", 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expectPrices(t, rs, "20")
+			if version, err := sp.PublishedVersion("r"); err != nil || version != 2 {
+				t.Fatalf("PublishedVersion = %d, %v, want 2", version, err)
+			}
+
+			versions, err := sp.ListVersions("r")
+			if err != nil || len(versions) != 2 {
+				t.Fatalf("ListVersions = %+v, %v, want 2 versions", versions, err)
+			}
+			if versions[0].Version != 1 || versions[0].Published || versions[1].Version != 2 || !versions[1].Published {
+				t.Fatalf("ListVersions = %+v, want version 2 marked published", versions)
+			}
+		})
+	}
+}
+
+func TestVersionedSupplyRollback(t *testing.T) {
+	for name, newSupply := range versionedSupplies() {
+		t.Run(name, func(t *testing.T) {
+			sp := newSupply(t)
+			saveVersion(t, sp, supplytest.Setting("a", "r", 1, "10"))
+			saveVersion(t, sp, supplytest.Setting("a", "r", 1, "20"))
+			saveVersion(t, sp, supplytest.Setting("a", "r", 1, "30"))
+			for _, version := range []int{1, 3, 3, 2} {
+				if err := sp.Publish("r", version); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			for _, want := range []int{3, 1} {
+				version, err := sp.Rollback("r")
+				if err != nil || version != want {
+					t.Fatalf("Rollback = %d, %v, want %d", version, err, want)
+				}
+			}
+			rs, err := sp.FetchRuleSettings("r", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expectPrices(t, rs, "10")
+			if _, err := sp.Rollback("r"); !errors.Is(err, rule.RuleSettingError.VERSION_NOT_FOUND) {
+				t.Fatalf("Rollback past the first publication = %v, want VERSION_NOT_FOUND", err)
+			}
+		})
+	}
+}
+
+func TestVersionedSupplyVersionsImmutable(t *testing.T) {
+	for name, newSupply := range versionedSupplies() {
+		t.Run(name, func(t *testing.T) {
+			sp := newSupply(t)
+			saveVersion(t, sp, supplytest.Setting("a", "r", 1, "10"))
+			rs, err := sp.FetchVersion("r", 1, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rs[0].Rule.ModiferChain[0].RightSide = "changed"
+			saveVersion(t, sp, supplytest.Setting("a", "r", 1, "20"), supplytest.Setting("b", "r", 0, "5"))
+
+			rs, err = sp.FetchVersion("r", 1, math.MinInt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expectPrices(t, rs, "10")
+			rs, err = sp.FetchVersion("r", 2, math.MinInt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expectPrices(t, rs, "5", "20")
+		})
+	}
+}
+
+func TestVersionedSupplyNotFound(t *testing.T) {
+	for name, newSupply := range versionedSupplies() {
+		t.Run(name, func(t *testing.T) {
+			sp := newSupply(t)
+			saveVersion(t, sp, supplytest.Setting("a", "r", 1, "10"))
+
+			if err := sp.Publish("r", 2); !errors.Is(err, rule.RuleSettingError.VERSION_NOT_FOUND) {
+				t.Errorf("Publish = %v, want VERSION_NOT_FOUND", err)
+			}
+			if _, err := sp.FetchVersion("r", 0, 0); !errors.Is(err, rule.RuleSettingError.VERSION_NOT_FOUND) {
+				t.Errorf("FetchVersion = %v, want VERSION_NOT_FOUND", err)
+			}
+			if err := sp.Publish("missing", 1); !errors.Is(err, rule.RuleSettingError.RULE_NOT_FOUND) {
+				t.Errorf("Publish of a missing rule set = %v, want RULE_NOT_FOUND", err)
+			}
+			if _, err := sp.ListVersions("missing"); !errors.Is(err, rule.RuleSettingError.RULE_NOT_FOUND) {
+				t.Errorf("ListVersions = %v, want RULE_NOT_FOUND", err)
+			}
+			if _, err := sp.PublishedVersion("r"); !errors.Is(err, rule.RuleSettingError.RULE_NOT_FOUND) {
+				t.Errorf("PublishedVersion = %v, want RULE_NOT_FOUND", err)
+			}
+			if _, err := sp.Rollback("missing"); err == nil {
+				t.Error("Rollback of a missing rule set succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestDiffVersions(t *testing.T) {
+	sp := rule.NewVersionedMemorySupply()
+	saveVersion(t, sp, supplytest.Setting("kept", "r", 1, "10"), supplytest.Setting("changed", "r", 2, "20"), supplytest.Setting("untouched", "r", 3, "30"))
+	// a save upserts into the latest version, settings only leave a rule set in DiffSettings below
+	saveVersion(t, sp, supplytest.Setting("kept", "r", 1, "10"), supplytest.Setting("changed", "r", 2, "25"), supplytest.Setting("added", "r", 4, "40"))
+
+	d, err := rule.DiffVersions(sp, "r", 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.RuleID != "r" || d.From != 1 || d.To != 2 {
+		t.Fatalf("DiffVersions = %+v, want r from 1 to 2", d)
+	}
+	if len(d.Added) != 1 || d.Added[0].ID != "added" {
+		t.Errorf("Added = %+v, want added", d.Added)
+	}
+	if len(d.Removed) != 0 {
+		t.Errorf("Removed = %+v, want nothing", d.Removed)
+	}
+	if len(d.Changed) != 1 || d.Changed[0].From.Rule.ModiferChain[0].RightSide != "20" || d.Changed[0].To.Rule.ModiferChain[0].RightSide != "25" {
+		t.Errorf("Changed = %+v, want 20 changed to 25", d.Changed)
+	}
+	if same, err := rule.DiffVersions(sp, "r", 2, 2); err != nil || !same.Empty() {
+		t.Errorf("DiffVersions of a version and itself = %+v, %v, want no difference", same, err)
+	}
+	if _, err := rule.DiffVersions(sp, "r", 1, 9); !errors.Is(err, rule.RuleSettingError.VERSION_NOT_FOUND) {
+		t.Errorf("DiffVersions = %v, want VERSION_NOT_FOUND", err)
+	}
+
+	removed := rule.DiffSettings(
+		[]rule.RuleSetting{supplytest.Setting("a", "r", 1, "10"), supplytest.Setting("b", "r", 2, "20")},
+		[]rule.RuleSetting{supplytest.Setting("b", "r", 2, "20")},
+	)
+	if len(removed.Removed) != 1 || removed.Removed[0].ID != "a" || len(removed.Added) != 0 || len(removed.Changed) != 0 {
+		t.Errorf("DiffSettings = %+v, want a removed", removed)
+	}
+}
+
+func TestEngineWithVersion(t *testing.T) {
+	type booking struct {
+		Price int64
+	}
+	sp := rule.NewVersionedMemorySupply()
+	saveVersion(t, sp, supplytest.Setting("a", "prices", 1, "10"))
+	saveVersion(t, sp, supplytest.Setting("a", "prices", 1, "20"))
+	if err := sp.Publish("prices", 2); err != nil {
+		t.Fatal(err)
+	}
+	jump := []rule.RuleSetting{{
+		ID:     "jump",
+		RuleID: "entry",
+		Enable: true,
+		Rule: rule.Rule{ModiferChain: []rule.Modifer{{
+			DataType:  rule.ModiferDataType.JMP,
+			LeftSide:  "prices",
+			RightSide: "0",
+		}}},
+	}}
+
+	for _, tc := range []struct {
+		name string
+		opts []rule.Option
+		want int64
+	}{
+		{"Published", nil, 120},
+		{"Pinned", []rule.Option{rule.WithVersion("prices", 1)}, 110},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			b := &booking{Price: 100}
+			if _, err := rule.NewEngine(sp, tc.opts...).ApplySettings(b, jump); err != nil {
+				t.Fatal(err)
+			}
+			if b.Price != tc.want {
+				t.Fatalf("Price = %d, want %d", b.Price, tc.want)
+			}
+		})
+	}
+
+	b := &booking{Price: 100}
+	_, err := rule.NewEngine(rule.NewMemorySupply(), rule.WithVersion("prices", 1)).ApplySettings(b, jump)
+	if !errors.Is(err, rule.RuleSettingError.UNABLE_TO_FETCH) {
+		t.Fatalf("ApplySettings = %v, want UNABLE_TO_FETCH pinning a version on a supply without versions", err)
+	}
+}